@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"kanban-backend/internal/config"
+	"kanban-backend/internal/handler"
+	"kanban-backend/internal/storage/postgres"
+	"kanban-backend/internal/trello"
+)
+
+// runImportTrello реализует подкоманду "import-trello": импорт экспорта доски Trello
+// в задачи пользователя без запуска HTTP-сервера. Сводка печатается в stdout в JSON.
+//
+//	server import-trello -user alice -file board.json [-dry-run] [-db-dsn ...]
+func runImportTrello(args []string) {
+	fs := flag.NewFlagSet("import-trello", flag.ExitOnError)
+	username := fs.String("user", "", "Username of the user who will own the imported tasks")
+	file := fs.String("file", "", "Path to the Trello board export JSON")
+	dryRun := fs.Bool("dry-run", false, "Only print the report, do not create tasks")
+	dsn := fs.String("db-dsn", os.Getenv("DATABASE_DSN"), "PostgreSQL DSN")
+	fs.Parse(args)
+	if *username == "" || *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *dsn == "" {
+		*dsn = config.DefaultDatabaseDSN
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Не удалось открыть файл экспорта: %v", err)
+	}
+	board, err := trello.Decode(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	taskStore := postgres.NewTaskStore()
+	if err := taskStore.Connect(ctx, *dsn); err != nil {
+		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
+	}
+	defer taskStore.Close()
+	userStore := postgres.NewUserStore(taskStore.DB())
+
+	user, err := userStore.GetUserByUsername(ctx, *username)
+	if err != nil {
+		log.Fatalf("Пользователь %s не найден: %v", *username, err)
+	}
+
+	tasks, report := trello.Convert(board, handler.UserExists(ctx, userStore))
+	report.DryRun = *dryRun
+	if err := handler.NormalizeTaskCustomFields(ctx, postgres.NewFieldStore(taskStore), user.ID, tasks); err != nil {
+		log.Fatalf("Не удалось импортировать доску: %v", err)
+	}
+	if !*dryRun && len(tasks) > 0 {
+		if err := taskStore.ImportTasks(ctx, user.ID, tasks); err != nil {
+			log.Fatalf("Не удалось импортировать доску: %v", err)
+		}
+		report.Created = len(tasks)
+		for _, t := range tasks {
+			report.TaskIDs = append(report.TaskIDs, t.ID)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("Ошибка вывода сводки: %v", err)
+	}
+}
@@ -12,8 +12,14 @@ import (
 
 	_ "kanban-backend/docs" // swagger generated docs
 	"kanban-backend/internal/config"
+	"kanban-backend/internal/events"
 	"kanban-backend/internal/handler"
+	"kanban-backend/internal/mail"
+	"kanban-backend/internal/notify"
+	"kanban-backend/internal/oidc"
+	"kanban-backend/internal/rules"
 	"kanban-backend/internal/storage/postgres"
+	"kanban-backend/internal/webhook"
 	"kanban-backend/internal/auth"
 
 	"github.com/go-chi/chi/v5"
@@ -24,7 +30,16 @@ import (
 
 // ... (остальные твои комментарии Swagger)
 
+// allowedOrigins - источники, которым разрешены CORS-запросы и WebSocket-подключения.
+var allowedOrigins = []string{"http://95.163.237.78:777"} // Конкретно твой фронтенд
+
 func main() {
+	// Подкоманды выполняются вместо запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "import-trello" {
+		runImportTrello(os.Args[2:])
+		return
+	}
+
 	cfg := config.Load()
 	log.Printf("Конфигурация загружена: Port=%s, DB DSN (скрыто)", cfg.ServerPort)
 
@@ -46,15 +61,203 @@ func main() {
 	if err := dbStore.Migrate(migrateCtx); err != nil {
 		log.Fatalf("Не удалось выполнить миграцию базы данных: %v", err)
 	}
+	if err := dbStore.MigrateSnapshots(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию снимков статусов: %v", err)
+	}
 
-	// --- UserStore и AuthHandler ---
+	// --- UserStore ---
 	userStore := postgres.NewUserStore(dbStore.DB()) // Получаем *sql.DB из TaskStore
 	if err := userStore.Migrate(migrateCtx); err != nil {
 		log.Fatalf("Не удалось выполнить миграцию users: %v", err)
 	}
-	authHandler := handler.NewAuthHandler(userStore)
 
-	taskHandler := handler.NewTaskHandler(dbStore)
+	// --- Брокер событий реального времени ---
+	var broker events.Broker
+	switch cfg.EventBroker {
+	case "postgres":
+		pgBroker, err := events.NewPostgresBroker(dbStore.DB(), cfg.DatabaseDSN)
+		if err != nil {
+			log.Fatalf("Не удалось запустить брокер событий: %v", err)
+		}
+		broker = pgBroker
+	case "memory":
+		broker = events.NewMemoryBroker()
+	default:
+		log.Fatalf("Неизвестный брокер событий: %s", cfg.EventBroker)
+	}
+	defer broker.Close()
+
+	eventLogStore := postgres.NewEventLogStore(dbStore.DB())
+	if err := eventLogStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию event_log: %v", err)
+	}
+
+	// --- Webhook: очередь доставок и фоновый воркер ---
+	webhookStore := postgres.NewWebhookStore(dbStore.DB())
+	if err := webhookStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию webhooks: %v", err)
+	}
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go webhook.NewWorker(webhookStore, cfg.WebhookMaxAttempts).Run(workerCtx)
+	go runPeriodically(workerCtx, time.Hour, "Очистка истории доставок webhook", func(ctx context.Context) (int64, error) {
+		return webhookStore.PurgeDeliveries(ctx, cfg.WebhookDeliveryRetention)
+	})
+	go runPeriodically(workerCtx, time.Minute, "Создание повторений задач", func(ctx context.Context) (int64, error) {
+		return dbStore.MaterializeOccurrences(ctx, time.Now())
+	})
+	// Снимок за вчерашний день делается первым запуском после полуночи UTC
+	go runPeriodically(workerCtx, time.Hour, "Снимок количества задач по статусам", func(ctx context.Context) (int64, error) {
+		return dbStore.SnapshotStatusCounts(ctx, time.Now())
+	})
+	go runPeriodically(workerCtx, time.Hour, "Очистка корзины", func(ctx context.Context) (int64, error) {
+		return dbStore.PurgeDeleted(ctx, cfg.TrashRetention)
+	})
+
+	idempotencyStore := postgres.NewIdempotencyStore(dbStore.DB(), cfg.IdempotencyTTL)
+	if err := idempotencyStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию idempotency_keys: %v", err)
+	}
+	go runPeriodically(workerCtx, time.Hour, "Удаление истекших ключей идемпотентности", idempotencyStore.PurgeExpired)
+	idempotency := handler.NewIdempotencyMiddleware(idempotencyStore)
+
+	calendarTokenStore := postgres.NewCalendarTokenStore(dbStore.DB())
+	if err := calendarTokenStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию calendar_tokens: %v", err)
+	}
+
+	templateStore := postgres.NewTemplateStore(dbStore.DB())
+	if err := templateStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию templates: %v", err)
+	}
+
+	sprintStore := postgres.NewSprintStore(dbStore)
+	if err := sprintStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию sprints: %v", err)
+	}
+
+	fieldStore := postgres.NewFieldStore(dbStore)
+	if err := fieldStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию custom_fields: %v", err)
+	}
+
+	timeEntryStore := postgres.NewTimeEntryStore(dbStore.DB())
+	if err := timeEntryStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию time_entries: %v", err)
+	}
+
+	// --- Уведомления ---
+	notificationStore := postgres.NewNotificationStore(dbStore.DB())
+	if err := notificationStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию notifications: %v", err)
+	}
+	watchStore := postgres.NewWatchStore(dbStore.DB())
+	if err := watchStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию task_watchers: %v", err)
+	}
+	notifier := notify.NewNotifier(notificationStore, watchStore, broker, cfg.DueSoonWindow)
+	go notifier.Run(workerCtx)
+	go runPeriodically(workerCtx, time.Minute, "Уведомления о близких сроках", notifier.RunDueSoon)
+	go runPeriodically(workerCtx, 15*time.Second, "Уведомления наблюдателям", notifier.RunWatchDigest)
+
+	// --- Почта: очередь писем, мгновенные уведомления и сводки ---
+	mailStore := postgres.NewMailStore(dbStore.DB())
+	if err := mailStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию mail_outbox: %v", err)
+	}
+	var mailer mail.Mailer
+	switch cfg.MailBackend {
+	case "smtp":
+		mailer = mail.NewSMTPMailer(cfg.SMTPAddr, cfg.MailFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+	case "log":
+		mailer = mail.NewLogMailer(cfg.MailLogDir, cfg.MailFrom)
+	default:
+		log.Fatalf("Неизвестный почтовый бэкенд: %s", cfg.MailBackend)
+	}
+	mailSecret := []byte(cfg.MailSecret)
+	if len(mailSecret) == 0 {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			log.Fatalf("Не удалось создать ключ подписи ссылок: %v", err)
+		}
+		mailSecret = []byte(secret)
+		log.Printf("MAIL_SECRET не задан: ссылки из писем перестанут работать после перезапуска")
+	}
+	mailDispatcher := mail.NewDispatcher(mailStore, mailSecret, cfg.PublicURL)
+	go mail.NewWorker(mailStore, mailer, cfg.MailMaxAttempts).Run(workerCtx)
+	go runPeriodically(workerCtx, time.Minute, "Письма с уведомлениями", mailDispatcher.RunInstant)
+	go runPeriodically(workerCtx, time.Hour, "Сводки уведомлений", mailDispatcher.RunDigests)
+	log.Printf("Почтовый бэкенд: %s", cfg.MailBackend)
+
+	// --- Подтверждение адреса, сброс пароля и версии сессий ---
+	accountStore := postgres.NewAccountStore(dbStore.DB())
+	if err := accountStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию account_tokens: %v", err)
+	}
+	go runPeriodically(workerCtx, time.Hour, "Удаление истекших токенов из писем", accountStore.PurgeExpiredTokens)
+
+	// --- Двухфакторная аутентификация ---
+	twoFactorStore := postgres.NewTwoFactorStore(dbStore.DB())
+	if err := twoFactorStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию user_totp: %v", err)
+	}
+	authHandler := handler.NewAuthHandler(userStore, accountStore, twoFactorStore, mailSecret, cfg.PublicURL)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorStore, accountStore, cfg.TOTPIssuer, cfg.AdminUsernames)
+
+	// --- Вход через провайдера OpenID Connect ---
+	identityStore := postgres.NewIdentityStore(dbStore.DB())
+	if err := identityStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию привязок OIDC: %v", err)
+	}
+	if cfg.OIDCIssuer != "" {
+		// Cookie с состоянием входа подписывается отдельным постоянным ключом: со случайным
+		// ключом возврат от провайдера не пройдет на другой реплике или после перезапуска
+		if cfg.OIDCStateSecret == "" {
+			log.Fatalf("Для входа через OIDC нужен OIDC_STATE_SECRET")
+		}
+		authHandler.OIDC = &handler.OIDCSettings{
+			Provider: oidc.NewProvider(oidc.Config{
+				Issuer:       cfg.OIDCIssuer,
+				ClientID:     cfg.OIDCClientID,
+				ClientSecret: cfg.OIDCClientSecret,
+				RedirectURL:  cfg.OIDCRedirectURL,
+				Scopes:       cfg.OIDCScopes,
+			}),
+			Identities:     identityStore,
+			AutoProvision:  cfg.OIDCAutoProvision,
+			LinkByUsername: cfg.OIDCLinkByUsername,
+			PostLoginURL:   cfg.OIDCPostLoginURL,
+			MFAACRValues:   cfg.OIDCMFAACRValues,
+			StateSecret:    []byte(cfg.OIDCStateSecret),
+		}
+		log.Printf("Вход через OIDC включен: %s", cfg.OIDCIssuer)
+	}
+
+	// --- Правила автоматизации ---
+	ruleStore := postgres.NewRuleStore(dbStore.DB())
+	if err := ruleStore.Migrate(migrateCtx); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию rules: %v", err)
+	}
+	ruleEngine := rules.NewEngine(ruleStore, dbStore)
+	go ruleEngine.Run(workerCtx)
+	go runPeriodically(workerCtx, time.Minute, "Правила по прошедшим срокам", ruleEngine.RunDue)
+
+	dbStore.SetPublisher(events.NewRecorder(eventLogStore, events.Publishers{broker, ruleEngine, notifier}))
+	log.Printf("Брокер событий: %s", cfg.EventBroker)
+
+	taskHandler := handler.NewTaskHandler(dbStore, fieldStore)
+	eventsHandler := handler.NewEventsHandler(broker, eventLogStore, accountStore, allowedOrigins)
+	webhookHandler := handler.NewWebhookHandler(webhookStore)
+	trelloHandler := handler.NewTrelloHandler(dbStore, userStore, fieldStore)
+	calendarHandler := handler.NewCalendarHandler(dbStore, calendarTokenStore)
+	templateHandler := handler.NewTemplateHandler(templateStore, dbStore, fieldStore)
+	ruleHandler := handler.NewRuleHandler(ruleStore)
+	sprintHandler := handler.NewSprintHandler(sprintStore)
+	timeHandler := handler.NewTimeHandler(timeEntryStore)
+	fieldHandler := handler.NewFieldHandler(fieldStore)
+	notificationHandler := handler.NewNotificationHandler(notificationStore)
+	watchHandler := handler.NewWatchHandler(watchStore)
+	mailHandler := handler.NewMailHandler(mailStore, mailSecret)
 
 	r := chi.NewRouter()
 
@@ -62,10 +265,10 @@ func main() {
 	// Это должно быть одним из первых middleware
 	corsMiddleware := cors.New(cors.Options{
 		// AllowedOrigins: []string{"*"}, // Разрешить все источники (менее безопасно для продакшена)
-		AllowedOrigins:   []string{"http://95.163.237.78:777"}, // Конкретно твой фронтенд
+		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300, // Максимальное время кеширования preflight запроса в секундах
 	})
@@ -76,21 +279,139 @@ func main() {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
 
 	// Маршруты API v1
 	r.Route("/api/v1", func(r chi.Router) {
-		// Auth middleware только для задач
+		// Обычные запросы ограничены по времени
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+			// Auth middleware только для задач
+			r.Group(func(r chi.Router) {
+				r.Use(auth.JWTAuthMiddleware(accountStore))
+				r.Get("/tasks", taskHandler.GetTasks)
+				r.Get("/tasks/{taskID}", taskHandler.GetTask)
+				r.Get("/tasks/{taskID}/activity", taskHandler.GetTaskActivity)
+				r.Get("/tasks/{taskID}/occurrences", taskHandler.PreviewOccurrences)
+				// Idempotency-Key только для изменения задач: ответ хранится в базе весь срок ключа,
+				// а ответы других маршрутов содержат секреты (токены календаря, секреты webhook, коды 2FA)
+				r.Group(func(r chi.Router) {
+					r.Use(idempotency.Handler)
+					r.Post("/tasks", taskHandler.CreateTask)
+					r.Post("/tasks/bulk", taskHandler.BulkTasks)
+					r.Post("/import", taskHandler.ImportTasks)
+					r.Post("/import/trello", trelloHandler.ImportTrello)
+					r.Put("/tasks/{taskID}", taskHandler.UpdateTask)
+					r.Delete("/tasks/{taskID}", taskHandler.DeleteTask)
+					r.Post("/tasks/{taskID}/archive", taskHandler.ArchiveTask)
+					r.Delete("/tasks/{taskID}/archive", taskHandler.UnarchiveTask)
+					r.Post("/tasks/{taskID}/restore", taskHandler.RestoreTask)
+				})
+				r.Get("/trash", taskHandler.GetTrash)
+				r.Get("/activity", taskHandler.GetActivity)
+				r.Post("/events/ticket", eventsHandler.IssueTicket)
+				r.Get("/metrics/flow", taskHandler.GetFlowMetrics)
+				r.Get("/metrics/cfd", taskHandler.GetCumulativeFlow)
+
+				r.Get("/webhooks", webhookHandler.GetWebhooks)
+				r.Post("/webhooks", webhookHandler.CreateWebhook)
+				r.Delete("/webhooks/{webhookID}", webhookHandler.DeleteWebhook)
+				r.Get("/webhooks/{webhookID}/deliveries", webhookHandler.GetDeliveries)
+				r.Post("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", webhookHandler.Redeliver)
+
+				r.Get("/templates", templateHandler.GetTemplates)
+				r.Post("/templates", templateHandler.CreateTemplate)
+				r.Post("/templates/from-tasks", templateHandler.CreateTemplateFromTasks)
+				r.Get("/templates/{templateID}", templateHandler.GetTemplate)
+				r.Put("/templates/{templateID}", templateHandler.UpdateTemplate)
+				r.Delete("/templates/{templateID}", templateHandler.DeleteTemplate)
+				r.Post("/templates/{templateID}/instantiate", templateHandler.InstantiateTemplate)
+
+				r.Get("/rules", ruleHandler.GetRules)
+				r.Post("/rules", ruleHandler.CreateRule)
+				r.Get("/rules/{ruleID}", ruleHandler.GetRule)
+				r.Put("/rules/{ruleID}", ruleHandler.UpdateRule)
+				r.Delete("/rules/{ruleID}", ruleHandler.DeleteRule)
+				r.Get("/rules/{ruleID}/executions", ruleHandler.GetExecutions)
+
+				r.Get("/sprints", sprintHandler.GetSprints)
+				r.Post("/sprints", sprintHandler.CreateSprint)
+				r.Get("/sprints/{sprintID}", sprintHandler.GetSprint)
+				r.Put("/sprints/{sprintID}", sprintHandler.UpdateSprint)
+				r.Delete("/sprints/{sprintID}", sprintHandler.DeleteSprint)
+				r.Get("/sprints/{sprintID}/tasks", sprintHandler.GetSprintTasks)
+				r.Post("/sprints/{sprintID}/tasks", sprintHandler.AddSprintTasks)
+				r.Delete("/sprints/{sprintID}/tasks/{taskID}", sprintHandler.RemoveSprintTask)
+				r.Get("/sprints/{sprintID}/burndown", sprintHandler.GetSprintBurndown)
+				r.Get("/sprints/{sprintID}/burnup", sprintHandler.GetSprintBurnup)
+				r.Post("/sprints/{sprintID}/close", sprintHandler.CloseSprint)
+
+				r.Get("/fields", fieldHandler.GetFields)
+				r.Post("/fields", fieldHandler.CreateField)
+				r.Get("/fields/{fieldID}", fieldHandler.GetField)
+				r.Put("/fields/{fieldID}", fieldHandler.UpdateField)
+				r.Delete("/fields/{fieldID}", fieldHandler.DeleteField)
+
+				r.Get("/timer", timeHandler.GetRunningTimer)
+				r.Post("/tasks/{taskID}/timer/start", timeHandler.StartTimer)
+				r.Post("/tasks/{taskID}/timer/stop", timeHandler.StopTimer)
+				r.Get("/tasks/{taskID}/time", timeHandler.GetTaskTime)
+				r.Post("/tasks/{taskID}/time-entries", timeHandler.CreateTimeEntry)
+				r.Put("/time-entries/{entryID}", timeHandler.UpdateTimeEntry)
+				r.Delete("/time-entries/{entryID}", timeHandler.DeleteTimeEntry)
+				r.Get("/timesheet", timeHandler.GetTimesheet)
+
+				r.Get("/notifications", notificationHandler.GetNotifications)
+				r.Get("/notifications/unread-count", notificationHandler.GetUnreadCount)
+				r.Get("/notifications/settings", mailHandler.GetMailSettings)
+				r.Post("/email/verification", authHandler.SendVerification)
+				r.Get("/2fa", twoFactorHandler.GetStatus)
+				r.Delete("/2fa", twoFactorHandler.Disable)
+				r.Post("/2fa/enroll", twoFactorHandler.Enroll)
+				r.Post("/2fa/confirm", twoFactorHandler.Confirm)
+				r.Post("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+				r.Delete("/admin/users/{userID}/2fa", twoFactorHandler.AdminReset)
+				r.Put("/notifications/settings", mailHandler.UpdateMailSettings)
+				r.Post("/notifications/read-all", notificationHandler.MarkAllRead)
+				r.Post("/notifications/{notificationID}/read", notificationHandler.MarkRead)
+
+				r.Post("/tasks/{taskID}/watch", watchHandler.WatchTask)
+				r.Delete("/tasks/{taskID}/watch", watchHandler.UnwatchTask)
+				r.Get("/tasks/{taskID}/watchers", watchHandler.GetWatchers)
+				r.Get("/watching", watchHandler.GetWatching)
+
+				r.Get("/calendar/tokens", calendarHandler.GetTokens)
+				r.Post("/calendar/tokens", calendarHandler.CreateToken)
+				r.Delete("/calendar/tokens/{tokenID}", calendarHandler.DeleteToken)
+			})
+			// Календарная лента: авторизация по секретному токену в URL
+			r.Get("/calendar/{token}.ics", calendarHandler.Feed)
+			// Отписка по подписанной ссылке из письма, без входа в систему
+			r.Get("/unsubscribe", mailHandler.UnsubscribePage)
+			r.Post("/unsubscribe", mailHandler.Unsubscribe)
+			// --- Auth routes ---
+			r.Post("/register", authHandler.Register)
+			r.Post("/login", authHandler.Login)
+			r.Post("/login/2fa", authHandler.CompleteLogin)
+			r.Get("/oidc/login", authHandler.OIDCLogin)
+			r.Get("/oidc/callback", authHandler.OIDCCallback)
+			r.Post("/password/forgot", authHandler.ForgotPassword)
+			// Ссылки из писем: GET показывает страницу, токен расходуется только при POST
+			r.Get("/password/reset", authHandler.ResetPasswordPage)
+			r.Post("/password/reset", authHandler.ResetPassword)
+			r.Get("/email/verify", authHandler.VerifyEmailPage)
+			r.Post("/email/verify", authHandler.VerifyEmail)
+		})
+		// Долгоживущие соединения: без middleware.Timeout,
+		// дедлайны на запись (вместо WriteTimeout сервера) выставляет сам обработчик
+		r.Group(func(r chi.Router) {
+			r.Use(auth.StreamAuthMiddleware(accountStore, accountStore))
+			r.Get("/ws", eventsHandler.Subscribe)
+			r.Get("/events", eventsHandler.Stream)
+		})
 		r.Group(func(r chi.Router) {
-			r.Use(auth.JWTAuthMiddleware)
-			r.Get("/tasks", taskHandler.GetTasks)
-			r.Post("/tasks", taskHandler.CreateTask)
-			r.Get("/tasks/{taskID}", taskHandler.GetTask)
-			r.Delete("/tasks/{taskID}", taskHandler.DeleteTask)
+			r.Use(auth.JWTAuthMiddleware(accountStore))
+			r.Get("/export", taskHandler.ExportTasks)
 		})
-		// --- Auth routes ---
-		r.Post("/register", authHandler.Register)
-		r.Post("/login", authHandler.Login)
 	})
 
 	r.Get("/swagger/*", httpSwagger.Handler(
@@ -119,6 +440,7 @@ func main() {
 
 	<-done
 	log.Println("Получен сигнал завершения. Начинаем graceful shutdown...")
+	stopWorkers()
 
 	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancelShutdown()
@@ -128,4 +450,24 @@ func main() {
 	}
 
 	log.Println("Сервер успешно остановлен.")
-}
\ No newline at end of file
+}
+
+// runPeriodically выполняет job сразу и затем каждые interval до отмены ctx.
+// job возвращает количество обработанных записей для лога.
+func runPeriodically(ctx context.Context, interval time.Duration, name string, job func(ctx context.Context) (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := job(ctx)
+		if err != nil {
+			log.Printf("%s: ошибка: %v", name, err)
+		} else if n > 0 {
+			log.Printf("%s: обработано записей: %d", name, n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
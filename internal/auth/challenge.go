@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ChallengeTTL - сколько действует токен второго шага входа.
+const ChallengeTTL = 5 * time.Minute
+
+// challengeType отличает токен второго шага от JWT сессии; JWTAuthMiddleware такие токены отклоняет.
+const challengeType = "2fa_challenge"
+
+// GenerateChallengeJWT выдает короткоживущий токен пользователю, который ввел верный пароль,
+// но еще не подтвердил вход вторым фактором.
+func GenerateChallengeJWT(userID int, sessionVersion int) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ChallengeTTL)
+	claims := jwt.MapClaims{
+		"sub": strconv.Itoa(userID),
+		"typ": challengeType,
+		"ver": sessionVersion,
+		"exp": expiresAt.Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	return token, expiresAt, err
+}
+
+// ParseChallengeJWT проверяет токен второго шага и возвращает пользователя и версию сессий.
+func ParseChallengeJWT(tokenStr string) (userID int, sessionVersion int, err error) {
+	claims, err := ParseJWT(tokenStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if typ, _ := claims["typ"].(string); typ != challengeType {
+		return 0, 0, jwt.ErrTokenMalformed
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err = strconv.Atoi(sub)
+	if err != nil {
+		return 0, 0, jwt.ErrTokenMalformed
+	}
+	version, _ := claims["ver"].(float64)
+	return userID, int(version), nil
+}
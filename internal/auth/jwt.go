@@ -7,14 +7,30 @@ import (
 	"net/http"
 	"context"
 	"strings"
+	"errors"
+	"log"
+
+	"kanban-backend/internal/storage"
 )
 
 var jwtSecret = []byte("supersecretkey") // TODO: вынести в конфиг
 
-func GenerateJWT(userID int, username string) (string, error) {
+// SessionChecker возвращает текущую версию сессий пользователя.
+// JWT с другой версией (выданные до сброса пароля) отклоняются.
+type SessionChecker interface {
+	SessionVersion(ctx context.Context, userID int) (int, error)
+}
+
+// TicketChecker использует одноразовый билет и возвращает его пользователя.
+type TicketChecker interface {
+	UseStreamTicket(ctx context.Context, ticketHash string) (int, error)
+}
+
+func GenerateJWT(userID int, username string, sessionVersion int) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id":  userID,
 		"username": username,
+		"ver":      sessionVersion,
 		"exp":      time.Now().Add(24 * time.Hour).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -35,26 +51,74 @@ func ParseJWT(tokenStr string) (jwt.MapClaims, error) {
 }
 
 // JWTAuthMiddleware проверяет JWT и кладет user_id в контекст запроса.
-func JWTAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		header := r.Header.Get("Authorization")
-		if header == "" || !strings.HasPrefix(header, "Bearer ") {
-			http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
-		tokenStr := strings.TrimPrefix(header, "Bearer ")
-		claims, err := ParseJWT(tokenStr)
-		if err != nil {
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-			return
-		}
-		userIDf, ok := claims["user_id"].(float64)
-		if !ok {
-			http.Error(w, "Invalid token payload", http.StatusUnauthorized)
-			return
-		}
-		userID := int(userIDf)
-		ctx := context.WithValue(r.Context(), "user_id", userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
-} 
\ No newline at end of file
+// Токены без версии сессий выданы до ее появления и считаются версией 0.
+func JWTAuthMiddleware(sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" || !strings.HasPrefix(header, "Bearer ") {
+				http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+				return
+			}
+			tokenStr := strings.TrimPrefix(header, "Bearer ")
+			claims, err := ParseJWT(tokenStr)
+			if err != nil {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			// Токен второго шага входа не дает доступа к API
+			if _, ok := claims["typ"]; ok {
+				http.Error(w, "Invalid token payload", http.StatusUnauthorized)
+				return
+			}
+			userIDf, ok := claims["user_id"].(float64)
+			if !ok {
+				http.Error(w, "Invalid token payload", http.StatusUnauthorized)
+				return
+			}
+			userID := int(userIDf)
+			version, _ := claims["ver"].(float64)
+			current, err := sessions.SessionVersion(r.Context(), userID)
+			if errors.Is(err, storage.ErrNotFound) || (err == nil && current != int(version)) {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Printf("Ошибка при проверке сессии пользователя %d: %v", userID, err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			ctx := context.WithValue(r.Context(), "user_id", userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// StreamAuthMiddleware - JWTAuthMiddleware для потоков событий. Браузер не может передать
+// заголовок Authorization при открытии WebSocket или EventSource, поэтому вместо него принимается
+// одноразовый билет в параметре ticket.
+func StreamAuthMiddleware(sessions SessionChecker, tickets TicketChecker) func(http.Handler) http.Handler {
+	withJWT := JWTAuthMiddleware(sessions)
+	return func(next http.Handler) http.Handler {
+		jwtNext := withJWT(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ticket := r.URL.Query().Get("ticket")
+			if ticket == "" {
+				jwtNext.ServeHTTP(w, r)
+				return
+			}
+			userID, err := tickets.UseStreamTicket(r.Context(), StreamTicketHash(ticket))
+			if errors.Is(err, storage.ErrTokenInvalid) {
+				http.Error(w, "Invalid or expired ticket", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Printf("Ошибка при проверке билета потока событий: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			ctx := context.WithValue(r.Context(), "user_id", userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
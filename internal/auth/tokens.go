@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// NewActionToken создает одноразовый токен для ссылки в письме: случайная часть,
+// подписанная HMAC-SHA256 вместе с назначением токена. Возвращает сам токен и его хеш;
+// в базе хранится только хеш, поэтому утечка базы не дает действующих ссылок.
+func NewActionToken(secret []byte, purpose string) (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+	token = nonce + "." + base64.RawURLEncoding.EncodeToString(actionMAC(secret, purpose, nonce))
+	return token, hashActionToken(token), nil
+}
+
+// ActionTokenHash проверяет подпись токена и возвращает хеш для поиска в базе.
+// Токены с неверной подписью или другим назначением отклоняются без обращения к базе.
+func ActionTokenHash(secret []byte, purpose string, token string) (string, bool) {
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, actionMAC(secret, purpose, nonce)) {
+		return "", false
+	}
+	return hashActionToken(token), true
+}
+
+// NewStreamTicket создает одноразовый билет для подключения к потоку событий.
+// Билет проверяется только по базе, поэтому подпись ему не нужна.
+func NewStreamTicket() (ticket string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	ticket = base64.RawURLEncoding.EncodeToString(b)
+	return ticket, hashActionToken(ticket), nil
+}
+
+// StreamTicketHash возвращает хеш билета для поиска в базе.
+func StreamTicketHash(ticket string) string {
+	return hashActionToken(ticket)
+}
+
+func actionMAC(secret []byte, purpose string, nonce string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + ":" + nonce))
+	return mac.Sum(nil)
+}
+
+func hashActionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret - ключ SHA1 из тестовых векторов RFC 6238 ("12345678901234567890") в base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Векторы RFC 6238, приложение B, для SHA1. В RFC коды восьмизначные,
+// шестизначный код - его последние шесть цифр.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "12345678901234567890" {
+		t.Fatalf("секрет декодирован как %q", key)
+	}
+	for _, v := range rfc6238Vectors {
+		want := v.code[2:]
+		if got := totpCode(key, v.unix/totpPeriod); got != want {
+			t.Errorf("T=%d: код %s, ожидался %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		counter, ok := ValidateTOTP(rfc6238Secret, v.code[2:], now, 0)
+		if !ok || counter != v.unix/totpPeriod {
+			t.Errorf("T=%d: код не принят (шаг %d)", v.unix, counter)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	cases := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, c := range cases {
+		code := totpCode(key, step+c.offset)
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now, 0); ok != c.ok {
+			t.Errorf("код шага %+d: принят %v, ожидалось %v", c.offset, ok, c.ok)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	now := time.Unix(59, 0)
+	counter, ok := ValidateTOTP(rfc6238Secret, "287082", now, 0)
+	if !ok {
+		t.Fatal("код не принят")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "287082", now, counter); ok {
+		t.Error("код принят повторно")
+	}
+}
+
+func TestValidateTOTPRejectsMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "94287082", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now, 0); ok {
+			t.Errorf("принят код %q", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now, 0); ok {
+		t.Error("принят код для неверного секрета")
+	}
+	// Секрет нечувствителен к регистру и пробелам вокруг, как при ручном вводе
+	if _, ok := ValidateTOTP(" "+strings.ToLower(rfc6238Secret)+" ", "287082", now, 0); !ok {
+		t.Error("не принят код для секрета в нижнем регистре")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' || !IsRecoveryCode(c) {
+			t.Errorf("неверный код восстановления %q", c)
+		}
+		if seen[c] {
+			t.Errorf("код %q повторяется", c)
+		}
+		seen[c] = true
+		// Код можно ввести без дефиса и в верхнем регистре
+		if HashRecoveryCode(strings.ToUpper(strings.Replace(c, "-", "", 1))) != HashRecoveryCode(c) {
+			t.Errorf("хеш кода %q зависит от записи", c)
+		}
+	}
+	if IsRecoveryCode("287082") {
+		t.Error("код TOTP принят за код восстановления")
+	}
+}
@@ -0,0 +1,269 @@
+// Package customfields проверяет определения пользовательских полей задач
+// и приводит значения полей к каноническому виду.
+package customfields
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/models"
+)
+
+const (
+	// MaxFields - наибольшее количество полей у пользователя.
+	MaxFields      = 50
+	maxOptions     = 100
+	maxOptionLen   = 100
+	maxTextLen     = 10000
+	maxPatternLen  = 500
+	dateLayout     = "2006-01-02"
+	keyDescription = "ключ должен начинаться с латинской буквы и содержать до 40 строчных латинских букв, цифр и _"
+)
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+var knownTypes = []string{
+	models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate, models.CustomFieldSelect,
+	models.CustomFieldMultiSelect, models.CustomFieldUser, models.CustomFieldURL,
+}
+
+// ValidateDefinition проверяет определение поля перед сохранением.
+func ValidateDefinition(field *models.CustomField) error {
+	if !keyPattern.MatchString(field.Key) {
+		return errors.New(keyDescription)
+	}
+	name := strings.TrimSpace(field.Name)
+	if name == "" {
+		return errors.New("название поля не может быть пустым")
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		return errors.New("название поля длиннее 255 символов")
+	}
+	if !contains(knownTypes, field.Type) {
+		return fmt.Errorf("тип поля должен быть одним из: %s", strings.Join(knownTypes, ", "))
+	}
+
+	hasOptions := field.Type == models.CustomFieldSelect || field.Type == models.CustomFieldMultiSelect
+	switch {
+	case hasOptions && len(field.Options) == 0:
+		return errors.New("для select и multi_select нужны варианты options")
+	case !hasOptions && len(field.Options) > 0:
+		return errors.New("варианты options указываются только для select и multi_select")
+	case len(field.Options) > maxOptions:
+		return fmt.Errorf("не больше %d вариантов", maxOptions)
+	}
+	for i, o := range field.Options {
+		if strings.TrimSpace(o) == "" || utf8.RuneCountInString(o) > maxOptionLen {
+			return fmt.Errorf("вариант %d должен быть непустой строкой до %d символов", i+1, maxOptionLen)
+		}
+		if contains(field.Options[:i], o) {
+			return fmt.Errorf("вариант %q повторяется", o)
+		}
+	}
+
+	if (field.Min != nil || field.Max != nil) && field.Type != models.CustomFieldNumber {
+		return errors.New("min и max указываются только для number")
+	}
+	if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+		return errors.New("min не может быть больше max")
+	}
+	if (field.MaxLength != nil || field.Pattern != "") && field.Type != models.CustomFieldText {
+		return errors.New("max_length и pattern указываются только для text")
+	}
+	if field.MaxLength != nil && (*field.MaxLength < 1 || *field.MaxLength > maxTextLen) {
+		return fmt.Errorf("max_length должен быть от 1 до %d", maxTextLen)
+	}
+	if field.Pattern != "" {
+		if len(field.Pattern) > maxPatternLen {
+			return fmt.Errorf("pattern длиннее %d символов", maxPatternLen)
+		}
+		if _, err := regexp.Compile(field.Pattern); err != nil {
+			return fmt.Errorf("неверный pattern: %w", err)
+		}
+	}
+	field.Name = name
+	return nil
+}
+
+// Normalize проверяет значения полей задачи по определениям fields и возвращает
+// их в каноническом виде. null означает отсутствие значения. Если значение
+// обязательного поля отсутствует или указан неизвестный ключ, возвращается ошибка.
+// Пустой результат возвращается как nil.
+func Normalize(fields []models.CustomField, values map[string]interface{}) (map[string]interface{}, error) {
+	byKey := make(map[string]*models.CustomField, len(fields))
+	for i := range fields {
+		byKey[fields[i].Key] = &fields[i]
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var result map[string]interface{}
+	for _, k := range keys {
+		field, ok := byKey[k]
+		if !ok {
+			return nil, fmt.Errorf("неизвестное поле %q", k)
+		}
+		if values[k] == nil {
+			continue
+		}
+		v, err := normalizeValue(field, values[k])
+		if err != nil {
+			return nil, fmt.Errorf("поле %s: %w", k, err)
+		}
+		if v == nil {
+			continue
+		}
+		if result == nil {
+			result = map[string]interface{}{}
+		}
+		result[k] = v
+	}
+	for _, field := range fields {
+		if _, ok := result[field.Key]; field.Required && !ok {
+			return nil, fmt.Errorf("поле %s обязательно", field.Key)
+		}
+	}
+	return result, nil
+}
+
+func normalizeValue(field *models.CustomField, v interface{}) (interface{}, error) {
+	switch field.Type {
+	case models.CustomFieldText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("значение должно быть строкой")
+		}
+		limit := maxTextLen
+		if field.MaxLength != nil {
+			limit = *field.MaxLength
+		}
+		if utf8.RuneCountInString(s) > limit {
+			return nil, fmt.Errorf("значение длиннее %d символов", limit)
+		}
+		if field.Pattern != "" && !regexp.MustCompile(`^(?:`+field.Pattern+`)$`).MatchString(s) {
+			return nil, errors.New("значение не соответствует шаблону")
+		}
+		return s, nil
+	case models.CustomFieldNumber:
+		n, ok := v.(float64)
+		if !ok {
+			return nil, errors.New("значение должно быть числом")
+		}
+		if field.Min != nil && n < *field.Min {
+			return nil, fmt.Errorf("значение меньше %v", *field.Min)
+		}
+		if field.Max != nil && n > *field.Max {
+			return nil, fmt.Errorf("значение больше %v", *field.Max)
+		}
+		return n, nil
+	case models.CustomFieldDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("значение должно быть датой в формате 2006-01-02")
+		}
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return nil, errors.New("значение должно быть датой в формате 2006-01-02")
+		}
+		return s, nil
+	case models.CustomFieldSelect:
+		s, ok := v.(string)
+		if !ok || !contains(field.Options, s) {
+			return nil, errors.New("значение должно быть одним из вариантов")
+		}
+		return s, nil
+	case models.CustomFieldMultiSelect:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.New("значение должно быть массивом вариантов")
+		}
+		selected := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok || !contains(field.Options, s) {
+				return nil, errors.New("значение должно быть массивом вариантов")
+			}
+			if !contains(selected, s) {
+				selected = append(selected, s)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, nil
+		}
+		return selected, nil
+	case models.CustomFieldUser:
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != math.Trunc(n) || n > math.MaxInt32 {
+			return nil, errors.New("значение должно быть ID пользователя")
+		}
+		return int(n), nil
+	case models.CustomFieldURL:
+		s, ok := v.(string)
+		if !ok || !isWebURL(s) {
+			return nil, errors.New("значение должно быть абсолютным URL http или https")
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("неизвестный тип поля %s", field.Type)
+}
+
+func isWebURL(s string) bool {
+	if len(s) > 2048 {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// UserIDs возвращает ID пользователей из значений полей типа user.
+func UserIDs(fields []models.CustomField, values map[string]interface{}) []int {
+	var ids []int
+	for _, field := range fields {
+		if id, ok := values[field.Key].(int); ok && field.Type == models.CustomFieldUser {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ParseFilterValue разбирает значение фильтра из строки запроса по типу поля.
+// Операторы gte и lte допустимы только для number и date.
+func ParseFilterValue(field *models.CustomField, op string, raw string) (interface{}, error) {
+	if op != models.FilterEq && field.Type != models.CustomFieldNumber && field.Type != models.CustomFieldDate {
+		return nil, fmt.Errorf("поле %s: сравнение поддерживается только для number и date", field.Key)
+	}
+	var v interface{} = raw
+	switch field.Type {
+	case models.CustomFieldNumber, models.CustomFieldUser:
+		// NaN и Inf ParseFloat принимает без ошибки, но в JSON фильтра их не записать
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("поле %s: значение должно быть числом", field.Key)
+		}
+		v = n
+	case models.CustomFieldDate:
+		if _, err := time.Parse(dateLayout, raw); err != nil {
+			return nil, fmt.Errorf("поле %s: значение должно быть датой в формате 2006-01-02", field.Key)
+		}
+	}
+	return v, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
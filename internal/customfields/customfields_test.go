@@ -0,0 +1,21 @@
+package customfields
+
+import (
+	"testing"
+
+	"kanban-backend/internal/models"
+)
+
+// NaN, Inf и переполнение - ошибка запроса (400), а не ошибка базы при сравнении.
+func TestParseFilterValueRejectsNonFiniteNumbers(t *testing.T) {
+	field := &models.CustomField{Key: "points", Type: models.CustomFieldNumber}
+	for _, raw := range []string{"NaN", "nan", "Inf", "-Infinity", "+inf", "1e309", "abc"} {
+		if _, err := ParseFilterValue(field, models.FilterEq, raw); err == nil {
+			t.Errorf("значение %q должно быть отклонено", raw)
+		}
+	}
+	v, err := ParseFilterValue(field, models.FilterEq, "2.5")
+	if err != nil || v != 2.5 {
+		t.Errorf("значение 2.5: %v, %v", v, err)
+	}
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Типы событий, рассылаемых клиентам.
+const (
+	TaskCreated    = "task.created"
+	TaskUpdated    = "task.updated"
+	TaskDeleted    = "task.deleted"
+	TaskRestored   = "task.restored"
+	TaskArchived   = "task.archived"
+	TaskUnarchived = "task.unarchived"
+)
+
+// NotificationCreated доставляется получателю уведомления только в реальном времени:
+// его нет в Types и в журнале событий, пропущенные уведомления клиент читает из входящих.
+const NotificationCreated = "notification.created"
+
+// Types - все типы событий, на которые можно подписаться.
+var Types = []string{TaskCreated, TaskUpdated, TaskDeleted, TaskRestored, TaskArchived, TaskUnarchived}
+
+// IsKnownType сообщает, является ли t одним из Types.
+func IsKnownType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event описывает изменение, о котором нужно уведомить подписчиков.
+// События адресуются владельцу задачи: задачи в системе принадлежат одному пользователю.
+// ID присваивается при записи в журнал событий и служит для докачки через Last-Event-ID.
+type Event struct {
+	ID        int64           `json:"id,omitempty"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	TaskID    int             `json:"task_id"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// New создает событие, сериализуя data в JSON.
+func New(eventType string, userID, taskID int, data interface{}) (Event, error) {
+	e := Event{
+		Type:      eventType,
+		UserID:    userID,
+		TaskID:    taskID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return Event{}, fmt.Errorf("ошибка сериализации события %s: %w", eventType, err)
+		}
+		e.Data = raw
+	}
+	return e, nil
+}
+
+// Publisher публикует события.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Publishers передает событие каждому из Publisher по очереди.
+// Ошибка одного получателя не мешает доставке остальным.
+type Publishers []Publisher
+
+// Publish публикует событие во все Publisher и объединяет их ошибки.
+func (ps Publishers) Publish(ctx context.Context, e Event) error {
+	var errs []error
+	for _, p := range ps {
+		if err := p.Publish(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Broker рассылает опубликованные события подписчикам.
+type Broker interface {
+	Publisher
+	// Subscribe возвращает канал событий пользователя и функцию отписки.
+	// После отписки канал закрывается.
+	Subscribe(userID int) (<-chan Event, func())
+	Close() error
+}
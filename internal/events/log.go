@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Log хранит последние события, чтобы переподключившийся клиент
+// мог получить пропущенное по Last-Event-ID.
+type Log interface {
+	// Append сохраняет событие и присваивает ему ID.
+	Append(ctx context.Context, e *Event) error
+	// Since возвращает события пользователя с ID больше lastID.
+	// complete равен false, если часть событий уже вытеснена из журнала.
+	Since(ctx context.Context, userID int, lastID int64) (events []Event, complete bool, err error)
+}
+
+// Recorder сохраняет событие в журнал и затем передает его дальше.
+type Recorder struct {
+	Log  Log
+	Next Publisher
+}
+
+// NewRecorder создает новый экземпляр Recorder.
+func NewRecorder(log Log, next Publisher) *Recorder {
+	return &Recorder{Log: log, Next: next}
+}
+
+// Publish сохраняет событие в журнал и публикует его. Если записать событие в журнал
+// не удалось, оно все равно публикуется (без ID): докачать его по Last-Event-ID будет нельзя,
+// но подписчики, webhooks, правила и уведомления его получат.
+func (r *Recorder) Publish(ctx context.Context, e Event) error {
+	var logErr error
+	if err := r.Log.Append(ctx, &e); err != nil {
+		e.ID = 0
+		logErr = fmt.Errorf("ошибка записи события в журнал: %w", err)
+	}
+	return errors.Join(logErr, r.Next.Publish(ctx, e))
+}
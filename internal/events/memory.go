@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// subscriberBuffer - размер буфера канала одного подписчика.
+const subscriberBuffer = 64
+
+// MemoryBroker рассылает события подписчикам внутри одного процесса.
+type MemoryBroker struct {
+	mu     sync.RWMutex
+	subs   map[int]map[chan Event]struct{}
+	closed bool
+}
+
+// NewMemoryBroker создает новый экземпляр MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[int]map[chan Event]struct{})}
+}
+
+// Publish доставляет событие всем подписчикам пользователя.
+func (b *MemoryBroker) Publish(_ context.Context, e Event) error {
+	b.dispatch(e)
+	return nil
+}
+
+func (b *MemoryBroker) dispatch(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs[e.UserID] {
+		select {
+		case ch <- e:
+		default:
+			// Медленный клиент не должен блокировать остальных
+			log.Printf("Буфер подписчика пользователя %d переполнен, событие %s пропущено", e.UserID, e.Type)
+		}
+	}
+}
+
+// Subscribe подписывает на события пользователя.
+func (b *MemoryBroker) Subscribe(userID int) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan Event]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[userID][ch]; !ok {
+				return
+			}
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Close закрывает каналы всех подписчиков.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for userID, chans := range b.subs {
+		for ch := range chans {
+			close(ch)
+		}
+		delete(b.subs, userID)
+	}
+	return nil
+}
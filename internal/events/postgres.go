@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyChannel - канал PostgreSQL, через который реплики обмениваются событиями.
+const notifyChannel = "kanban_events"
+
+// maxNotifyPayload - ограничение PostgreSQL на размер payload в NOTIFY (8000 байт) с запасом.
+const maxNotifyPayload = 7900
+
+// PostgresBroker рассылает события между репликами сервера через LISTEN/NOTIFY.
+// Каждая реплика получает в том числе свои собственные уведомления
+// и доставляет их локальным подписчикам.
+type PostgresBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+	local    *MemoryBroker
+	done     chan struct{}
+}
+
+// NewPostgresBroker подписывается на канал уведомлений и запускает их обработку.
+func NewPostgresBroker(db *sql.DB, dsn string) (*PostgresBroker, error) {
+	listener := pq.NewListener(dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Ошибка соединения LISTEN/NOTIFY: %v", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("ошибка подписки на канал %s: %w", notifyChannel, err)
+	}
+
+	b := &PostgresBroker{
+		db:       db,
+		listener: listener,
+		local:    NewMemoryBroker(),
+		done:     make(chan struct{}),
+	}
+	go b.listen()
+	return b, nil
+}
+
+// Publish отправляет событие всем репликам через pg_notify.
+func (b *PostgresBroker) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		// Слишком большое событие отправляем без данных, клиент перечитает задачу сам
+		e.Data = nil
+		if payload, err = json.Marshal(e); err != nil {
+			return fmt.Errorf("ошибка сериализации события: %w", err)
+		}
+	}
+	if _, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("ошибка отправки NOTIFY: %w", err)
+	}
+	return nil
+}
+
+// Subscribe подписывает на события пользователя.
+func (b *PostgresBroker) Subscribe(userID int) (<-chan Event, func()) {
+	return b.local.Subscribe(userID)
+}
+
+// Close останавливает прослушивание канала и закрывает подписки.
+func (b *PostgresBroker) Close() error {
+	close(b.done)
+	err := b.listener.Close()
+	b.local.Close()
+	return err
+}
+
+func (b *PostgresBroker) listen() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case n := <-b.listener.Notify:
+			if n == nil {
+				// Соединение было переустановлено, уведомления за это время потеряны
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Printf("Некорректное событие в канале %s: %v", notifyChannel, err)
+				continue
+			}
+			b.local.dispatch(e)
+		case <-time.After(90 * time.Second):
+			go func() {
+				if err := b.listener.Ping(); err != nil {
+					log.Printf("Ошибка проверки соединения LISTEN/NOTIFY: %v", err)
+				}
+			}()
+		}
+	}
+}
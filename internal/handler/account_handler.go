@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/mail"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+	// accountMailInterval ограничивает частоту писем одного назначения одному пользователю
+	accountMailInterval = time.Minute
+)
+
+// accountLinks - пути страниц, на которые ведут ссылки из писем, и сроки действия токенов.
+var accountLinks = map[string]struct {
+	path string
+	ttl  time.Duration
+}{
+	models.AccountTokenVerifyEmail:   {"/api/v1/email/verify", verifyEmailTTL},
+	models.AccountTokenResetPassword: {"/api/v1/password/reset", resetPasswordTTL},
+}
+
+// forgotPasswordMessage одинаков для существующих и несуществующих адресов.
+const forgotPasswordMessage = "If the address belongs to an account and is verified, a password reset link has been sent"
+
+// issueToken создает токен назначения purpose и ставит письмо со ссылкой на адрес пользователя.
+func (h *AuthHandler) issueToken(ctx context.Context, purpose string, user *models.User) error {
+	link := accountLinks[purpose]
+	token, hash, err := auth.NewActionToken(h.Secret, purpose)
+	if err != nil {
+		return fmt.Errorf("ошибка генерации токена: %w", err)
+	}
+	m, err := mail.AccountMail(purpose, user, *user.Email, h.BaseURL+link.path+"?token="+url.QueryEscape(token), link.ttl)
+	if err != nil {
+		return err
+	}
+	return h.Accounts.IssueAccountToken(ctx, &models.AccountToken{
+		Hash:      hash,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     *user.Email,
+		ExpiresAt: time.Now().Add(link.ttl),
+	}, &m, accountMailInterval)
+}
+
+// ForgotPassword godoc
+// @Summary Запросить сброс пароля
+// @Description Отправляет на подтвержденный адрес ссылку для сброса пароля, действующую 1 час. Ответ не зависит от того, существует ли пользователь с таким адресом
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param payload body models.PasswordForgotPayload true "Адрес"
+// @Success 202 {object} map[string]string "Запрос принят"
+// @Failure 400 {object} map[string]string "Адрес не указан"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /password/forgot [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload models.PasswordForgotPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	email := strings.TrimSpace(payload.Email)
+	if email == "" {
+		respondWithError(w, http.StatusBadRequest, "Email required")
+		return
+	}
+	user, err := h.Accounts.GetUserByVerifiedEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Ошибка при поиске пользователя для сброса пароля: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process request")
+		return
+	}
+	if user != nil {
+		// Ошибки только логируются: иной ответ выдал бы, что адрес зарегистрирован
+		err := h.issueToken(r.Context(), models.AccountTokenResetPassword, user)
+		if err != nil && !errors.Is(err, storage.ErrTokenThrottled) {
+			log.Printf("Ошибка при отправке ссылки для сброса пароля пользователю %d: %v", user.ID, err)
+		}
+	}
+	respondWithJSON(w, http.StatusAccepted, map[string]string{"message": forgotPasswordMessage})
+}
+
+// ResetPasswordPage godoc
+// @Summary Страница сброса пароля
+// @Description Открывается по ссылке из письма и показывает форму нового пароля. Токен расходуется только при отправке формы
+// @Tags auth
+// @Produce html
+// @Param token query string true "Токен из ссылки"
+// @Success 200 "Форма нового пароля"
+// @Failure 400 "Неверная ссылка"
+// @Router /password/reset [get]
+func (h *AuthHandler) ResetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, ok := auth.ActionTokenHash(h.Secret, models.AccountTokenResetPassword, token); !ok {
+		renderAccountPage(w, http.StatusBadRequest, mail.AccountPage{Title: "Сброс пароля", Message: "Ссылка недействительна."})
+		return
+	}
+	renderAccountPage(w, http.StatusOK, mail.AccountPage{
+		Title:    "Сброс пароля",
+		Message:  "После смены пароля все открытые сессии будут завершены.",
+		Action:   "?token=" + url.QueryEscape(token),
+		Button:   "Сохранить пароль",
+		Password: true,
+	})
+}
+
+// ResetPassword godoc
+// @Summary Сбросить пароль
+// @Description Устанавливает новый пароль по одноразовому токену из письма и завершает все сессии пользователя: выданные ранее JWT перестают приниматься. Принимает JSON или форму со страницы сброса (токен в параметре token)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param payload body models.PasswordResetPayload true "Токен и новый пароль"
+// @Success 200 {object} map[string]string "Пароль изменен"
+// @Failure 400 {object} map[string]string "Токен недействителен или истек"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /password/reset [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	form := isFormPost(r)
+	page := mail.AccountPage{Title: "Сброс пароля"}
+	var payload models.PasswordResetPayload
+	if form {
+		payload.Token = r.URL.Query().Get("token")
+		payload.Password = r.PostFormValue("password")
+	} else if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if payload.Password == "" {
+		page.Message = "Введите новый пароль."
+		respondAccount(w, form, http.StatusBadRequest, "Password required", page)
+		return
+	}
+	hash, ok := auth.ActionTokenHash(h.Secret, models.AccountTokenResetPassword, payload.Token)
+	if !ok {
+		page.Message = "Ссылка недействительна."
+		respondAccount(w, form, http.StatusBadRequest, "Invalid or expired token", page)
+		return
+	}
+	user, err := h.Accounts.ResetPassword(r.Context(), hash, payload.Password)
+	if errors.Is(err, storage.ErrTokenInvalid) {
+		page.Message = "Ссылка недействительна, уже использована или истекла. Запросите сброс пароля еще раз."
+		respondAccount(w, form, http.StatusBadRequest, "Invalid or expired token", page)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при сбросе пароля: %v", err)
+		page.Message = "Не удалось сменить пароль, попробуйте позже."
+		respondAccount(w, form, http.StatusInternalServerError, "Failed to reset password", page)
+		return
+	}
+	log.Printf("Пароль пользователя %d сброшен по ссылке из письма", user.ID)
+	page.Message = "Пароль изменен. Войдите с новым паролем."
+	respondAccount(w, form, http.StatusOK, "Password has been reset", page)
+}
+
+// VerifyEmailPage godoc
+// @Summary Страница подтверждения адреса
+// @Description Открывается по ссылке из письма и предлагает подтвердить адрес. Токен расходуется только при отправке формы, поэтому сканеры ссылок в почте его не используют
+// @Tags auth
+// @Produce html
+// @Param token query string true "Токен из ссылки"
+// @Success 200 "Страница подтверждения"
+// @Failure 400 "Неверная ссылка"
+// @Router /email/verify [get]
+func (h *AuthHandler) VerifyEmailPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, ok := auth.ActionTokenHash(h.Secret, models.AccountTokenVerifyEmail, token); !ok {
+		renderAccountPage(w, http.StatusBadRequest, mail.AccountPage{Title: "Подтверждение адреса", Message: "Ссылка недействительна."})
+		return
+	}
+	renderAccountPage(w, http.StatusOK, mail.AccountPage{
+		Title:  "Подтверждение адреса",
+		Action: "?token=" + url.QueryEscape(token),
+		Button: "Подтвердить адрес",
+	})
+}
+
+// VerifyEmail godoc
+// @Summary Подтвердить адрес
+// @Description Подтверждает адрес по одноразовому токену из письма. Принимает JSON или форму со страницы подтверждения (токен в параметре token)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param payload body models.EmailVerifyPayload true "Токен"
+// @Success 200 {object} map[string]string "Адрес подтвержден"
+// @Failure 400 {object} map[string]string "Токен недействителен или истек"
+// @Failure 409 {object} map[string]string "Адрес уже подтвержден другим пользователем"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /email/verify [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	form := isFormPost(r)
+	page := mail.AccountPage{Title: "Подтверждение адреса"}
+	var payload models.EmailVerifyPayload
+	if form {
+		payload.Token = r.URL.Query().Get("token")
+	} else if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	hash, ok := auth.ActionTokenHash(h.Secret, models.AccountTokenVerifyEmail, payload.Token)
+	if !ok {
+		page.Message = "Ссылка недействительна."
+		respondAccount(w, form, http.StatusBadRequest, "Invalid or expired token", page)
+		return
+	}
+	if _, err := h.Accounts.VerifyEmail(r.Context(), hash); err != nil {
+		if errors.Is(err, storage.ErrTokenInvalid) {
+			page.Message = "Ссылка недействительна, уже использована или истекла, либо адрес был изменен."
+			respondAccount(w, form, http.StatusBadRequest, "Invalid or expired token", page)
+			return
+		}
+		if errors.Is(err, storage.ErrEmailTaken) {
+			page.Message = "Этот адрес уже подтвержден другим пользователем."
+			respondAccount(w, form, http.StatusConflict, "Email is already verified by another user", page)
+			return
+		}
+		log.Printf("Ошибка при подтверждении адреса: %v", err)
+		page.Message = "Не удалось подтвердить адрес, попробуйте позже."
+		respondAccount(w, form, http.StatusInternalServerError, "Failed to verify email", page)
+		return
+	}
+	page.Message = "Адрес подтвержден."
+	respondAccount(w, form, http.StatusOK, "Email verified", page)
+}
+
+// SendVerification godoc
+// @Summary Отправить письмо для подтверждения адреса
+// @Description Отправляет ссылку для подтверждения текущего адреса пользователя, действующую 48 часов. Прежние ссылки перестают действовать. Адрес задается в /notifications/settings
+// @Tags auth
+// @Produce json
+// @Success 202 {object} map[string]string "Письмо поставлено в очередь"
+// @Failure 400 {object} map[string]string "Адрес не указан"
+// @Failure 409 {object} map[string]string "Адрес уже подтвержден"
+// @Failure 429 {object} map[string]string "Письмо отправлено недавно"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /email/verification [post]
+func (h *AuthHandler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	user, err := h.Accounts.GetAccount(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+	if user.Email == nil {
+		respondWithError(w, http.StatusBadRequest, "No email address set")
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		respondWithError(w, http.StatusConflict, "Email already verified")
+		return
+	}
+	if err := h.issueToken(r.Context(), models.AccountTokenVerifyEmail, user); err != nil {
+		if errors.Is(err, storage.ErrTokenThrottled) {
+			respondWithError(w, http.StatusTooManyRequests, "Verification email already sent, try again later")
+			return
+		}
+		log.Printf("Ошибка при отправке письма подтверждения пользователю %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, map[string]string{"message": "Verification email sent"})
+}
+
+// isFormPost сообщает, что запрос отправлен HTML-формой со страницы из письма, а не клиентом API.
+func isFormPost(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded"
+}
+
+// respondAccount отвечает страницей на отправку формы и JSON на запрос API.
+func respondAccount(w http.ResponseWriter, form bool, status int, message string, page mail.AccountPage) {
+	switch {
+	case form:
+		renderAccountPage(w, status, page)
+	case status >= http.StatusBadRequest:
+		respondWithError(w, status, message)
+	default:
+		respondWithJSON(w, status, map[string]string{"message": message})
+	}
+}
+
+func renderAccountPage(w http.ResponseWriter, status int, page mail.AccountPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Страницы содержат токен в адресе: не кешируем их
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := mail.RenderAccountPage(w, page); err != nil {
+		log.Printf("Ошибка вывода страницы: %v", err)
+	}
+}
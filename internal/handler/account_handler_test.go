@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// verifyingAccounts отвечает на VerifyEmail заданной ошибкой.
+type verifyingAccounts struct {
+	storage.AccountStore
+	err error
+}
+
+func (s verifyingAccounts) VerifyEmail(ctx context.Context, tokenHash string) (*models.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.User{ID: 1, Username: "alice"}, nil
+}
+
+// Адрес, уже подтвержденный другим пользователем, - конфликт (409), а не ошибка сервера.
+func TestVerifyEmailTakenAddress(t *testing.T) {
+	secret := []byte("test-secret")
+	token, _, err := auth.NewActionToken(secret, models.AccountTokenVerifyEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{storage.ErrTokenInvalid, http.StatusBadRequest},
+		{storage.ErrEmailTaken, http.StatusConflict},
+	} {
+		h := &AuthHandler{Accounts: verifyingAccounts{err: tc.err}, Secret: secret}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/email/verify", strings.NewReader(`{"token":"`+token+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.VerifyEmail(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("ошибка хранилища %v: статус %d, ожидался %d", tc.err, rec.Code, tc.want)
+		}
+	}
+}
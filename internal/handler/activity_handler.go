@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// parseActivityPage читает параметры пагинации limit и before из строки запроса.
+func parseActivityPage(r *http.Request) (before int64, limit int, err error) {
+	limit = defaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, errors.New("limit должен быть числом от 1 до 200")
+		}
+	}
+	if v := r.URL.Query().Get("before"); v != "" {
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 0 {
+			return 0, 0, errors.New("неверный формат параметра before")
+		}
+	}
+	return before, limit, nil
+}
+
+// GetTaskActivity godoc
+// @Summary Журнал изменений задачи
+// @Description Возвращает историю изменений задачи от новых к старым: кто, когда и что изменил. Доступна и для удаленных задач
+// @Tags activity
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param limit query int false "Размер страницы (1-200, по умолчанию 50)"
+// @Param before query int false "Значение next_before из предыдущей страницы"
+// @Success 200 {object} models.ActivityPage "Страница журнала"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/activity [get]
+func (h *TaskHandler) GetTaskActivity(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return
+	}
+	before, limit, err := parseActivityPage(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := h.Store.GetTaskActivity(r.Context(), id, userID, before, limit)
+	if err != nil {
+		log.Printf("Ошибка при получении журнала задачи %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить журнал изменений")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// GetActivity godoc
+// @Summary Журнал изменений всех задач
+// @Description Возвращает историю изменений всех задач пользователя от новых к старым
+// @Tags activity
+// @Produce json
+// @Param limit query int false "Размер страницы (1-200, по умолчанию 50)"
+// @Param before query int false "Значение next_before из предыдущей страницы"
+// @Success 200 {object} models.ActivityPage "Страница журнала"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /activity [get]
+func (h *TaskHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	before, limit, err := parseActivityPage(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := h.Store.GetActivity(r.Context(), userID, before, limit)
+	if err != nil {
+		log.Printf("Ошибка при получении журнала изменений: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить журнал изменений")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
@@ -14,15 +16,23 @@ import (
 
 type AuthHandler struct {
 	UserStore storage.UserStore
+	Accounts  storage.AccountStore
+	TwoFactor storage.TwoFactorStore
+	// Secret подписывает токены в ссылках из писем
+	Secret []byte
+	// BaseURL - внешний адрес API, на который ведут ссылки из писем
+	BaseURL string
+	// OIDC включает вход через провайдера OpenID Connect; nil - вход через провайдера выключен
+	OIDC *OIDCSettings
 }
 
-func NewAuthHandler(userStore storage.UserStore) *AuthHandler {
-	return &AuthHandler{UserStore: userStore}
+func NewAuthHandler(userStore storage.UserStore, accounts storage.AccountStore, twoFactor storage.TwoFactorStore, secret []byte, baseURL string) *AuthHandler {
+	return &AuthHandler{UserStore: userStore, Accounts: accounts, TwoFactor: twoFactor, Secret: secret, BaseURL: strings.TrimRight(baseURL, "/")}
 }
 
 // Register godoc
 // @Summary Регистрация нового пользователя
-// @Description Регистрирует нового пользователя по username и password
+// @Description Регистрирует нового пользователя по username и password. Если указан email, на него отправляется ссылка для подтверждения адреса
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -44,23 +54,36 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := &models.User{Username: payload.Username}
+	email, ok := normalizeEmail(payload.Email)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
+
+	user := &models.User{Username: payload.Username, Email: email}
 	_, err := h.UserStore.CreateUser(r.Context(), user, payload.Password)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "User already exists or DB error: "+err.Error())
 		return
 	}
+	if user.Email != nil {
+		// Пользователь уже создан: письмо можно запросить повторно через /email/verification
+		if err := h.issueToken(r.Context(), models.AccountTokenVerifyEmail, user); err != nil {
+			log.Printf("Ошибка при отправке письма подтверждения пользователю %d: %v", user.ID, err)
+		}
+	}
 	respondWithJSON(w, http.StatusCreated, map[string]string{"message": "User registered successfully"})
 }
 
 // Login godoc
 // @Summary Аутентификация пользователя
-// @Description Возвращает JWT-токен при успешном логине
+// @Description Возвращает JWT-токен при успешном логине. Если у пользователя включена 2FA, вместо JWT возвращается токен второго шага для POST /login/2fa
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param credentials body models.UserLoginPayload true "Данные для входа"
 // @Success 200 {object} map[string]string "Успешная аутентификация"
+// @Success 202 {object} models.LoginChallenge "Требуется код 2FA"
 // @Failure 401 {object} map[string]string "Неверные имя пользователя или пароль"
 // @Router /login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -80,10 +103,72 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Invalid username or password")
 		return
 	}
-	token, err := auth.GenerateJWT(user.ID, user.Username)
+	totp, err := h.TwoFactor.GetTOTP(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Ошибка при получении 2FA пользователя %d: %v", user.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	if totp != nil && totp.ConfirmedAt != nil {
+		challenge, expiresAt, err := auth.GenerateChallengeJWT(user.ID, user.TokenVersion)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+		respondWithJSON(w, http.StatusAccepted, models.LoginChallenge{TwoFactorRequired: true, ChallengeToken: challenge, ExpiresAt: expiresAt})
+		return
+	}
+	token, err := auth.GenerateJWT(user.ID, user.Username, user.TokenVersion)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// CompleteLogin godoc
+// @Summary Второй шаг входа с 2FA
+// @Description Обменивает токен второго шага из /login и код из приложения-аутентификатора (или код восстановления) на JWT. Токен второго шага действует 5 минут; после 5 неверных кодов подряд проверка блокируется на 15 минут
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param payload body models.TwoFactorLoginPayload true "Токен второго шага и код"
+// @Success 200 {object} map[string]string "Успешная аутентификация"
+// @Failure 401 {object} map[string]string "Неверный код или токен второго шага"
+// @Failure 429 {object} map[string]string "Слишком много неверных кодов"
+// @Router /login/2fa [post]
+func (h *AuthHandler) CompleteLogin(w http.ResponseWriter, r *http.Request) {
+	var payload models.TwoFactorLoginPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	userID, version, err := auth.ParseChallengeJWT(payload.ChallengeToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+	user, err := h.Accounts.GetAccount(r.Context(), userID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && user.TokenVersion != version) {
+		// Пароль сменили после первого шага
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при получении пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	if err := verifySecondFactor(r.Context(), h.TwoFactor, userID, payload.Code); err != nil {
+		respondSecondFactorError(w, userID, err)
+		return
+	}
+	token, err := auth.GenerateJWT(user.ID, user.Username, user.TokenVersion)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 	respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
-} 
\ No newline at end of file
+}
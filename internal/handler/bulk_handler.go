@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"kanban-backend/internal/models"
+)
+
+// maxBulkItems - максимальное число пар (операция, задача) в одном запросе.
+const maxBulkItems = 500
+
+// validateBulkRequest проверяет запрос до обращения к хранилищу.
+func validateBulkRequest(req *models.BulkRequest) error {
+	if len(req.Operations) == 0 {
+		return fmt.Errorf("список операций пуст")
+	}
+	total := 0
+	for i, op := range req.Operations {
+		switch op.Op {
+		case models.BulkOpArchive, models.BulkOpUnarchive, models.BulkOpDelete, models.BulkOpRestore:
+		case models.BulkOpUpdate:
+			f := op.Fields
+			if f == nil || (f.Title == nil && f.Description == nil && f.Status == nil) {
+				return fmt.Errorf("операция %d: для update нужно указать хотя бы одно поле в fields", i)
+			}
+			if f.Title != nil && strings.TrimSpace(*f.Title) == "" {
+				return fmt.Errorf("операция %d: название задачи не может быть пустым", i)
+			}
+		default:
+			return fmt.Errorf("операция %d: %q не поддерживается", i, op.Op)
+		}
+		if len(op.TaskIDs) == 0 {
+			return fmt.Errorf("операция %d: список task_ids пуст", i)
+		}
+		total += len(op.TaskIDs)
+	}
+	if total > maxBulkItems {
+		return fmt.Errorf("не больше %d задач в одном запросе", maxBulkItems)
+	}
+	return nil
+}
+
+// BulkTasks godoc
+// @Summary Массовое изменение задач
+// @Description Применяет операции update, archive, unarchive, delete и restore к нескольким задачам в одной транзакции. По умолчанию ошибка в любой задаче отменяет все изменения (ответ 422); с continue_on_error ошибочные задачи пропускаются
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param request body models.BulkRequest true "Операции"
+// @Success 200 {object} models.BulkResponse "Изменения сохранены; результаты по каждой задаче"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 422 {object} models.BulkResponse "Изменения отменены из-за ошибки в одной из задач"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/bulk [post]
+func (h *TaskHandler) BulkTasks(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req models.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if err := validateBulkRequest(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.Store.BulkChangeTasks(r.Context(), userID, req.Operations, req.ContinueOnError)
+	if err != nil {
+		log.Printf("Ошибка массового изменения задач: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось изменить задачи")
+		return
+	}
+	if !resp.Committed {
+		respondWithJSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
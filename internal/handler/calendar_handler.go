@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban-backend/internal/ical"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+	"kanban-backend/internal/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CalendarHandler отдает задачи со сроками в виде календарной ленты iCalendar.
+// Календарные приложения не умеют передавать Bearer-токен, поэтому лента
+// доступна по секретному отзываемому токену в URL.
+type CalendarHandler struct {
+	Tasks  storage.TaskStore
+	Tokens storage.CalendarTokenStore
+}
+
+// NewCalendarHandler создает новый экземпляр CalendarHandler.
+func NewCalendarHandler(tasks storage.TaskStore, tokens storage.CalendarTokenStore) *CalendarHandler {
+	return &CalendarHandler{Tasks: tasks, Tokens: tokens}
+}
+
+// CreateToken godoc
+// @Summary Создать токен календарной ленты
+// @Description Создает секретный токен для подписки на ленту сроков задач в календарном приложении. Токен возвращается только в этом ответе
+// @Tags calendar
+// @Accept json
+// @Produce json
+// @Param token body models.CalendarTokenCreatePayload false "Данные токена"
+// @Success 201 {object} models.CalendarToken "Токен создан"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /calendar/tokens [post]
+func (h *CalendarHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.CalendarTokenCreatePayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	token := models.CalendarToken{Name: strings.TrimSpace(payload.Name), UserID: userID}
+	if token.Token, err = webhook.GenerateSecret(); err != nil {
+		log.Printf("Ошибка генерации токена календаря: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать токен")
+		return
+	}
+	if _, err := h.Tokens.CreateToken(r.Context(), &token); err != nil {
+		log.Printf("Ошибка при создании токена календаря: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать токен")
+		return
+	}
+	token.FeedPath = "/calendar/" + token.Token + ".ics"
+	// Токен показывается один раз и не должен сохраняться ни в кешах, ни для Idempotency-Key
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusCreated, token)
+}
+
+// GetTokens godoc
+// @Summary Получить токены календарных лент
+// @Description Возвращает токены пользователя без секретов
+// @Tags calendar
+// @Produce json
+// @Success 200 {array} models.CalendarToken "Список токенов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /calendar/tokens [get]
+func (h *CalendarHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tokens, err := h.Tokens.GetTokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении токенов календаря: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить токены")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+// DeleteToken godoc
+// @Summary Отозвать токен календарной ленты
+// @Description Удаляет токен; лента по нему перестает открываться
+// @Tags calendar
+// @Param tokenID path int true "ID токена"
+// @Success 204 "Токен отозван"
+// @Failure 400 {object} map[string]string "Неверный ID токена"
+// @Failure 404 {object} map[string]string "Токен не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /calendar/tokens/{tokenID} [delete]
+func (h *CalendarHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID токена")
+		return
+	}
+	if err := h.Tokens.DeleteToken(r.Context(), id, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Токен не найден")
+		} else {
+			log.Printf("Ошибка при удалении токена календаря %d: %v", id, err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось отозвать токен")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Feed godoc
+// @Summary Календарная лента сроков задач
+// @Description Возвращает неархивные задачи со сроком в формате iCalendar: событиями VEVENT или, с type=todo, задачами VTODO. UID записи стабилен и строится из ID задачи. Авторизация по токену в URL вместо Bearer. Поддерживается If-None-Match
+// @Tags calendar
+// @Produce text/calendar
+// @Param token path string true "Токен ленты"
+// @Param type query string false "Тип записей: event (по умолчанию) или todo"
+// @Success 200 {string} string "Календарь iCalendar"
+// @Success 304 "Лента не изменилась"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Токен не найден или отозван"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /calendar/{token}.ics [get]
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	asTodo := false
+	switch r.URL.Query().Get("type") {
+	case "", "event":
+	case "todo":
+		asTodo = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "type должен быть event или todo")
+		return
+	}
+
+	userID, err := h.Tokens.LookupToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			// Одинаковый ответ для неизвестного и отозванного токена
+			respondWithError(w, http.StatusNotFound, "Лента не найдена")
+		} else {
+			log.Printf("Ошибка при проверке токена календаря: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось получить ленту")
+		}
+		return
+	}
+
+	tasks, err := h.Tasks.GetAllTasks(r.Context(), userID, false)
+	if err != nil {
+		log.Printf("Ошибка при получении задач для ленты пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить ленту")
+		return
+	}
+	due := tasks[:0]
+	for _, task := range tasks {
+		if task.DueAt != nil {
+			due = append(due, task)
+		}
+	}
+	// Стабильный порядок, чтобы ETag не зависел от порядка выборки
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	modified, err := h.Tasks.GetTaskModifiedTimes(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении времени изменения задач для ленты пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить ленту")
+		return
+	}
+
+	var buf bytes.Buffer
+	writeCalendar(&buf, due, modified, asTodo, time.Now())
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := strconv.Quote(hex.EncodeToString(sum[:16]))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("Ошибка при отправке ленты пользователя %d: %v", userID, err)
+	}
+}
+
+// writeCalendar записывает задачи как VEVENT или VTODO. Без METHOD DTSTAMP означает время
+// последнего изменения (RFC 5545, 3.8.7.2), поэтому берется из журнала изменений задачи.
+// Для задач, созданных до появления журнала, берется последнее изменение любой задачи,
+// а если журнал пуст - время формирования ленты now. SEQUENCE равен версии задачи.
+func writeCalendar(buf *bytes.Buffer, tasks []models.Task, modified map[int]time.Time, asTodo bool, now time.Time) {
+	var latest time.Time
+	for _, t := range modified {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		latest = now
+	}
+	cal := ical.NewWriter(buf)
+	cal.Property("BEGIN", "VCALENDAR")
+	cal.Property("VERSION", "2.0")
+	cal.Property("PRODID", "-//kanban-backend//Tasks//RU")
+	cal.Property("CALSCALE", "GREGORIAN")
+	cal.Text("X-WR-CALNAME", "Задачи")
+	for _, task := range tasks {
+		component := "VEVENT"
+		if asTodo {
+			component = "VTODO"
+		}
+		cal.Property("BEGIN", component)
+		cal.Property("UID", "task-"+strconv.Itoa(task.ID)+"@kanban-backend")
+		stamp, ok := modified[task.ID]
+		if !ok {
+			stamp = latest
+		}
+		cal.Time("DTSTAMP", stamp)
+		cal.Property("SEQUENCE", strconv.Itoa(task.Version))
+		cal.Text("SUMMARY", task.Title)
+		if task.Description != "" {
+			cal.Text("DESCRIPTION", task.Description)
+		}
+		if asTodo {
+			cal.Time("DUE", *task.DueAt)
+			if task.Status == "completed" {
+				cal.Property("STATUS", "COMPLETED")
+			} else {
+				cal.Property("STATUS", "NEEDS-ACTION")
+			}
+		} else {
+			// Без DTEND событие длится ноль секунд и отображается как отметка времени
+			cal.Time("DTSTART", *task.DueAt)
+			cal.Property("TRANSP", "TRANSPARENT")
+		}
+		cal.Text("CATEGORIES", task.Status)
+		cal.Property("END", component)
+	}
+	cal.Property("END", "VCALENDAR")
+	// bytes.Buffer не возвращает ошибок записи
+}
+
+// etagMatches сообщает, совпадает ли etag с одним из значений If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+const (
+	defaultCFDDays = 30
+	maxCFDDays     = 2 * 366
+)
+
+// GetCumulativeFlow godoc
+// @Summary Данные диаграммы совокупного потока
+// @Description Возвращает количество задач в каждом статусе на конец каждого дня (UTC) периода. Считается по истории статусов; завершенные дни берутся из ежедневных снимков. Статус учитывается под тем именем, которое он имел в тот день: переименованный статус образует отдельный слой, а статусы, появившиеся или исчезнувшие в течение периода, имеют нули в остальные дни
+// @Tags metrics
+// @Produce json
+// @Produce text/csv
+// @Param from query string false "Первый день (2006-01-02), по умолчанию 29 дней до to"
+// @Param to query string false "Последний день (2006-01-02), по умолчанию сегодня"
+// @Param statuses query string false "Порядок слоев: статусы через запятую; остальные статусы идут следом по алфавиту"
+// @Param format query string false "json (по умолчанию) или csv"
+// @Success 200 {object} models.CumulativeFlow "Данные диаграммы"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /metrics/cfd [get]
+func (h *TaskHandler) GetCumulativeFlow(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		respondWithError(w, http.StatusBadRequest, "Формат должен быть json или csv")
+		return
+	}
+	from, to, err := parseDayRange(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	counts, err := h.Store.GetStatusCounts(r.Context(), userID, from, to)
+	if err != nil {
+		log.Printf("Ошибка при расчете CFD пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось рассчитать данные диаграммы")
+		return
+	}
+	var order []string
+	if v := r.URL.Query().Get("statuses"); v != "" {
+		order = parseStatusList(v, "")
+	}
+	cfd := buildCumulativeFlow(from, to, order, counts)
+
+	if format == "json" {
+		respondWithJSON(w, http.StatusOK, cfd)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="cfd-`+cfd.From+`-`+cfd.To+`.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"date"}, cfd.Statuses...))
+	for _, day := range cfd.Days {
+		record := []string{day.Date}
+		for _, s := range cfd.Statuses {
+			record = append(record, strconv.Itoa(day.Counts[s]))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Ошибка при отправке CFD пользователя %d: %v", userID, err)
+	}
+}
+
+// parseDayRange читает период from/to в днях (UTC).
+func parseDayRange(r *http.Request) (from, to time.Time, err error) {
+	to = time.Now().UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			return from, to, errors.New("to должен быть датой в формате 2006-01-02")
+		}
+	}
+	from = to.AddDate(0, 0, -(defaultCFDDays - 1))
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			return from, to, errors.New("from должен быть датой в формате 2006-01-02")
+		}
+	}
+	if from.After(to) {
+		return from, to, errors.New("from не может быть позже to")
+	}
+	if to.Sub(from) >= maxCFDDays*24*time.Hour {
+		return from, to, errors.New("период не может быть длиннее " + strconv.Itoa(maxCFDDays) + " дней")
+	}
+	return from, to, nil
+}
+
+// buildCumulativeFlow раскладывает количества по дням и заполняет пропуски нулями.
+func buildCumulativeFlow(from, to time.Time, order []string, counts []models.StatusCount) *models.CumulativeFlow {
+	byDay := map[string]map[string]int{}
+	seen := map[string]bool{}
+	for _, c := range counts {
+		day := c.Day.Format("2006-01-02")
+		if byDay[day] == nil {
+			byDay[day] = map[string]int{}
+		}
+		byDay[day][c.Status] += c.Count
+		seen[c.Status] = true
+	}
+
+	statuses := []string{}
+	for _, s := range order {
+		if s != "" && !contains(statuses, s) {
+			statuses = append(statuses, s)
+		}
+	}
+	var rest []string
+	for s := range seen {
+		if !contains(statuses, s) {
+			rest = append(rest, s)
+		}
+	}
+	sort.Strings(rest)
+	statuses = append(statuses, rest...)
+
+	cfd := &models.CumulativeFlow{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Statuses: statuses,
+		Days:     []models.CumulativeFlowDay{},
+	}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := models.CumulativeFlowDay{Date: d.Format("2006-01-02"), Counts: make(map[string]int, len(statuses))}
+		for _, s := range statuses {
+			day.Counts[s] = byDay[day.Date][s]
+		}
+		cfd.Days = append(cfd.Days, day)
+	}
+	return cfd
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
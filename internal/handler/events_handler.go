@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait - время на отправку одного сообщения клиенту.
+	wsWriteWait = 10 * time.Second
+	// wsPongWait - сколько ждать pong от клиента, прежде чем считать соединение мертвым.
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod - период отправки ping, должен быть меньше wsPongWait.
+	wsPingPeriod = wsPongWait * 9 / 10
+
+	// sseWriteWait - время на отправку одного события SSE.
+	sseWriteWait = 10 * time.Second
+	// sseHeartbeat - период отправки комментария-пинга, чтобы прокси не закрывали соединение.
+	sseHeartbeat = 25 * time.Second
+	// sseResetEvent говорит клиенту, что пропущенные события недоступны
+	// и состояние нужно перечитать целиком.
+	sseResetEvent = "stream.reset"
+	// sseDedupWindow - сколько последних ID событий помнит поток, чтобы не отправить событие дважды.
+	sseDedupWindow = 1024
+
+	// streamTicketTTL - срок действия билета: его используют сразу после получения.
+	streamTicketTTL = 30 * time.Second
+)
+
+// EventsHandler отдает события об изменении задач в реальном времени.
+type EventsHandler struct {
+	Broker   events.Broker
+	Log      events.Log
+	Tickets  storage.StreamTicketStore
+	upgrader websocket.Upgrader
+}
+
+// NewEventsHandler создает новый экземпляр EventsHandler.
+// allowedOrigins - те же источники, что разрешены в CORS.
+func NewEventsHandler(broker events.Broker, eventLog events.Log, tickets storage.StreamTicketStore, allowedOrigins []string) *EventsHandler {
+	origins := make(map[string]struct{}, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		origins[o] = struct{}{}
+	}
+	return &EventsHandler{
+		Broker:  broker,
+		Log:     eventLog,
+		Tickets: tickets,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" {
+					return true // Не браузерный клиент
+				}
+				_, ok := origins[origin]
+				return ok
+			},
+		},
+	}
+}
+
+// IssueTicket godoc
+// @Summary Получить билет для подключения к потоку событий
+// @Description Браузер не может передать заголовок Authorization при открытии WebSocket или EventSource. Билет передается в параметре ticket при подключении к /ws или /events, действует 30 секунд и только для одного подключения
+// @Tags events
+// @Produce json
+// @Success 201 {object} models.StreamTicket "Билет выдан"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /events/ticket [post]
+func (h *EventsHandler) IssueTicket(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	ticket, hash, err := auth.NewStreamTicket()
+	if err != nil {
+		log.Printf("Ошибка генерации билета: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось выдать билет")
+		return
+	}
+	expiresAt := time.Now().Add(streamTicketTTL)
+	if err := h.Tickets.IssueStreamTicket(r.Context(), hash, userID, expiresAt); err != nil {
+		log.Printf("Ошибка сохранения билета пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось выдать билет")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusCreated, models.StreamTicket{Ticket: ticket, ExpiresAt: expiresAt})
+}
+
+// Subscribe godoc
+// @Summary Подписка на события задач (WebSocket)
+// @Description Открывает WebSocket, в который приходят события task.created и task.deleted по задачам пользователя. Браузер авторизуется одноразовым билетом из POST /events/ticket, остальные клиенты могут передать JWT в заголовке Authorization
+// @Tags events
+// @Param ticket query string false "Билет из POST /events/ticket"
+// @Success 101 "Соединение переключено на WebSocket"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Router /ws [get]
+func (h *EventsHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade сам отправляет ответ с ошибкой
+		log.Printf("Ошибка установки WebSocket соединения: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ch, unsubscribe := h.Broker.Subscribe(userID)
+	defer unsubscribe()
+
+	// Читаем входящие сообщения только ради pong и закрытия соединения
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case e, ok := <-ch:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				log.Printf("Ошибка отправки события пользователю %d: %v", userID, err)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Stream godoc
+// @Summary Поток событий задач (Server-Sent Events)
+// @Description Отдает те же события, что и WebSocket, в формате text/event-stream. При переподключении с заголовком Last-Event-ID сначала отправляются пропущенные события из журнала. Браузер авторизуется одноразовым билетом из POST /events/ticket; билет нельзя использовать повторно, поэтому при обрыве клиент получает новый билет и передает ID последнего события в параметре last_event_id
+// @Tags events
+// @Produce text/event-stream
+// @Param ticket query string false "Билет из POST /events/ticket"
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Param last_event_id query int false "То же, что Last-Event-ID, для нового EventSource"
+// @Success 200 "Поток событий"
+// @Failure 400 {object} map[string]string "Неверный Last-Event-ID"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /events [get]
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var lastID int64
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		// Новый EventSource не позволяет задать заголовок
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v != "" {
+		lastID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || lastID < 0 {
+			respondWithError(w, http.StatusBadRequest, "Неверный формат Last-Event-ID")
+			return
+		}
+	}
+
+	// Подписываемся до чтения журнала, чтобы не потерять события между ними
+	ch, unsubscribe := h.Broker.Subscribe(userID)
+	defer unsubscribe()
+
+	var missed []events.Event
+	complete := true
+	if lastID > 0 {
+		missed, complete, err = h.Log.Since(r.Context(), userID, lastID)
+		if err != nil {
+			log.Printf("Ошибка чтения журнала событий пользователя %d: %v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось получить пропущенные события")
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Отключаем буферизацию в nginx
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		if err := writeSSE(w, rc, 0, sseResetEvent, []byte("{}")); err != nil {
+			return
+		}
+	}
+	// ID присваиваются после коммита, поэтому параллельные изменения могут прийти не по порядку:
+	// событие с меньшим ID нельзя отбрасывать, повторы отсекаются по уже отправленным ID
+	sent := newRecentIDs(sseDedupWindow)
+	for _, e := range missed {
+		if err := writeSSEEvent(w, rc, e); err != nil {
+			return
+		}
+		sent.add(e.ID)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.ID != 0 && !sent.add(e.ID) {
+				continue // Уже отправлено из журнала
+			}
+			if err := writeSSEEvent(w, rc, e); err != nil {
+				log.Printf("Ошибка отправки события пользователю %d: %v", userID, err)
+				return
+			}
+		case <-ticker.C:
+			rc.SetWriteDeadline(time.Now().Add(sseWriteWait))
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// recentIDs помнит последние size добавленных ID.
+type recentIDs struct {
+	set  map[int64]struct{}
+	ring []int64
+	next int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{set: make(map[int64]struct{}, size), ring: make([]int64, 0, size)}
+}
+
+// add запоминает id и сообщает, не встречался ли он раньше.
+func (r *recentIDs) add(id int64) bool {
+	if _, ok := r.set[id]; ok {
+		return false
+	}
+	if len(r.ring) < cap(r.ring) {
+		r.ring = append(r.ring, id)
+	} else {
+		delete(r.set, r.ring[r.next])
+		r.ring[r.next] = id
+		r.next = (r.next + 1) % len(r.ring)
+	}
+	r.set[id] = struct{}{}
+	return true
+}
+
+func writeSSEEvent(w http.ResponseWriter, rc *http.ResponseController, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeSSE(w, rc, e.ID, e.Type, data)
+}
+
+// writeSSE отправляет одно событие. Сервер выставляет общий WriteTimeout
+// на все соединение, поэтому для потока дедлайн продлевается перед каждой записью.
+func writeSSE(w http.ResponseWriter, rc *http.ResponseController, id int64, eventType string, data []byte) error {
+	rc.SetWriteDeadline(time.Now().Add(sseWriteWait))
+	if id != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+// memoryTickets хранит билеты в памяти с той же семантикой, что и хранилище в PostgreSQL.
+type memoryTickets struct {
+	mu      sync.Mutex
+	tickets map[string]int
+}
+
+func (s *memoryTickets) IssueStreamTicket(ctx context.Context, ticketHash string, userID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickets[ticketHash] = userID
+	return nil
+}
+
+func (s *memoryTickets) UseStreamTicket(ctx context.Context, ticketHash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userID, ok := s.tickets[ticketHash]
+	if !ok {
+		return 0, storage.ErrTokenInvalid
+	}
+	delete(s.tickets, ticketHash)
+	return userID, nil
+}
+
+// noSessions отклоняет любой JWT: в этих тестах клиент приходит только с билетом.
+type noSessions struct{}
+
+func (noSessions) SessionVersion(ctx context.Context, userID int) (int, error) {
+	return 0, storage.ErrNotFound
+}
+
+// fixedLog отдает одно и то же пропущенное событие после любого lastID.
+type fixedLog struct {
+	events.Log
+	missed events.Event
+}
+
+func (l fixedLog) Since(ctx context.Context, userID int, lastID int64) ([]events.Event, bool, error) {
+	if lastID >= l.missed.ID {
+		return nil, true, nil
+	}
+	return []events.Event{l.missed}, true, nil
+}
+
+// newEventsTest поднимает /events/ticket от имени пользователя 1, а /ws и /events с проверкой билета.
+func newEventsTest(t *testing.T) (*httptest.Server, *events.MemoryBroker) {
+	t.Helper()
+	broker := events.NewMemoryBroker()
+	missed, _ := events.New("task.deleted", 1, 3, nil)
+	missed.ID = 10
+	h := NewEventsHandler(broker, fixedLog{missed: missed}, &memoryTickets{tickets: map[string]int{}}, nil)
+	r := chi.NewRouter()
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "user_id", 1)))
+		})
+	}).Post("/events/ticket", h.IssueTicket)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.StreamAuthMiddleware(noSessions{}, h.Tickets))
+		r.Get("/ws", h.Subscribe)
+		r.Get("/events", h.Stream)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		srv.Close()
+		broker.Close()
+	})
+	return srv, broker
+}
+
+func issueTicket(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/events/ticket", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Cache-Control") != "no-store" {
+		t.Fatalf("выдача билета: статус %d, Cache-Control %q", resp.StatusCode, resp.Header.Get("Cache-Control"))
+	}
+	var ticket models.StreamTicket
+	if err := json.NewDecoder(resp.Body).Decode(&ticket); err != nil || ticket.Ticket == "" {
+		t.Fatalf("неверный ответ с билетом: %v", err)
+	}
+	return ticket.Ticket
+}
+
+// Браузер подключается к /ws без заголовка Authorization, только с билетом.
+func TestSubscribeWithTicket(t *testing.T) {
+	srv, broker := newEventsTest(t)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("подключение без билета и JWT должно вернуть 401, получено %v", err)
+	}
+
+	ticket := issueTicket(t, srv)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+ticket, nil)
+	if err != nil {
+		t.Fatalf("подключение с билетом: %v", err)
+	}
+	defer conn.Close()
+
+	// Обработчик подписывается уже после ответа на рукопожатие, поэтому событие
+	// публикуется повторно, пока клиент его не получит
+	e, _ := events.New("task.created", 1, 7, nil)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				broker.Publish(context.Background(), e)
+			}
+		}
+	}()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got events.Event
+	if err := conn.ReadJSON(&got); err != nil {
+		t.Fatalf("событие не получено: %v", err)
+	}
+	if got.Type != "task.created" || got.TaskID != 7 {
+		t.Errorf("получено событие %+v", got)
+	}
+
+	// Билет одноразовый
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+ticket, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("повторное подключение с тем же билетом должно вернуть 401, получено %v", err)
+	}
+}
+
+// EventSource в браузере тоже не передает Authorization: поток открывается по билету,
+// а ID последнего события при новом подключении передается в адресе.
+func TestStreamWithTicket(t *testing.T) {
+	srv, _ := newEventsTest(t)
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("поток без билета и JWT: статус %d, ожидался 401", resp.StatusCode)
+	}
+
+	ticket := issueTicket(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?last_event_id=9&ticket="+ticket, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("поток с билетом: статус %d", resp.StatusCode)
+	}
+	buf := make([]byte, 512)
+	n, err := resp.Body.Read(buf)
+	if err != nil {
+		t.Fatalf("пропущенное событие не получено: %v", err)
+	}
+	if got := string(buf[:n]); !strings.HasPrefix(got, "id: 10\nevent: task.deleted\n") {
+		t.Errorf("первое событие потока: %q", got)
+	}
+
+	// Билет одноразовый
+	resp, err = http.Get(srv.URL + "/events?ticket=" + ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("повторное использование билета: статус %d, ожидался 401", resp.StatusCode)
+	}
+}
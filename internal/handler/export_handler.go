@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/customfields"
+	"kanban-backend/internal/models"
+)
+
+const (
+	maxImportSize  = 10 << 20
+	maxImportTasks = 5000
+	// exportWriteWait - дедлайн записи, продлеваемый во время потоковой выгрузки.
+	exportWriteWait = 10 * time.Second
+)
+
+var csvExportHeader = []string{"id", "title", "description", "status", "archived_at", "due_at", "rrule", "estimate"}
+
+func toExportedTask(task *models.Task) models.ExportedTask {
+	return models.ExportedTask{
+		ID:           task.ID,
+		Title:        task.Title,
+		Description:  task.Description,
+		Status:       task.Status,
+		ArchivedAt:   task.ArchivedAt,
+		DueAt:        task.DueAt,
+		RRule:        task.RRule,
+		Estimate:     task.Estimate,
+		CustomFields: task.CustomFields,
+	}
+}
+
+// ExportTasks godoc
+// @Summary Экспорт задач
+// @Description Выгружает все задачи пользователя, кроме задач в корзине, в формате JSON (версионированный документ models.TaskExport) или CSV. Ответ отдается потоком
+// @Tags export
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "json (по умолчанию) или csv"
+// @Success 200 {object} models.TaskExport "Документ экспорта"
+// @Failure 400 {object} map[string]string "Неизвестный формат"
+// @Router /export [get]
+func (h *TaskHandler) ExportTasks(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		respondWithError(w, http.StatusBadRequest, "Формат должен быть json или csv")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	filename := fmt.Sprintf("tasks-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	written := 0
+	// Сервер ограничивает запись WriteTimeout, поэтому дедлайн продлевается по ходу выгрузки
+	extendDeadline := func() {
+		if written%100 == 0 {
+			rc.SetWriteDeadline(time.Now().Add(exportWriteWait))
+		}
+		written++
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write(csvExportHeader)
+		err = h.Store.ExportTasks(r.Context(), userID, func(task *models.Task) error {
+			extendDeadline()
+			return cw.Write([]string{strconv.Itoa(task.ID), task.Title, task.Description, task.Status,
+				formatCSVTime(task.ArchivedAt), formatCSVTime(task.DueAt), task.RRule, formatCSVFloat(task.Estimate)})
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"version":%d,"exported_at":%q,"tasks":[`, models.ExportVersion, time.Now().UTC().Format(time.RFC3339))
+		first := true
+		err = h.Store.ExportTasks(r.Context(), userID, func(task *models.Task) error {
+			extendDeadline()
+			data, err := json.Marshal(toExportedTask(task))
+			if err != nil {
+				return err
+			}
+			if !first {
+				io.WriteString(w, ",")
+			}
+			first = false
+			_, err = w.Write(data)
+			return err
+		})
+		if err == nil {
+			_, err = io.WriteString(w, "]}")
+		}
+	}
+	if err != nil {
+		// Заголовки уже отправлены: клиент получит обрезанный документ
+		log.Printf("Ошибка экспорта задач пользователя %d: %v", userID, err)
+	}
+}
+
+// ImportTasks godoc
+// @Summary Импорт задач
+// @Description Создает задачи из документа экспорта (JSON или CSV). Все задачи проверяются заранее; при ошибках ни одна задача не создается. С dry_run=true выполняется только проверка
+// @Tags export
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "json или csv; по умолчанию определяется по Content-Type"
+// @Param dry_run query bool false "Только проверить документ"
+// @Param document body models.TaskExport true "Документ экспорта"
+// @Success 200 {object} models.ImportReport "Результат проверки (dry_run)"
+// @Success 201 {object} models.ImportReport "Задачи созданы"
+// @Failure 400 {object} map[string]string "Неверный формат документа"
+// @Failure 422 {object} models.ImportReport "Документ содержит ошибки, задачи не созданы"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /import [post]
+func (h *TaskHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверное значение dry_run")
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = "csv"
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	defer body.Close()
+	var rows []models.ExportedTask
+	var report models.ImportReport
+	switch format {
+	case "json":
+		rows, err = decodeJSONExport(body)
+	case "csv":
+		rows, report.Errors, err = decodeCSVExport(body)
+	default:
+		err = errors.New("формат должен быть json или csv")
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный документ импорта: "+err.Error())
+		return
+	}
+	if len(rows) > maxImportTasks {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Не больше %d задач за один импорт", maxImportTasks))
+		return
+	}
+
+	fields, err := h.Fields.GetFields(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении полей пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось импортировать задачи")
+		return
+	}
+	report.DryRun = dryRun
+	report.Total = len(rows)
+	tasks := make([]models.Task, 0, len(rows))
+	rowErrors := make([][]string, len(rows))
+	var userIDs []int
+	for i, row := range rows {
+		errs := validateExportedTask(row)
+		rrule, err := normalizeRecurrence(row.RRule, row.DueAt)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		customFields, err := customfields.Normalize(fields, row.CustomFields)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		userIDs = append(userIDs, customfields.UserIDs(fields, customFields)...)
+		rowErrors[i] = errs
+		tasks = append(tasks, models.Task{
+			Title:        strings.TrimSpace(row.Title),
+			Description:  row.Description,
+			Status:       row.Status,
+			ArchivedAt:   row.ArchivedAt,
+			DueAt:        row.DueAt,
+			RRule:        rrule,
+			Estimate:     row.Estimate,
+			CustomFields: customFields,
+		})
+	}
+	missing, err := h.missingUsers(r.Context(), userIDs)
+	if err != nil {
+		log.Printf("Ошибка при проверке пользователей: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось импортировать задачи")
+		return
+	}
+	notFound := make(map[int]bool, len(missing))
+	for _, id := range missing {
+		notFound[id] = true
+	}
+	for i, task := range tasks {
+		for _, id := range customfields.UserIDs(fields, task.CustomFields) {
+			if notFound[id] {
+				rowErrors[i] = append(rowErrors[i], fmt.Sprintf("пользователь %d не найден", id))
+			}
+		}
+		if len(rowErrors[i]) > 0 {
+			report.Errors = append(report.Errors, models.ImportRowError{Row: i + 1, Errors: rowErrors[i]})
+		}
+	}
+	if report.Errors == nil {
+		report.Errors = []models.ImportRowError{}
+	}
+	if len(report.Errors) > 0 {
+		status := http.StatusUnprocessableEntity
+		if dryRun {
+			status = http.StatusOK
+		}
+		respondWithJSON(w, status, report)
+		return
+	}
+	if dryRun {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if err := h.Store.ImportTasks(r.Context(), userID, tasks); err != nil {
+		log.Printf("Ошибка импорта задач: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось импортировать задачи")
+		return
+	}
+	report.Created = len(tasks)
+	for _, t := range tasks {
+		report.TaskIDs = append(report.TaskIDs, t.ID)
+	}
+	respondWithJSON(w, http.StatusCreated, report)
+}
+
+func decodeJSONExport(r io.Reader) ([]models.ExportedTask, error) {
+	var doc models.TaskExport
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != models.ExportVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия формата %d", doc.Version)
+	}
+	return doc.Tasks, nil
+}
+
+// decodeCSVExport читает CSV с заголовком. Столбцы определяются по заголовку,
+// неизвестные столбцы игнорируются. Ошибки в отдельных строках возвращаются в rowErrors.
+func decodeCSVExport(r io.Reader) (rows []models.ExportedTask, rowErrors []models.ImportRowError, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось прочитать заголовок CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, nil, errors.New("в CSV нет столбца title")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: n, Errors: []string{parseErr.Err.Error()}})
+				rows = append(rows, models.ExportedTask{})
+				continue
+			}
+			return nil, nil, err
+		}
+		row := models.ExportedTask{
+			Title:       field(record, "title"),
+			Description: field(record, "description"),
+			Status:      field(record, "status"),
+			RRule:       field(record, "rrule"),
+		}
+		for name, dst := range map[string]**time.Time{"archived_at": &row.ArchivedAt, "due_at": &row.DueAt} {
+			v := field(record, name)
+			if v == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: n, Errors: []string{name + " должен быть в формате RFC 3339"}})
+				continue
+			}
+			*dst = &t
+		}
+		if v := field(record, "estimate"); v != "" {
+			estimate, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: n, Errors: []string{"estimate должен быть числом"}})
+			} else {
+				row.Estimate = &estimate
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func formatCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatCSVFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// validateExportedTask проверяет задачу по ограничениям таблицы tasks.
+func validateExportedTask(row models.ExportedTask) []string {
+	var errs []string
+	title := strings.TrimSpace(row.Title)
+	if title == "" {
+		errs = append(errs, "название задачи не может быть пустым")
+	} else if utf8.RuneCountInString(title) > 255 {
+		errs = append(errs, "название задачи длиннее 255 символов")
+	}
+	if utf8.RuneCountInString(row.Status) > 50 {
+		errs = append(errs, "статус длиннее 50 символов")
+	}
+	if err := validateEstimate(row.Estimate); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kanban-backend/internal/customfields"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FieldHandler обрабатывает HTTP запросы, связанные с определениями пользовательских полей.
+type FieldHandler struct {
+	Fields storage.FieldStore
+}
+
+// NewFieldHandler создает новый экземпляр FieldHandler.
+func NewFieldHandler(fields storage.FieldStore) *FieldHandler {
+	return &FieldHandler{Fields: fields}
+}
+
+// CreateField godoc
+// @Summary Создать пользовательское поле
+// @Description Определяет поле задач с типом и правилами проверки. Досок нет, поэтому поля действуют для всех задач пользователя
+// @Tags fields
+// @Accept json
+// @Produce json
+// @Param field body models.CustomFieldPayload true "Определение поля"
+// @Success 201 {object} models.CustomField "Поле создано"
+// @Failure 400 {object} map[string]string "Неверное определение поля"
+// @Failure 409 {object} map[string]string "Поле с таким ключом уже существует"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /fields [post]
+func (h *FieldHandler) CreateField(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	field, ok := decodeField(w, r, userID)
+	if !ok {
+		return
+	}
+	fields, err := h.Fields.GetFields(r.Context(), userID)
+	if err != nil {
+		respondFieldError(w, err, 0)
+		return
+	}
+	if len(fields) >= customfields.MaxFields {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Не больше %d полей", customfields.MaxFields))
+		return
+	}
+	if err := h.Fields.CreateField(r.Context(), field); err != nil {
+		respondFieldError(w, err, 0)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, field)
+}
+
+// GetFields godoc
+// @Summary Получить пользовательские поля
+// @Tags fields
+// @Produce json
+// @Success 200 {array} models.CustomField "Определения полей"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /fields [get]
+func (h *FieldHandler) GetFields(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	fields, err := h.Fields.GetFields(r.Context(), userID)
+	if err != nil {
+		respondFieldError(w, err, 0)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, fields)
+}
+
+// GetField godoc
+// @Summary Получить пользовательское поле
+// @Tags fields
+// @Produce json
+// @Param fieldID path int true "ID поля"
+// @Success 200 {object} models.CustomField "Определение поля"
+// @Failure 400 {object} map[string]string "Неверный ID поля"
+// @Failure 404 {object} map[string]string "Поле не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /fields/{fieldID} [get]
+func (h *FieldHandler) GetField(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := fieldRequest(w, r)
+	if !ok {
+		return
+	}
+	field, err := h.Fields.GetField(r.Context(), id, userID)
+	if err != nil {
+		respondFieldError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, field)
+}
+
+// UpdateField godoc
+// @Summary Изменить пользовательское поле
+// @Description Меняет название, обязательность и правила проверки поля. Ключ и тип не меняются. Сохраненные значения не перепроверяются: новые правила действуют при следующем изменении задачи
+// @Tags fields
+// @Accept json
+// @Produce json
+// @Param fieldID path int true "ID поля"
+// @Param field body models.CustomFieldPayload true "Новое определение поля"
+// @Success 200 {object} models.CustomField "Измененное поле"
+// @Failure 400 {object} map[string]string "Неверное определение поля"
+// @Failure 404 {object} map[string]string "Поле не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /fields/{fieldID} [put]
+func (h *FieldHandler) UpdateField(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := fieldRequest(w, r)
+	if !ok {
+		return
+	}
+	current, err := h.Fields.GetField(r.Context(), id, userID)
+	if err != nil {
+		respondFieldError(w, err, id)
+		return
+	}
+	var payload models.CustomFieldPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	payload.Key, payload.Type = current.Key, current.Type
+	field := newField(userID, payload)
+	field.ID = id
+	if err := customfields.ValidateDefinition(field); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Fields.UpdateField(r.Context(), field); err != nil {
+		respondFieldError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, field)
+}
+
+// DeleteField godoc
+// @Summary Удалить пользовательское поле
+// @Description Удаляет поле и его значения у всех задач
+// @Tags fields
+// @Param fieldID path int true "ID поля"
+// @Success 204 "Поле удалено"
+// @Failure 400 {object} map[string]string "Неверный ID поля"
+// @Failure 404 {object} map[string]string "Поле не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /fields/{fieldID} [delete]
+func (h *FieldHandler) DeleteField(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := fieldRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Fields.DeleteField(r.Context(), id, userID); err != nil {
+		respondFieldError(w, err, id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeField читает и проверяет определение поля; при ошибке ответ уже отправлен.
+func decodeField(w http.ResponseWriter, r *http.Request, userID int) (*models.CustomField, bool) {
+	var payload models.CustomFieldPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return nil, false
+	}
+	defer r.Body.Close()
+	field := newField(userID, payload)
+	if err := customfields.ValidateDefinition(field); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return field, true
+}
+
+func newField(userID int, payload models.CustomFieldPayload) *models.CustomField {
+	return &models.CustomField{
+		UserID:    userID,
+		Key:       payload.Key,
+		Name:      payload.Name,
+		Type:      payload.Type,
+		Required:  payload.Required,
+		Options:   payload.Options,
+		Min:       payload.Min,
+		Max:       payload.Max,
+		MaxLength: payload.MaxLength,
+		Pattern:   payload.Pattern,
+	}
+}
+
+// fieldRequest извлекает пользователя и ID поля; при ошибке ответ уже отправлен.
+func fieldRequest(w http.ResponseWriter, r *http.Request) (userID int, id int, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	id, err = strconv.Atoi(chi.URLParam(r, "fieldID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID поля")
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func respondFieldError(w http.ResponseWriter, err error, id int) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Поле не найдено")
+	case errors.Is(err, storage.ErrFieldExists):
+		respondWithError(w, http.StatusConflict, "Поле с таким ключом уже существует")
+	default:
+		log.Printf("Ошибка при работе с полем %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+	}
+}
+
+// customFieldValues проверяет значения пользовательских полей задачи по определениям
+// пользователя; при ошибке ответ уже отправлен.
+func (h *TaskHandler) customFieldValues(w http.ResponseWriter, r *http.Request, userID int, values map[string]interface{}) (map[string]interface{}, bool) {
+	fields, err := h.Fields.GetFields(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении полей пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return nil, false
+	}
+	normalized, err := customfields.Normalize(fields, values)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	missing, err := h.missingUsers(r.Context(), customfields.UserIDs(fields, normalized))
+	if err != nil {
+		log.Printf("Ошибка при проверке пользователей: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return nil, false
+	}
+	if len(missing) > 0 {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Пользователь %d не найден", missing[0]))
+		return nil, false
+	}
+	return normalized, true
+}
+
+// missingUsers возвращает те из ids, которым не соответствуют пользователи.
+func (h *TaskHandler) missingUsers(ctx context.Context, ids []int) ([]int, error) {
+	return findMissingUsers(ctx, h.Fields, ids)
+}
+
+// ErrInvalidCustomFields означает, что значения пользовательских полей задачи не прошли проверку.
+var ErrInvalidCustomFields = errors.New("неверные значения пользовательских полей")
+
+// NormalizeTaskCustomFields проверяет и нормализует значения пользовательских полей задач,
+// которые создаются не через POST /tasks (шаблоны, импорт Trello), по тем же правилам:
+// обязательные поля, типы и ограничения значений, существование пользователей.
+// Ошибки проверки оборачивают ErrInvalidCustomFields.
+func NormalizeTaskCustomFields(ctx context.Context, fieldStore storage.FieldStore, userID int, tasks []models.Task) error {
+	fields, err := fieldStore.GetFields(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении полей пользователя %d: %w", userID, err)
+	}
+	var userIDs []int
+	for i := range tasks {
+		normalized, err := customfields.Normalize(fields, tasks[i].CustomFields)
+		if err != nil {
+			return fmt.Errorf("%w: задача %q: %v", ErrInvalidCustomFields, tasks[i].Title, err)
+		}
+		tasks[i].CustomFields = normalized
+		userIDs = append(userIDs, customfields.UserIDs(fields, normalized)...)
+	}
+	missing, err := findMissingUsers(ctx, fieldStore, userIDs)
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке пользователей: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: пользователь %d не найден", ErrInvalidCustomFields, missing[0])
+	}
+	return nil
+}
+
+func findMissingUsers(ctx context.Context, fields storage.FieldStore, ids []int) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	existing, err := fields.ExistingUserIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+	var missing []int
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// taskQuery читает параметры списка задач: include_archived, фильтры
+// cf.<ключ>=значение, cf.<ключ>.gte=..., cf.<ключ>.lte=... и сортировку sort=cf.<ключ>
+// или sort=-cf.<ключ>. Определения полей загружаются, только если запрос их использует.
+// При ошибке ответ уже отправлен.
+func (h *TaskHandler) taskQuery(w http.ResponseWriter, r *http.Request, userID int) (models.TaskQuery, bool) {
+	var query models.TaskQuery
+	params := r.URL.Query()
+	if v := params.Get("include_archived"); v != "" {
+		var err error
+		if query.IncludeArchived, err = strconv.ParseBool(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверное значение include_archived")
+			return query, false
+		}
+	}
+	sortParam := params.Get("sort")
+	needFields := sortParam != ""
+	for name := range params {
+		needFields = needFields || strings.HasPrefix(name, "cf.")
+	}
+	var fields map[string]*models.CustomField
+	if needFields {
+		list, err := h.Fields.GetFields(r.Context(), userID)
+		if err != nil {
+			log.Printf("Ошибка при получении полей пользователя %d: %v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось получить список задач")
+			return query, false
+		}
+		fields = make(map[string]*models.CustomField, len(list))
+		for i := range list {
+			fields[list[i].Key] = &list[i]
+		}
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !strings.HasPrefix(name, "cf.") {
+			continue
+		}
+		key, op := strings.TrimPrefix(name, "cf."), models.FilterEq
+		for _, suffix := range []string{models.FilterGte, models.FilterLte} {
+			if strings.HasSuffix(key, "."+suffix) {
+				key, op = strings.TrimSuffix(key, "."+suffix), suffix
+			}
+		}
+		field, ok := fields[key]
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Неизвестное поле %q", key))
+			return query, false
+		}
+		for _, raw := range params[name] {
+			value, err := customfields.ParseFilterValue(field, op, raw)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error())
+				return query, false
+			}
+			query.Filters = append(query.Filters, models.CustomFieldFilter{Key: key, Type: field.Type, Op: op, Value: value})
+		}
+	}
+
+	if sortParam != "" {
+		desc := strings.HasPrefix(sortParam, "-")
+		key := strings.TrimPrefix(strings.TrimPrefix(sortParam, "-"), "cf.")
+		field, ok := fields[key]
+		if !ok || !strings.HasPrefix(strings.TrimPrefix(sortParam, "-"), "cf.") {
+			respondWithError(w, http.StatusBadRequest, "sort должен иметь вид cf.<ключ> или -cf.<ключ> для существующего поля")
+			return query, false
+		}
+		query.Sort = &models.CustomFieldSort{Key: key, Type: field.Type, Desc: desc}
+	}
+	return query, true
+}
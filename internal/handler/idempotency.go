@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+const (
+	// IdempotencyKeyHeader - заголовок, по которому повторы запроса распознаются как один запрос.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+// replayedHeaders - заголовки ответа, которые сохраняются и отдаются при повторе.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyMiddleware выполняет изменяющие запросы с заголовком Idempotency-Key не более одного раза.
+// Должен стоять после JWTAuthMiddleware: ключи действуют в пределах пользователя.
+type IdempotencyMiddleware struct {
+	Store storage.IdempotencyStore
+}
+
+// NewIdempotencyMiddleware создает новый экземпляр IdempotencyMiddleware.
+func NewIdempotencyMiddleware(store storage.IdempotencyStore) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{Store: store}
+}
+
+// Handler возвращает сохраненный ответ на повтор запроса с тем же ключом и телом
+// и 422, если тот же ключ пришел с другим запросом. Ответы 5xx не сохраняются,
+// чтобы повтор после сбоя мог выполниться заново. Ответы с Cache-Control: no-store
+// (секреты, которые показываются один раз) тоже не сохраняются: иначе их копия
+// пролежала бы в базе весь срок ключа и вернулась бы любому повтору.
+func (m *IdempotencyMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !isMutatingMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondWithError(w, http.StatusBadRequest, "Idempotency-Key слишком длинный")
+			return
+		}
+		userID, err := GetUserIDFromContext(r)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Не удалось прочитать тело запроса")
+			return
+		}
+		if len(body) > maxIdempotentBodySize {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Тело запроса слишком большое")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		resp, replayed, err := m.Store.Do(r.Context(), userID, key, requestFingerprint(r, body), func() *models.StoredResponse {
+			rec := newResponseRecorder()
+			next.ServeHTTP(rec, r)
+			if rec.statusCode >= http.StatusInternalServerError || isNoStore(rec.header) {
+				rec.flushTo(w)
+				return nil
+			}
+			resp := rec.storedResponse()
+			writeStoredResponse(w, resp)
+			return resp
+		})
+		if err != nil {
+			if errors.Is(err, storage.ErrIdempotencyKeyReused) {
+				respondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key уже использован с другим запросом")
+				return
+			}
+			log.Printf("Ошибка обработки Idempotency-Key: %v", err)
+			if resp == nil {
+				respondWithError(w, http.StatusInternalServerError, "Не удалось обработать запрос")
+			}
+			// Иначе ответ уже отправлен клиенту, не удалось только сохранить его
+			return
+		}
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeStoredResponse(w, resp)
+		}
+	})
+}
+
+// isNoStore сообщает, запретил ли обработчик сохранять ответ.
+func isNoStore(header http.Header) bool {
+	for _, v := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint отличает разные запросы, пришедшие с одним ключом.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeStoredResponse(w http.ResponseWriter, resp *models.StoredResponse) {
+	for name, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := w.Write(resp.Body); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
+
+// responseRecorder буферизует ответ обработчика, чтобы сохранить его перед отправкой.
+type responseRecorder struct {
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}, statusCode: http.StatusOK}
+}
+
+func (rec *responseRecorder) Header() http.Header { return rec.header }
+
+func (rec *responseRecorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
+
+func (rec *responseRecorder) WriteHeader(statusCode int) { rec.statusCode = statusCode }
+
+func (rec *responseRecorder) storedResponse() *models.StoredResponse {
+	header := map[string][]string{}
+	for _, name := range replayedHeaders {
+		if values := rec.header.Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+	return &models.StoredResponse{StatusCode: rec.statusCode, Header: header, Body: rec.body.Bytes()}
+}
+
+func (rec *responseRecorder) flushTo(w http.ResponseWriter) {
+	for name, values := range rec.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(rec.statusCode)
+	if _, err := w.Write(rec.body.Bytes()); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"kanban-backend/internal/models"
+)
+
+// memoryIdempotencyStore хранит ответы в памяти с той же семантикой, что и хранилище в PostgreSQL.
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]*models.StoredResponse
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{responses: map[string]*models.StoredResponse{}}
+}
+
+func (s *memoryIdempotencyStore) Do(ctx context.Context, userID int, key string, fingerprint string, fn func() *models.StoredResponse) (*models.StoredResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resp, ok := s.responses[key]; ok {
+		return resp, true, nil
+	}
+	resp := fn()
+	if resp != nil {
+		s.responses[key] = resp
+	}
+	return resp, false, nil
+}
+
+// idempotentRequest выполняет POST с ключом от имени пользователя 1.
+func idempotentRequest(h http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/resource", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", 1))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	calls := 0
+	h := NewIdempotencyMiddleware(store).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		respondWithJSON(w, http.StatusCreated, map[string]int{"id": calls})
+	}))
+
+	first := idempotentRequest(h, "k1")
+	second := idempotentRequest(h, "k1")
+	if calls != 1 {
+		t.Fatalf("обработчик вызван %d раз, ожидался 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("повтор: статус %d, тело %s", second.Code, second.Body)
+	}
+}
+
+func TestIdempotencySkipsNoStoreResponses(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	calls := 0
+	h := NewIdempotencyMiddleware(store).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "private, no-store")
+		respondWithJSON(w, http.StatusCreated, map[string]string{"secret": "s3cret"})
+	}))
+
+	first := idempotentRequest(h, "k1")
+	if first.Code != http.StatusCreated || !strings.Contains(first.Body.String(), "s3cret") {
+		t.Fatalf("первый запрос: статус %d, тело %s", first.Code, first.Body)
+	}
+	if len(store.responses) != 0 {
+		t.Fatalf("ответ с секретом сохранен: %s", store.responses["k1"].Body)
+	}
+	// Повтор выполняется заново, а не получает сохраненный секрет
+	second := idempotentRequest(h, "k1")
+	if calls != 2 || second.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("повтор: вызовов %d, Idempotent-Replayed %q", calls, second.Header().Get("Idempotent-Replayed"))
+	}
+}
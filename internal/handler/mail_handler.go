@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+
+	"kanban-backend/internal/mail"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+const maxEmailLen = 254
+
+// MailHandler обрабатывает HTTP запросы почтовых настроек и отписки.
+type MailHandler struct {
+	Mail storage.MailStore
+	// Secret проверяет подпись ссылок для отписки
+	Secret []byte
+}
+
+// NewMailHandler создает новый экземпляр MailHandler.
+func NewMailHandler(store storage.MailStore, secret []byte) *MailHandler {
+	return &MailHandler{Mail: store, Secret: secret}
+}
+
+// GetMailSettings godoc
+// @Summary Почтовые настройки уведомлений
+// @Description Возвращает адрес для уведомлений и режим отправки по каждому типу уведомлений: instant - письмо сразу, daily - сводка раз в сутки, off - не присылать
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} models.MailSettings "Настройки"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /notifications/settings [get]
+func (h *MailHandler) GetMailSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	settings, err := h.Mail.GetMailSettings(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении настроек почты пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить настройки")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// UpdateMailSettings godoc
+// @Summary Изменить почтовые настройки уведомлений
+// @Description Заменяет адрес и режимы отправки. Типы, не указанные в preferences, получают режим по умолчанию. Сводка за сутки уходит после полуночи UTC. Письма приходят только на подтвержденный адрес: новый адрес подтверждается по ссылке, которую отправляет POST /email/verification
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param settings body models.MailSettings true "Настройки"
+// @Success 200 {object} models.MailSettings "Сохраненные настройки"
+// @Failure 400 {object} map[string]string "Неверные настройки"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /notifications/settings [put]
+func (h *MailHandler) UpdateMailSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var settings models.MailSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	if settings.Email != nil {
+		email, ok := normalizeEmail(*settings.Email)
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, "Неверный почтовый адрес")
+			return
+		}
+		settings.Email = email
+	}
+	for t, mode := range settings.Preferences {
+		if !contains(models.NotificationTypes, t) {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Неизвестный тип уведомления %q", t))
+			return
+		}
+		if !contains(models.MailModes, mode) {
+			respondWithError(w, http.StatusBadRequest, "Режим должен быть одним из: "+strings.Join(models.MailModes, ", "))
+			return
+		}
+	}
+	if err := h.Mail.UpdateMailSettings(r.Context(), userID, &settings); err != nil {
+		log.Printf("Ошибка при сохранении настроек почты пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось сохранить настройки")
+		return
+	}
+	h.GetMailSettings(w, r)
+}
+
+// normalizeEmail проверяет адрес; пустая строка означает удаление адреса.
+// Принимается только сам адрес, без отображаемого имени.
+func normalizeEmail(raw string) (*string, bool) {
+	email := strings.TrimSpace(raw)
+	if email == "" {
+		return nil, true
+	}
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > maxEmailLen {
+		return nil, false
+	}
+	return &email, true
+}
+
+// UnsubscribePage godoc
+// @Summary Страница отписки от писем
+// @Description Открывается по ссылке из письма без входа в систему и предлагает подтвердить отписку. Ссылка подписана сервером
+// @Tags notifications
+// @Produce html
+// @Param token query string true "Токен из ссылки"
+// @Success 200 "Страница подтверждения"
+// @Failure 400 {object} map[string]string "Неверная ссылка"
+// @Router /unsubscribe [get]
+func (h *MailHandler) UnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, scope, ok := h.unsubscribeToken(w, token); ok {
+		// Сканеры ссылок в почте открывают GET, поэтому отписка выполняется только по POST
+		h.renderUnsubscribePage(w, mail.UnsubscribePage{Scope: scope, Action: "?token=" + url.QueryEscape(token)})
+	}
+}
+
+// Unsubscribe godoc
+// @Summary Отписаться от писем
+// @Description Отключает письма с уведомлениями выбранного в ссылке типа или все письма. Работает без входа в систему, в том числе как отписка в один клик по RFC 8058
+// @Tags notifications
+// @Produce html
+// @Param token query string true "Токен из ссылки"
+// @Success 200 "Отписка выполнена"
+// @Failure 400 {object} map[string]string "Неверная ссылка"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /unsubscribe [post]
+func (h *MailHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, scope, ok := h.unsubscribeToken(w, r.URL.Query().Get("token"))
+	if !ok {
+		return
+	}
+	types := []string{scope}
+	if scope == mail.UnsubscribeAll {
+		types = models.NotificationTypes
+	}
+	if err := h.Mail.SetMailMode(r.Context(), userID, types, models.MailOff); err != nil {
+		log.Printf("Ошибка при отписке пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось отписаться")
+		return
+	}
+	h.renderUnsubscribePage(w, mail.UnsubscribePage{Done: true, Scope: scope})
+}
+
+func (h *MailHandler) unsubscribeToken(w http.ResponseWriter, token string) (userID int, scope string, ok bool) {
+	userID, scope, err := mail.VerifyUnsubscribe(h.Secret, token)
+	if err != nil || !mail.IsUnsubscribeScope(scope) {
+		respondWithError(w, http.StatusBadRequest, "Неверная ссылка для отписки")
+		return 0, "", false
+	}
+	return userID, scope, true
+}
+
+func (h *MailHandler) renderUnsubscribePage(w http.ResponseWriter, page mail.UnsubscribePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := mail.RenderUnsubscribePage(w, page); err != nil {
+		log.Printf("Ошибка вывода страницы отписки: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+const (
+	defaultMetricsRange = 90 * 24 * time.Hour
+	maxMetricsRange     = 5 * 366 * 24 * time.Hour
+)
+
+// GetFlowMetrics godoc
+// @Summary Метрики потока задач
+// @Description Возвращает перцентили (p50/p85/p95) времени выполнения и времени цикла в часах, недельную пропускную способность и возраст задач в работе. Статусы играют роль колонок: задача начата, когда впервые покидает статусы бэклога, и выполнена, когда находится в выполненном статусе. Расчет ведется по истории статусов
+// @Tags metrics
+// @Produce json
+// @Param from query string false "Начало периода (RFC 3339), по умолчанию 90 дней назад"
+// @Param to query string false "Конец периода (RFC 3339), по умолчанию сейчас"
+// @Param done query string false "Выполненные статусы через запятую, по умолчанию completed"
+// @Param backlog query string false "Статусы бэклога через запятую, по умолчанию pending"
+// @Success 200 {object} models.FlowMetrics "Метрики"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /metrics/flow [get]
+func (h *TaskHandler) GetFlowMetrics(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	query, err := parseFlowMetricsQuery(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	metrics, err := h.Store.GetFlowMetrics(r.Context(), userID, query)
+	if err != nil {
+		log.Printf("Ошибка при расчете метрик пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось рассчитать метрики")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, metrics)
+}
+
+// parseTimeRange читает период from/to; по умолчанию - def до текущего момента.
+func parseTimeRange(r *http.Request, def, max time.Duration) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("to должен быть в формате RFC 3339")
+		}
+	}
+	from = to.Add(-def)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("from должен быть в формате RFC 3339")
+		}
+	}
+	if !from.Before(to) {
+		return from, to, errors.New("from должен быть раньше to")
+	}
+	if to.Sub(from) > max {
+		return from, to, errors.New("слишком длинный период")
+	}
+	return from, to, nil
+}
+
+func parseFlowMetricsQuery(r *http.Request) (models.FlowMetricsQuery, error) {
+	var query models.FlowMetricsQuery
+	var err error
+	if query.From, query.To, err = parseTimeRange(r, defaultMetricsRange, maxMetricsRange); err != nil {
+		return query, err
+	}
+	query.DoneStatuses = parseStatusList(r.URL.Query().Get("done"), "completed")
+	query.BacklogStatuses = parseStatusList(r.URL.Query().Get("backlog"), "pending")
+	for _, s := range query.DoneStatuses {
+		for _, b := range query.BacklogStatuses {
+			if s == b {
+				return query, errors.New("статус не может быть одновременно в done и backlog: " + s)
+			}
+		}
+	}
+	return query, nil
+}
+
+// parseStatusList разбирает список статусов через запятую.
+func parseStatusList(v, def string) []string {
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	if len(list) == 0 {
+		list = []string{def}
+	}
+	return list
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NotificationHandler обрабатывает HTTP запросы входящих уведомлений.
+type NotificationHandler struct {
+	Notifications storage.NotificationStore
+}
+
+// NewNotificationHandler создает новый экземпляр NotificationHandler.
+func NewNotificationHandler(store storage.NotificationStore) *NotificationHandler {
+	return &NotificationHandler{Notifications: store}
+}
+
+// GetNotifications godoc
+// @Summary Входящие уведомления
+// @Description Возвращает уведомления пользователя от новых к старым: упоминания через @username в описаниях задач и приближение сроков. Новые уведомления также приходят в /ws и /events как событие notification.created
+// @Tags notifications
+// @Produce json
+// @Param unread query bool false "Только непрочитанные"
+// @Param limit query int false "Размер страницы (1-200, по умолчанию 50)"
+// @Param before query int false "Значение next_before из предыдущей страницы"
+// @Success 200 {object} models.NotificationPage "Страница уведомлений"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /notifications [get]
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	before, limit, err := parseActivityPage(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	unreadOnly := false
+	if v := r.URL.Query().Get("unread"); v != "" {
+		unreadOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверный формат параметра unread")
+			return
+		}
+	}
+	page, err := h.Notifications.GetNotifications(r.Context(), userID, unreadOnly, before, limit)
+	if err != nil {
+		log.Printf("Ошибка при получении уведомлений пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить уведомления")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// GetUnreadCount godoc
+// @Summary Количество непрочитанных уведомлений
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} models.NotificationCount "Количество непрочитанных"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /notifications/unread-count [get]
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	n, err := h.Notifications.UnreadCount(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при подсчете уведомлений пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить количество уведомлений")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.NotificationCount{Unread: n})
+}
+
+// MarkRead godoc
+// @Summary Отметить уведомление прочитанным
+// @Tags notifications
+// @Param notificationID path int true "ID уведомления"
+// @Success 204 "Уведомление отмечено прочитанным"
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 404 {object} map[string]string "Уведомление не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /notifications/{notificationID}/read [post]
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "notificationID"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID уведомления")
+		return
+	}
+	if err := h.Notifications.MarkRead(r.Context(), id, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Уведомление не найдено")
+			return
+		}
+		log.Printf("Ошибка при отметке уведомления %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось отметить уведомление")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllRead godoc
+// @Summary Отметить все уведомления прочитанными
+// @Tags notifications
+// @Success 204 "Все уведомления отмечены прочитанными"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /notifications/read-all [post]
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if _, err := h.Notifications.MarkAllRead(r.Context(), userID); err != nil {
+		log.Printf("Ошибка при отметке уведомлений пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось отметить уведомления")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/oidc"
+	"kanban-backend/internal/storage"
+)
+
+const (
+	oidcFlowCookie = "oidc_flow"
+	// oidcFlowTTL - сколько пользователь может провести на странице входа провайдера
+	oidcFlowTTL = 10 * time.Minute
+	// maxOIDCUsernameLen оставляет место для номера, который добавляется к занятому имени
+	maxOIDCUsernameLen = 60
+)
+
+var errOIDCNoAccount = errors.New("учетная запись провайдера не привязана к пользователю")
+
+// OIDCSettings настраивают вход через провайдера OpenID Connect.
+type OIDCSettings struct {
+	Provider   *oidc.Provider
+	Identities storage.IdentityStore
+	// AutoProvision создает пользователя при первом входе, если его не удалось привязать к существующему
+	AutoProvision bool
+	// LinkByUsername привязывает вход к пользователю с тем же preferred_username.
+	// Безопасно, только если имена у провайдера не может выбирать сам пользователь
+	LinkByUsername bool
+	// PostLoginURL - адрес клиента, куда после входа перенаправляется браузер с JWT во фрагменте;
+	// пусто - JWT возвращается в JSON
+	PostLoginURL string
+	// MFAACRValues - значения acr, которые провайдер выдает только после входа с MFA.
+	// Такой вход, как и вход с amr из RFC 8176 о втором факторе, не требует кода 2FA
+	MFAACRValues []string
+	// StateSecret - ключ подписи cookie с состоянием входа. Должен быть одинаковым на всех
+	// репликах и переживать перезапуск, иначе возврат от провайдера не пройдет проверку
+	StateSecret []byte
+}
+
+// oidcFlow - состояние начатого входа, которое хранится в подписанной cookie браузера
+// и сверяется при возврате от провайдера.
+type oidcFlow struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// OIDCLogin godoc
+// @Summary Вход через провайдера OpenID Connect
+// @Description Перенаправляет браузер на страницу входа провайдера (authorization code flow с PKCE). После входа провайдер возвращает браузер на /oidc/callback
+// @Tags auth
+// @Success 302 "Перенаправление к провайдеру"
+// @Failure 404 {object} map[string]string "Вход через провайдера не настроен"
+// @Failure 502 {object} map[string]string "Провайдер недоступен"
+// @Router /oidc/login [get]
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "SSO is not configured")
+		return
+	}
+	var flow oidcFlow
+	var err error
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to start login")
+			return
+		}
+	}
+	flow.ExpiresAt = time.Now().Add(oidcFlowTTL).Unix()
+	redirect, err := h.OIDC.Provider.AuthCodeURL(r.Context(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		log.Printf("Ошибка при обращении к провайдеру OIDC: %v", err)
+		respondWithError(w, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+	http.SetCookie(w, h.oidcCookie(h.signOIDCFlow(flow), int(oidcFlowTTL/time.Second)))
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Завершение входа через провайдера OpenID Connect
+// @Description Проверяет state, обменивает код на токены, проверяет ID-токен (подпись по JWKS, issuer, audience, срок, nonce) и выдает JWT. Пользователь находится по привязке к провайдеру, привязывается к существующему по подтвержденному адресу (или по имени, если это включено) либо создается. Если у пользователя включена 2FA, а провайдер не подтвердил вход вторым фактором (amr или acr из OIDC_MFA_ACR_VALUES), вместо JWT выдается токен второго шага для POST /login/2fa. Если задан OIDC_POST_LOGIN_URL, браузер перенаправляется туда с JWT во фрагменте #token= или токеном второго шага во фрагменте #challenge_token=
+// @Tags auth
+// @Produce json
+// @Param code query string true "Код авторизации"
+// @Param state query string true "State из начала входа"
+// @Success 200 {object} map[string]string "Успешная аутентификация"
+// @Success 202 {object} models.LoginChallenge "Требуется код 2FA"
+// @Success 302 "Перенаправление в клиент с JWT или токеном второго шага"
+// @Failure 400 {object} map[string]string "Неверный или просроченный вход"
+// @Failure 401 {object} map[string]string "Провайдер отклонил вход"
+// @Failure 403 {object} map[string]string "Учетная запись не привязана"
+// @Failure 404 {object} map[string]string "Вход через провайдера не настроен"
+// @Router /oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "SSO is not configured")
+		return
+	}
+	// Cookie одноразовая: повторно открыть ту же ссылку нельзя
+	http.SetCookie(w, h.oidcCookie("", -1))
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		respondWithError(w, http.StatusUnauthorized, "Identity provider returned an error: "+e+" "+q.Get("error_description"))
+		return
+	}
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Login session not found, start again")
+		return
+	}
+	flow, ok := h.verifyOIDCFlow(cookie.Value)
+	if !ok || subtle.ConstantTimeCompare([]byte(flow.State), []byte(q.Get("state"))) != 1 {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired login session, start again")
+		return
+	}
+	code := q.Get("code")
+	if code == "" {
+		respondWithError(w, http.StatusBadRequest, "Authorization code required")
+		return
+	}
+	rawIDToken, err := h.OIDC.Provider.Exchange(r.Context(), code, flow.Verifier)
+	if err != nil {
+		log.Printf("Ошибка обмена кода OIDC: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Failed to exchange authorization code")
+		return
+	}
+	claims, err := h.OIDC.Provider.VerifyIDToken(r.Context(), rawIDToken, flow.Nonce)
+	if err != nil {
+		log.Printf("Ошибка проверки ID-токена OIDC: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid ID token")
+		return
+	}
+	user, err := h.oidcUser(r.Context(), claims)
+	if errors.Is(err, errOIDCNoAccount) {
+		respondWithError(w, http.StatusForbidden, "No account is linked to this identity")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при входе через OIDC (sub %s): %v", claims.Subject, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+	// Вход через провайдера не должен обходить 2FA, если провайдер сам не проверил второй фактор
+	if !claims.MultiFactor(h.OIDC.MFAACRValues) {
+		totp, err := h.TwoFactor.GetTOTP(r.Context(), user.ID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Ошибка при получении 2FA пользователя %d: %v", user.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to sign in")
+			return
+		}
+		if totp != nil && totp.ConfirmedAt != nil {
+			challenge, expiresAt, err := auth.GenerateChallengeJWT(user.ID, user.TokenVersion)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+				return
+			}
+			if h.OIDC.PostLoginURL != "" {
+				http.Redirect(w, r, h.OIDC.PostLoginURL+"#challenge_token="+url.QueryEscape(challenge), http.StatusFound)
+				return
+			}
+			respondWithJSON(w, http.StatusAccepted, models.LoginChallenge{TwoFactorRequired: true, ChallengeToken: challenge, ExpiresAt: expiresAt})
+			return
+		}
+	}
+	token, err := auth.GenerateJWT(user.ID, user.Username, user.TokenVersion)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	if h.OIDC.PostLoginURL != "" {
+		// Фрагмент не отправляется на серверы и не попадает в их журналы
+		http.Redirect(w, r, h.OIDC.PostLoginURL+"#token="+url.QueryEscape(token), http.StatusFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// oidcUser находит пользователя для учетной записи провайдера, привязывает ее к существующему
+// пользователю или создает нового. Привязка по адресу выполняется, только если адрес подтвержден
+// и у провайдера, и у нас: иначе чужой аккаунт можно было бы захватить, указав его адрес.
+func (h *AuthHandler) oidcUser(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	issuer := claims.Issuer
+	user, err := h.OIDC.Identities.GetUserByIdentity(ctx, issuer, claims.Subject)
+	if err == nil || !errors.Is(err, storage.ErrNotFound) {
+		return user, err
+	}
+	if claims.Email != "" && bool(claims.EmailVerified) {
+		user, err := h.Accounts.GetUserByVerifiedEmail(ctx, claims.Email)
+		if err == nil {
+			log.Printf("Учетная запись OIDC %s привязана к пользователю %d по адресу", claims.Subject, user.ID)
+			return user, h.OIDC.Identities.LinkIdentity(ctx, issuer, claims.Subject, user.ID)
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+	}
+	if h.OIDC.LinkByUsername && claims.PreferredUsername != "" {
+		if user, err := h.UserStore.GetUserByUsername(ctx, claims.PreferredUsername); err == nil {
+			log.Printf("Учетная запись OIDC %s привязана к пользователю %d по имени", claims.Subject, user.ID)
+			return user, h.OIDC.Identities.LinkIdentity(ctx, issuer, claims.Subject, user.ID)
+		}
+	}
+	if !h.OIDC.AutoProvision {
+		return nil, errOIDCNoAccount
+	}
+	user = &models.User{Username: oidcUsername(claims)}
+	if email, ok := normalizeEmail(claims.Email); ok && email != nil && bool(claims.EmailVerified) {
+		now := time.Now()
+		user.Email, user.EmailVerifiedAt = email, &now
+	}
+	if err := h.OIDC.Identities.CreateUserWithIdentity(ctx, user, issuer, claims.Subject); err != nil {
+		return nil, err
+	}
+	log.Printf("Создан пользователь %d (%s) для учетной записи OIDC %s", user.ID, user.Username, claims.Subject)
+	return user, nil
+}
+
+// oidcUsername выбирает имя нового пользователя: preferred_username, начало адреса или "user".
+func oidcUsername(claims *oidc.Claims) string {
+	candidates := []string{claims.PreferredUsername}
+	if local, _, ok := strings.Cut(claims.Email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+	for _, c := range candidates {
+		name := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-", r) {
+				return r
+			}
+			return -1
+		}, c)
+		if runes := []rune(name); len(runes) > maxOIDCUsernameLen {
+			name = string(runes[:maxOIDCUsernameLen])
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return "user"
+}
+
+func (h *AuthHandler) oidcCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/api/v1/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.BaseURL, "https://"),
+		// Lax: браузер отправит cookie при возврате от провайдера, это переход верхнего уровня
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (h *AuthHandler) signOIDCFlow(flow oidcFlow) string {
+	data, _ := json.Marshal(flow)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(h.oidcFlowMAC(payload))
+}
+
+func (h *AuthHandler) verifyOIDCFlow(value string) (oidcFlow, bool) {
+	var flow oidcFlow
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return flow, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, h.oidcFlowMAC(payload)) {
+		return flow, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, &flow) != nil {
+		return flow, false
+	}
+	return flow, time.Now().Unix() < flow.ExpiresAt && flow.State != ""
+}
+
+func (h *AuthHandler) oidcFlowMAC(payload string) []byte {
+	mac := hmac.New(sha256.New, h.OIDC.StateSecret)
+	mac.Write([]byte("oidc_flow:" + payload))
+	return mac.Sum(nil)
+}
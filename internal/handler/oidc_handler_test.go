@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/oidc"
+	"kanban-backend/internal/oidc/oidctest"
+	"kanban-backend/internal/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIdentities хранит привязки в памяти; остальные методы тестам не нужны.
+type fakeIdentities struct {
+	storage.IdentityStore
+	users map[string]*models.User
+}
+
+func (s *fakeIdentities) GetUserByIdentity(ctx context.Context, issuer string, subject string) (*models.User, error) {
+	if u, ok := s.users[subject]; ok {
+		return u, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+// CreateUserWithIdentity запоминает созданного пользователя под его subject.
+func (s *fakeIdentities) CreateUserWithIdentity(ctx context.Context, user *models.User, issuer string, subject string) error {
+	user.ID = len(s.users) + 1
+	s.users[subject] = user
+	return nil
+}
+
+// noVerifiedEmails - хранилище, в котором ни один адрес еще не подтвержден.
+type noVerifiedEmails struct {
+	storage.AccountStore
+}
+
+func (noVerifiedEmails) GetUserByVerifiedEmail(ctx context.Context, email string) (*models.User, error) {
+	return nil, storage.ErrNotFound
+}
+
+// fakeTwoFactor сообщает, у каких пользователей включена 2FA.
+type fakeTwoFactor struct {
+	storage.TwoFactorStore
+	enabled map[int]bool
+}
+
+func (s *fakeTwoFactor) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	if !s.enabled[userID] {
+		return nil, storage.ErrNotFound
+	}
+	now := time.Now()
+	return &models.TOTP{UserID: userID, ConfirmedAt: &now}, nil
+}
+
+type oidcTest struct {
+	srv *oidctest.Server
+	h   *AuthHandler
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	t.Helper()
+	srv := oidctest.NewServer()
+	t.Cleanup(srv.Close)
+	h := &AuthHandler{
+		TwoFactor: &fakeTwoFactor{enabled: map[int]bool{2: true}},
+		Secret:    []byte("test-secret"),
+		BaseURL:   "https://kanban.example.com",
+		OIDC: &OIDCSettings{
+			Provider: oidc.NewProvider(oidc.Config{
+				Issuer:       srv.URL,
+				ClientID:     srv.ClientID,
+				ClientSecret: srv.ClientSecret,
+				RedirectURL:  "https://kanban.example.com/api/v1/oidc/callback",
+				HTTPClient:   srv.Client(),
+			}),
+			Identities: &fakeIdentities{users: map[string]*models.User{
+				"alice": {ID: 1, Username: "alice"},
+				"bob":   {ID: 2, Username: "bob"},
+			}},
+			StateSecret: []byte("test-oidc-state-secret"),
+		},
+	}
+	return &oidcTest{srv: srv, h: h}
+}
+
+// start начинает вход и возвращает адрес страницы провайдера и cookie с состоянием входа.
+func (o *oidcTest) start(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	o.h.OIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/v1/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("OIDCLogin: статус %d: %s", rec.Code, rec.Body)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcFlowCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("cookie состояния входа: %+v", cookies)
+	}
+	return rec.Header().Get("Location"), cookies[0]
+}
+
+// callback возвращает браузер от провайдера с кодом для claims.
+func (o *oidcTest) callback(t *testing.T, authURL string, cookie *http.Cookie, claims jwt.MapClaims, mutate func(q url.Values)) *httptest.ResponseRecorder {
+	t.Helper()
+	redirect, err := o.srv.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := redirect.Query()
+	if mutate != nil {
+		mutate(q)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/oidc/callback?"+q.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	o.h.OIDCCallback(rec, req)
+	return rec
+}
+
+func TestOIDCLoginIssuesJWT(t *testing.T) {
+	o := newOIDCTest(t)
+	authURL, cookie := o.start(t)
+	rec := o.callback(t, authURL, cookie, o.srv.Claims("alice"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := auth.ParseJWT(body["token"])
+	if err != nil {
+		t.Fatalf("выдан неверный JWT: %v", err)
+	}
+	if claims["username"] != "alice" {
+		t.Errorf("JWT выдан пользователю %v", claims["username"])
+	}
+}
+
+func TestOIDCCallbackChecksState(t *testing.T) {
+	o := newOIDCTest(t)
+
+	authURL, cookie := o.start(t)
+	rec := o.callback(t, authURL, cookie, o.srv.Claims("alice"), func(q url.Values) { q.Set("state", "forged") })
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("чужой state: статус %d, ожидался 400", rec.Code)
+	}
+
+	authURL, _ = o.start(t)
+	rec = o.callback(t, authURL, nil, o.srv.Claims("alice"), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("без cookie: статус %d, ожидался 400", rec.Code)
+	}
+
+	authURL, cookie = o.start(t)
+	cookie.Value = strings.Replace(cookie.Value, ".", "x.", 1)
+	rec = o.callback(t, authURL, cookie, o.srv.Claims("alice"), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("подделанная cookie: статус %d, ожидался 400", rec.Code)
+	}
+}
+
+func TestOIDCCallbackChecksNonce(t *testing.T) {
+	o := newOIDCTest(t)
+	authURL, cookie := o.start(t)
+	claims := o.srv.Claims("alice")
+	claims["nonce"] = "replayed-nonce"
+	rec := o.callback(t, authURL, cookie, claims, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("чужой nonce: статус %d, ожидался 401", rec.Code)
+	}
+}
+
+func TestOIDCCallbackUnknownIdentity(t *testing.T) {
+	o := newOIDCTest(t)
+	authURL, cookie := o.start(t)
+	rec := o.callback(t, authURL, cookie, o.srv.Claims("mallory"), nil)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("статус %d, ожидался 403", rec.Code)
+	}
+}
+
+func TestOIDCCallbackRequiresSecondFactor(t *testing.T) {
+	o := newOIDCTest(t)
+	authURL, cookie := o.start(t)
+	rec := o.callback(t, authURL, cookie, o.srv.Claims("bob"), nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("статус %d, ожидался 202: %s", rec.Code, rec.Body)
+	}
+	var challenge models.LoginChallenge
+	if err := json.NewDecoder(rec.Body).Decode(&challenge); err != nil {
+		t.Fatal(err)
+	}
+	if userID, _, err := auth.ParseChallengeJWT(challenge.ChallengeToken); err != nil || userID != 2 {
+		t.Errorf("токен второго шага для пользователя %d: %v", userID, err)
+	}
+
+	// Провайдер подтвердил второй фактор сам - код 2FA не нужен
+	authURL, cookie = o.start(t)
+	claims := o.srv.Claims("bob")
+	claims["amr"] = []string{"pwd", "hwk"}
+	if rec := o.callback(t, authURL, cookie, claims, nil); rec.Code != http.StatusOK {
+		t.Errorf("вход с amr hwk: статус %d, ожидался 200", rec.Code)
+	}
+
+	// Один способ входа у провайдера, даже ключ или одноразовый код, не заменяет 2FA
+	for _, amr := range [][]string{{"otp"}, {"hwk"}} {
+		authURL, cookie = o.start(t)
+		claims := o.srv.Claims("bob")
+		claims["amr"] = amr
+		if rec := o.callback(t, authURL, cookie, claims, nil); rec.Code != http.StatusAccepted {
+			t.Errorf("вход с amr %v: статус %d, ожидался 202", amr, rec.Code)
+		}
+	}
+
+	// При перенаправлении в клиент JWT не выдается, передается только токен второго шага
+	o.h.OIDC.PostLoginURL = "https://app.example.com/sso"
+	authURL, cookie = o.start(t)
+	rec = o.callback(t, authURL, cookie, o.srv.Claims("bob"), nil)
+	location := rec.Header().Get("Location")
+	if rec.Code != http.StatusFound || !strings.HasPrefix(location, "https://app.example.com/sso#challenge_token=") {
+		t.Errorf("статус %d, перенаправление на %q", rec.Code, location)
+	}
+}
+
+// Новому пользователю сохраняется только адрес, подтвержденный провайдером: неподтвержденный
+// адрес мог бы помешать настоящему владельцу подтвердить его у нас.
+func TestOIDCAutoProvisionKeepsOnlyVerifiedEmail(t *testing.T) {
+	o := newOIDCTest(t)
+	o.h.Accounts = noVerifiedEmails{}
+	o.h.OIDC.AutoProvision = true
+	identities := o.h.OIDC.Identities.(*fakeIdentities)
+
+	for _, tc := range []struct {
+		subject  string
+		verified bool
+	}{
+		{"carol", false},
+		{"dave", true},
+	} {
+		authURL, cookie := o.start(t)
+		claims := o.srv.Claims(tc.subject)
+		claims["email"] = tc.subject + "@example.com"
+		claims["email_verified"] = tc.verified
+		if rec := o.callback(t, authURL, cookie, claims, nil); rec.Code != http.StatusOK {
+			t.Fatalf("%s: статус %d: %s", tc.subject, rec.Code, rec.Body)
+		}
+		user := identities.users[tc.subject]
+		if user == nil {
+			t.Fatalf("%s: пользователь не создан", tc.subject)
+		}
+		if (user.Email != nil) != tc.verified || (user.EmailVerifiedAt != nil) != tc.verified {
+			t.Errorf("%s: адрес %v, подтвержден %v, ожидалось сохранение только подтвержденного адреса", tc.subject, user.Email, user.EmailVerifiedAt)
+		}
+	}
+}
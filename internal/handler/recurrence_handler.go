@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxPreviewOccurrences - наибольшее число повторений в предпросмотре.
+const maxPreviewOccurrences = 100
+
+// PreviewOccurrences godoc
+// @Summary Следующие повторения задачи
+// @Description Возвращает до count ближайших повторений задачи после ее текущего срока по правилу RRULE. Для задачи без правила список пуст
+// @Tags tasks
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param count query int false "Количество повторений (1-100, по умолчанию 10)"
+// @Success 200 {object} models.RecurrencePreview "Ближайшие повторения"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/occurrences [get]
+func (h *TaskHandler) PreviewOccurrences(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return
+	}
+	count := 10
+	if v := r.URL.Query().Get("count"); v != "" {
+		count, err = strconv.Atoi(v)
+		if err != nil || count < 1 || count > maxPreviewOccurrences {
+			respondWithError(w, http.StatusBadRequest, "count должен быть числом от 1 до 100")
+			return
+		}
+	}
+	preview, err := h.Store.PreviewOccurrences(r.Context(), id, userID, count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "не найдена") {
+			respondWithError(w, http.StatusNotFound, "Задача не найдена")
+		} else {
+			log.Printf("Ошибка при расчете повторений задачи %d: %v", id, err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось рассчитать повторения")
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, preview)
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/rules"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RuleHandler обрабатывает HTTP запросы, связанные с правилами автоматизации.
+type RuleHandler struct {
+	Store storage.RuleStore
+}
+
+// NewRuleHandler создает новый экземпляр RuleHandler.
+func NewRuleHandler(store storage.RuleStore) *RuleHandler {
+	return &RuleHandler{Store: store}
+}
+
+// decodeRule читает и проверяет правило из тела запроса; при ошибке ответ уже отправлен.
+func decodeRule(w http.ResponseWriter, r *http.Request, userID int) (*models.Rule, bool) {
+	var payload models.RulePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return nil, false
+	}
+	defer r.Body.Close()
+	rule := &models.Rule{
+		Name:       strings.TrimSpace(payload.Name),
+		Enabled:    payload.Enabled == nil || *payload.Enabled,
+		Trigger:    payload.Trigger,
+		Conditions: payload.Conditions,
+		Actions:    payload.Actions,
+		UserID:     userID,
+	}
+	for i := range rule.Actions {
+		rule.Actions[i].HasSecret = false
+	}
+	if err := rules.Validate(r.Context(), rule); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return rule, true
+}
+
+// redactRules убирает из правил секреты подписи webhook, оставляя только признак has_secret,
+// как секреты подписок webhook, которые тоже не возвращаются после создания.
+func redactRules(list ...models.Rule) {
+	for i := range list {
+		for j := range list[i].Actions {
+			a := &list[i].Actions[j]
+			a.HasSecret = a.Secret != ""
+			a.Secret = ""
+		}
+	}
+}
+
+// CreateRule godoc
+// @Summary Создать правило автоматизации
+// @Description Создает правило "когда X, если Y, то Z". Триггер: события задач (event) или прошедший срок задачи (due_passed). Действия: set_status, archive, unarchive, delete, webhook. Цепочка правил, вызывающих друг друга, ограничена, и правило не срабатывает повторно в своей же цепочке
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param rule body models.RulePayload true "Данные правила"
+// @Success 201 {object} models.Rule "Правило создано"
+// @Failure 400 {object} map[string]string "Неверное правило"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /rules [post]
+func (h *RuleHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	rule, ok := decodeRule(w, r, userID)
+	if !ok {
+		return
+	}
+	if _, err := h.Store.CreateRule(r.Context(), rule); err != nil {
+		log.Printf("Ошибка при создании правила: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать правило")
+		return
+	}
+	redactRules(*rule)
+	respondWithJSON(w, http.StatusCreated, rule)
+}
+
+// GetRules godoc
+// @Summary Получить правила автоматизации
+// @Tags rules
+// @Produce json
+// @Success 200 {array} models.Rule "Список правил"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /rules [get]
+func (h *RuleHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	list, err := h.Store.GetRules(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении правил: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить правила")
+		return
+	}
+	redactRules(list...)
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// GetRule godoc
+// @Summary Получить правило автоматизации
+// @Tags rules
+// @Produce json
+// @Param ruleID path int true "ID правила"
+// @Success 200 {object} models.Rule "Правило"
+// @Failure 400 {object} map[string]string "Неверный ID правила"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /rules/{ruleID} [get]
+func (h *RuleHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := ruleRequest(w, r)
+	if !ok {
+		return
+	}
+	rule, err := h.Store.GetRule(r.Context(), id, userID)
+	if err != nil {
+		respondRuleError(w, err, id)
+		return
+	}
+	redactRules(*rule)
+	respondWithJSON(w, http.StatusOK, rule)
+}
+
+// UpdateRule godoc
+// @Summary Изменить правило автоматизации
+// @Description Заменяет правило целиком. Если у действия webhook не указан secret, сохраняется секрет прежнего действия с тем же URL
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param ruleID path int true "ID правила"
+// @Param rule body models.RulePayload true "Новые данные правила"
+// @Success 200 {object} models.Rule "Измененное правило"
+// @Failure 400 {object} map[string]string "Неверное правило"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /rules/{ruleID} [put]
+func (h *RuleHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := ruleRequest(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := decodeRule(w, r, userID)
+	if !ok {
+		return
+	}
+	rule.ID = id
+	current, err := h.Store.GetRule(r.Context(), id, userID)
+	if err != nil {
+		respondRuleError(w, err, id)
+		return
+	}
+	// Секреты не возвращаются клиенту, поэтому действие webhook без секрета
+	// сохраняет секрет прежнего действия с тем же URL
+	for i := range rule.Actions {
+		a := &rule.Actions[i]
+		if a.Type != rules.ActionWebhook || a.Secret != "" {
+			continue
+		}
+		for _, old := range current.Actions {
+			if old.Type == rules.ActionWebhook && old.URL == a.URL {
+				a.Secret = old.Secret
+				break
+			}
+		}
+	}
+	if err := h.Store.UpdateRule(r.Context(), rule); err != nil {
+		respondRuleError(w, err, id)
+		return
+	}
+	redactRules(*rule)
+	respondWithJSON(w, http.StatusOK, rule)
+}
+
+// DeleteRule godoc
+// @Summary Удалить правило автоматизации
+// @Description Удаляет правило вместе с журналом его выполнения
+// @Tags rules
+// @Param ruleID path int true "ID правила"
+// @Success 204 "Правило удалено"
+// @Failure 400 {object} map[string]string "Неверный ID правила"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /rules/{ruleID} [delete]
+func (h *RuleHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := ruleRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.DeleteRule(r.Context(), id, userID); err != nil {
+		respondRuleError(w, err, id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetExecutions godoc
+// @Summary Журнал выполнения правила
+// @Description Возвращает последние срабатывания правила от новых к старым: задачу, триггер, выполненные действия и ошибку
+// @Tags rules
+// @Produce json
+// @Param ruleID path int true "ID правила"
+// @Param limit query int false "Количество записей (1-200, по умолчанию 50)"
+// @Success 200 {array} models.RuleExecution "Журнал выполнения"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /rules/{ruleID}/executions [get]
+func (h *RuleHandler) GetExecutions(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := ruleRequest(w, r)
+	if !ok {
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			respondWithError(w, http.StatusBadRequest, "limit должен быть числом от 1 до 200")
+			return
+		}
+		limit = n
+	}
+	executions, err := h.Store.GetExecutions(r.Context(), id, userID, limit)
+	if err != nil {
+		respondRuleError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, executions)
+}
+
+// ruleRequest извлекает пользователя и ID правила; при ошибке ответ уже отправлен.
+func ruleRequest(w http.ResponseWriter, r *http.Request) (userID int, id int, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	id, err = strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID правила")
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func respondRuleError(w http.ResponseWriter, err error, id int) {
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Правило не найдено")
+		return
+	}
+	log.Printf("Ошибка при работе с правилом %d: %v", id, err)
+	respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+}
@@ -0,0 +1,459 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// maxSprintDays ограничивает длину спринта и, тем самым, размер диаграмм.
+	maxSprintDays = 366
+	// maxSprintTasks - наибольшее количество задач, добавляемых в спринт одним запросом.
+	maxSprintTasks = 500
+)
+
+// SprintHandler обрабатывает HTTP запросы, связанные со спринтами.
+type SprintHandler struct {
+	Sprints storage.SprintStore
+}
+
+// NewSprintHandler создает новый экземпляр SprintHandler.
+func NewSprintHandler(sprints storage.SprintStore) *SprintHandler {
+	return &SprintHandler{Sprints: sprints}
+}
+
+// CreateSprint godoc
+// @Summary Создать спринт
+// @Description Создает спринт с датами начала и окончания (включительно, UTC). Досок нет, поэтому спринты ведутся по всем задачам пользователя
+// @Tags sprints
+// @Accept json
+// @Produce json
+// @Param sprint body models.SprintPayload true "Данные спринта"
+// @Success 201 {object} models.Sprint "Спринт создан"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints [post]
+func (h *SprintHandler) CreateSprint(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	sprint, ok := decodeSprint(w, r, userID)
+	if !ok {
+		return
+	}
+	if _, err := h.Sprints.CreateSprint(r.Context(), sprint); err != nil {
+		log.Printf("Ошибка при создании спринта: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать спринт")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, sprint)
+}
+
+// GetSprints godoc
+// @Summary Получить спринты
+// @Description Возвращает спринты пользователя в порядке начала
+// @Tags sprints
+// @Produce json
+// @Success 200 {array} models.Sprint "Список спринтов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints [get]
+func (h *SprintHandler) GetSprints(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	sprints, err := h.Sprints.GetSprints(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении спринтов: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить спринты")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sprints)
+}
+
+// GetSprint godoc
+// @Summary Получить спринт
+// @Tags sprints
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Success 200 {object} models.Sprint "Спринт"
+// @Failure 400 {object} map[string]string "Неверный ID спринта"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID} [get]
+func (h *SprintHandler) GetSprint(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	sprint, err := h.Sprints.GetSprint(r.Context(), id, userID)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sprint)
+}
+
+// UpdateSprint godoc
+// @Summary Изменить спринт
+// @Description Заменяет название, цель и даты спринта. Диаграммы пересчитываются по новым датам
+// @Tags sprints
+// @Accept json
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Param sprint body models.SprintPayload true "Новые данные спринта"
+// @Success 200 {object} models.Sprint "Измененный спринт"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID} [put]
+func (h *SprintHandler) UpdateSprint(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	sprint, ok := decodeSprint(w, r, userID)
+	if !ok {
+		return
+	}
+	sprint.ID = id
+	if err := h.Sprints.UpdateSprint(r.Context(), sprint); err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sprint)
+}
+
+// DeleteSprint godoc
+// @Summary Удалить спринт
+// @Description Удаляет спринт; его задачи возвращаются в бэклог
+// @Tags sprints
+// @Param sprintID path int true "ID спринта"
+// @Success 204 "Спринт удален"
+// @Failure 400 {object} map[string]string "Неверный ID спринта"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID} [delete]
+func (h *SprintHandler) DeleteSprint(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Sprints.DeleteSprint(r.Context(), id, userID); err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSprintTasks godoc
+// @Summary Задачи спринта
+// @Tags sprints
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Success 200 {array} models.Task "Задачи спринта"
+// @Failure 400 {object} map[string]string "Неверный ID спринта"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID}/tasks [get]
+func (h *SprintHandler) GetSprintTasks(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	tasks, err := h.Sprints.GetSprintTasks(r.Context(), id, userID)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tasks)
+}
+
+// AddSprintTasks godoc
+// @Summary Добавить задачи в спринт
+// @Description Переносит задачи в спринт, в том числе из других спринтов. Все задачи добавляются одной транзакцией
+// @Tags sprints
+// @Accept json
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Param tasks body models.SprintTasksPayload true "ID задач"
+// @Success 200 {array} models.Task "Задачи после добавления"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Спринт или задача не найдены"
+// @Failure 409 {object} map[string]string "Спринт закрыт"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID}/tasks [post]
+func (h *SprintHandler) AddSprintTasks(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	var payload models.SprintTasksPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if len(payload.TaskIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Список задач не может быть пустым")
+		return
+	}
+	if len(payload.TaskIDs) > maxSprintTasks {
+		respondWithError(w, http.StatusBadRequest, "Не больше "+strconv.Itoa(maxSprintTasks)+" задач за один запрос")
+		return
+	}
+	tasks, err := h.Sprints.AddSprintTasks(r.Context(), id, userID, payload.TaskIDs)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tasks)
+}
+
+// RemoveSprintTask godoc
+// @Summary Убрать задачу из спринта
+// @Description Возвращает задачу спринта в бэклог
+// @Tags sprints
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Param taskID path int true "ID задачи"
+// @Success 200 {object} models.Task "Задача после изменения"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 404 {object} map[string]string "Спринт или задача не найдены"
+// @Failure 409 {object} map[string]string "Спринт закрыт"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID}/tasks/{taskID} [delete]
+func (h *SprintHandler) RemoveSprintTask(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return
+	}
+	task, err := h.Sprints.RemoveSprintTask(r.Context(), id, userID, taskID)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// GetSprintBurnup godoc
+// @Summary Диаграмма сгорания вверх
+// @Description Возвращает объем спринта и выполненную часть (в задачах и очках оценки) на конец каждого дня от начала спринта до окончания, текущего дня или закрытия. Данные восстанавливаются по журналу изменений задач
+// @Tags sprints
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Param done query string false "Выполненные статусы через запятую, по умолчанию completed"
+// @Success 200 {object} models.SprintBurnup "Данные диаграммы"
+// @Failure 400 {object} map[string]string "Неверный ID спринта"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID}/burnup [get]
+func (h *SprintHandler) GetSprintBurnup(w http.ResponseWriter, r *http.Request) {
+	sprint, days, ok := h.sprintDays(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.SprintBurnup{SprintID: sprint.ID, Days: days})
+}
+
+// GetSprintBurndown godoc
+// @Summary Диаграмма сгорания
+// @Description Возвращает остаток работы (в задачах и очках оценки) на конец каждого дня спринта и идеальную линию: равномерное сгорание объема первого дня к последнему дню спринта
+// @Tags sprints
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Param done query string false "Выполненные статусы через запятую, по умолчанию completed"
+// @Success 200 {object} models.SprintBurndown "Данные диаграммы"
+// @Failure 400 {object} map[string]string "Неверный ID спринта"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID}/burndown [get]
+func (h *SprintHandler) GetSprintBurndown(w http.ResponseWriter, r *http.Request) {
+	sprint, days, ok := h.sprintDays(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, buildBurndown(sprint, days))
+}
+
+// CloseSprint godoc
+// @Summary Закрыть спринт
+// @Description Фиксирует итоги спринта. Задачи в выполненных статусах остаются в спринте, остальные переносятся в указанный или ближайший следующий незакрытый спринт, а если его нет - в бэклог
+// @Tags sprints
+// @Accept json
+// @Produce json
+// @Param sprintID path int true "ID спринта"
+// @Param params body models.SprintClosePayload false "Параметры закрытия"
+// @Success 200 {object} models.Sprint "Закрытый спринт с итогами"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Спринт не найден"
+// @Failure 409 {object} map[string]string "Спринт уже закрыт"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /sprints/{sprintID}/close [post]
+func (h *SprintHandler) CloseSprint(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return
+	}
+	var payload models.SprintClosePayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+			return
+		}
+	}
+	defer r.Body.Close()
+	nextID := 0
+	if payload.NextSprintID != nil {
+		nextID = *payload.NextSprintID
+	}
+	done := parseStatusList(strings.Join(payload.DoneStatuses, ","), "completed")
+	sprint, err := h.Sprints.CloseSprint(r.Context(), id, userID, nextID, done)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sprint)
+}
+
+// sprintDays загружает спринт и его данные по дням; при ошибке ответ уже отправлен.
+func (h *SprintHandler) sprintDays(w http.ResponseWriter, r *http.Request) (*models.Sprint, []models.SprintDay, bool) {
+	userID, id, ok := sprintRequest(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	sprint, err := h.Sprints.GetSprint(r.Context(), id, userID)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return nil, nil, false
+	}
+	done := parseStatusList(r.URL.Query().Get("done"), "completed")
+	days, err := h.Sprints.GetSprintDays(r.Context(), sprint, done)
+	if err != nil {
+		respondSprintError(w, err, id)
+		return nil, nil, false
+	}
+	return sprint, days, true
+}
+
+// buildBurndown переводит данные по дням в остаток работы и добавляет идеальную линию.
+func buildBurndown(sprint *models.Sprint, days []models.SprintDay) models.SprintBurndown {
+	burndown := models.SprintBurndown{SprintID: sprint.ID, Days: []models.BurndownDay{}}
+	if len(days) == 0 {
+		return burndown
+	}
+	start, _ := time.Parse("2006-01-02", sprint.StartDate)
+	end, _ := time.Parse("2006-01-02", sprint.EndDate)
+	total := end.Sub(start).Hours() / 24
+	baseTasks, basePoints := float64(days[0].ScopeTasks), days[0].ScopePoints
+	for _, d := range days {
+		day, _ := time.Parse("2006-01-02", d.Date)
+		remaining := 0.0
+		if total > 0 {
+			remaining = 1 - day.Sub(start).Hours()/24/total
+		}
+		burndown.Days = append(burndown.Days, models.BurndownDay{
+			Date:            d.Date,
+			RemainingTasks:  d.ScopeTasks - d.DoneTasks,
+			RemainingPoints: d.ScopePoints - d.DonePoints,
+			IdealTasks:      math.Round(baseTasks*remaining*100) / 100,
+			IdealPoints:     math.Round(basePoints*remaining*100) / 100,
+		})
+	}
+	return burndown
+}
+
+// decodeSprint читает и проверяет данные спринта; при ошибке ответ уже отправлен.
+func decodeSprint(w http.ResponseWriter, r *http.Request, userID int) (*models.Sprint, bool) {
+	var payload models.SprintPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return nil, false
+	}
+	defer r.Body.Close()
+	sprint, err := newSprint(userID, payload)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return sprint, true
+}
+
+// newSprint проверяет название и даты спринта.
+func newSprint(userID int, payload models.SprintPayload) (*models.Sprint, error) {
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		return nil, errors.New("Название спринта не может быть пустым")
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		return nil, errors.New("Название спринта длиннее 255 символов")
+	}
+	start, err := time.Parse("2006-01-02", payload.StartDate)
+	if err != nil {
+		return nil, errors.New("start_date должен быть датой в формате 2006-01-02")
+	}
+	end, err := time.Parse("2006-01-02", payload.EndDate)
+	if err != nil {
+		return nil, errors.New("end_date должен быть датой в формате 2006-01-02")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date не может быть раньше start_date")
+	}
+	if end.Sub(start) >= maxSprintDays*24*time.Hour {
+		return nil, errors.New("спринт не может быть длиннее " + strconv.Itoa(maxSprintDays) + " дней")
+	}
+	return &models.Sprint{
+		UserID:    userID,
+		Name:      name,
+		Goal:      payload.Goal,
+		StartDate: payload.StartDate,
+		EndDate:   payload.EndDate,
+	}, nil
+}
+
+// sprintRequest извлекает пользователя и ID спринта; при ошибке ответ уже отправлен.
+func sprintRequest(w http.ResponseWriter, r *http.Request) (userID int, id int, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	id, err = strconv.Atoi(chi.URLParam(r, "sprintID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID спринта")
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func respondSprintError(w http.ResponseWriter, err error, id int) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Спринт или задача не найдены")
+	case errors.Is(err, storage.ErrSprintClosed):
+		respondWithError(w, http.StatusConflict, "Спринт закрыт")
+	default:
+		log.Printf("Ошибка при работе со спринтом %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+	}
+}
@@ -1,14 +1,19 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"kanban-backend/internal/ical"
 	"kanban-backend/internal/models"  // Замените на свой путь
 	"kanban-backend/internal/storage" // Замените
 
@@ -17,12 +22,13 @@ import (
 
 // TaskHandler обрабатывает HTTP запросы, связанные с задачами.
 type TaskHandler struct {
-	Store storage.TaskStore
+	Store  storage.TaskStore
+	Fields storage.FieldStore
 }
 
 // NewTaskHandler создает новый экземпляр TaskHandler.
-func NewTaskHandler(store storage.TaskStore) *TaskHandler {
-	return &TaskHandler{Store: store}
+func NewTaskHandler(store storage.TaskStore, fields storage.FieldStore) *TaskHandler {
+	return &TaskHandler{Store: store, Fields: fields}
 }
 
 // --- Вспомогательные функции ---
@@ -86,11 +92,28 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Название задачи не может быть пустым")
 		return
 	}
+	rrule, err := normalizeRecurrence(payload.RRule, payload.DueAt)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateEstimate(payload.Estimate); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	customFields, ok := h.customFieldValues(w, r, userID, payload.CustomFields)
+	if !ok {
+		return
+	}
 	task := models.Task{
-		Title:       payload.Title,
-		Description: payload.Description,
-		Status:      "pending",
-		UserID:      userID,
+		Title:        payload.Title,
+		Description:  payload.Description,
+		DueAt:        payload.DueAt,
+		RRule:        rrule,
+		Estimate:     payload.Estimate,
+		CustomFields: customFields,
+		Status:       "pending",
+		UserID:       userID,
 	}
 	id, err := h.Store.CreateTask(r.Context(), &task)
 	if err != nil {
@@ -105,10 +128,13 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 // GetTasks godoc
 // @Summary Получить список всех задач
-// @Description Возвращает все задачи, хранящиеся в системе
+// @Description Возвращает все задачи пользователя, кроме задач в корзине. Архивные задачи возвращаются только с include_archived=true. Задачи можно отфильтровать по пользовательским полям: cf.<ключ>=значение (для multi_select - задачи, где выбран вариант), cf.<ключ>.gte и cf.<ключ>.lte (для number и date); и отсортировать: sort=cf.<ключ> или sort=-cf.<ключ>, задачи без значения идут последними
 // @Tags tasks
 // @Produce json
+// @Param include_archived query bool false "Включить архивные задачи"
+// @Param sort query string false "Сортировка по пользовательскому полю: cf.<ключ> или -cf.<ключ>"
 // @Success 200 {array} models.Task "Список задач"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /tasks [get]
 func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
@@ -117,7 +143,11 @@ func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-	tasks, err := h.Store.GetAllTasks(r.Context(), userID)
+	query, ok := h.taskQuery(w, r, userID)
+	if !ok {
+		return
+	}
+	tasks, err := h.Store.FindTasks(r.Context(), userID, query)
 	if err != nil {
 		log.Printf("Ошибка при получении списка задач: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Не удалось получить список задач")
@@ -163,17 +193,82 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	setETag(w, task)
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// UpdateTask godoc
+// @Summary Изменить задачу
+// @Description Заменяет название, описание, статус, срок и правило повторения задачи. С заголовком If-Match изменение выполняется, только если версия задачи совпадает с ETag
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param If-Match header string false "ETag задачи, полученный ранее"
+// @Param task body models.TaskUpdatePayload true "Новые данные задачи"
+// @Success 200 {object} models.Task "Измененная задача"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 412 {object} models.Task "Задача была изменена, в ответе текущая версия"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID} [put]
+func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var payload models.TaskUpdatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if strings.TrimSpace(payload.Title) == "" {
+		respondWithError(w, http.StatusBadRequest, "Название задачи не может быть пустым")
+		return
+	}
+	if payload.RRule, err = normalizeRecurrence(payload.RRule, payload.DueAt); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateEstimate(payload.Estimate); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var ok bool
+	if payload.CustomFields, ok = h.customFieldValues(w, r, userID, payload.CustomFields); !ok {
+		return
+	}
+	task, err := h.Store.UpdateTask(r.Context(), id, userID, version, payload)
+	if err != nil {
+		h.respondChangeError(w, r, err, id, userID)
+		return
+	}
+	setETag(w, task)
 	respondWithJSON(w, http.StatusOK, task)
 }
 
 // DeleteTask godoc
 // @Summary Удалить задачу по ID
-// @Description Удаляет задачу с указанным идентификатором
+// @Description Перемещает задачу в корзину. Задачу можно восстановить, пока не истек срок хранения корзины
 // @Tags tasks
 // @Param taskID path int true "ID задачи для удаления"
+// @Param If-Match header string false "ETag задачи, полученный ранее"
 // @Success 204 "Задача успешно удалена"
 // @Failure 400 {object} map[string]string "Неверный ID задачи"
 // @Failure 404 {object} map[string]string "Задача не найдена для удаления"
+// @Failure 412 {object} models.Task "Задача была изменена, в ответе текущая версия"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /tasks/{taskID} [delete]
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
@@ -188,11 +283,18 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
 		return
 	}
-	err = h.Store.DeleteTask(r.Context(), id, userID)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = h.Store.DeleteTask(r.Context(), id, userID, version)
 	if err != nil {
-		if strings.Contains(err.Error(), "не найдена для удаления") {
+		if errors.Is(err, storage.ErrNotFound) {
 			log.Printf("Попытка удаления несуществующей задачи с ID %d", id)
 			respondWithError(w, http.StatusNotFound, "Задача не найдена для удаления")
+		} else if errors.Is(err, storage.ErrVersionConflict) {
+			h.respondVersionConflict(w, r, id, userID)
 		} else {
 			log.Printf("Ошибка при удалении задачи %d: %v", id, err)
 			respondWithError(w, http.StatusInternalServerError, "Не удалось удалить задачу")
@@ -202,3 +304,179 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Задача с ID %d успешно удалена", id)
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetTrash godoc
+// @Summary Получить корзину
+// @Description Возвращает удаленные задачи пользователя, которые еще можно восстановить
+// @Tags tasks
+// @Produce json
+// @Success 200 {array} models.Task "Список задач в корзине"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /trash [get]
+func (h *TaskHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tasks, err := h.Store.GetTrash(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении корзины: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить корзину")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tasks)
+}
+
+// RestoreTask godoc
+// @Summary Восстановить задачу из корзины
+// @Description Возвращает удаленную задачу из корзины
+// @Tags tasks
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param If-Match header string false "ETag задачи, полученный ранее"
+// @Success 200 {object} models.Task "Восстановленная задача"
+// @Failure 400 {object} map[string]string "Неверный ID задачи"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 412 {object} models.Task "Задача была изменена, в ответе текущая версия"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/restore [post]
+func (h *TaskHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	h.changeTask(w, r, h.Store.RestoreTask)
+}
+
+// ArchiveTask godoc
+// @Summary Архивировать задачу
+// @Description Скрывает задачу из списка задач; она остается доступной по ID и с include_archived=true
+// @Tags tasks
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param If-Match header string false "ETag задачи, полученный ранее"
+// @Success 200 {object} models.Task "Архивированная задача"
+// @Failure 400 {object} map[string]string "Неверный ID задачи"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 412 {object} models.Task "Задача была изменена, в ответе текущая версия"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/archive [post]
+func (h *TaskHandler) ArchiveTask(w http.ResponseWriter, r *http.Request) {
+	h.changeTask(w, r, h.Store.ArchiveTask)
+}
+
+// UnarchiveTask godoc
+// @Summary Вернуть задачу из архива
+// @Description Возвращает архивную задачу в список задач
+// @Tags tasks
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param If-Match header string false "ETag задачи, полученный ранее"
+// @Success 200 {object} models.Task "Задача"
+// @Failure 400 {object} map[string]string "Неверный ID задачи"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 412 {object} models.Task "Задача была изменена, в ответе текущая версия"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/archive [delete]
+func (h *TaskHandler) UnarchiveTask(w http.ResponseWriter, r *http.Request) {
+	h.changeTask(w, r, h.Store.UnarchiveTask)
+}
+
+// changeTask выполняет изменение задачи, не требующее тела запроса, и возвращает результат.
+func (h *TaskHandler) changeTask(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id int, userID int, version int) (*models.Task, error)) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	task, err := change(r.Context(), id, userID, version)
+	if err != nil {
+		h.respondChangeError(w, r, err, id, userID)
+		return
+	}
+	setETag(w, task)
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// respondChangeError отвечает на ошибку изменения задачи.
+func (h *TaskHandler) respondChangeError(w http.ResponseWriter, r *http.Request, err error, id int, userID int) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Задача не найдена")
+	case errors.Is(err, storage.ErrVersionConflict):
+		h.respondVersionConflict(w, r, id, userID)
+	default:
+		log.Printf("Ошибка при изменении задачи %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось изменить задачу")
+	}
+}
+
+// respondVersionConflict отвечает 412 Precondition Failed с текущим состоянием задачи,
+// чтобы клиент мог повторить изменение без лишнего запроса.
+func (h *TaskHandler) respondVersionConflict(w http.ResponseWriter, r *http.Request, id int, userID int) {
+	task, err := h.Store.GetTaskByID(r.Context(), id, userID)
+	if err != nil {
+		// Задача в корзине или недоступна - отдать ее текущее состояние нельзя
+		respondWithError(w, http.StatusPreconditionFailed, "Задача была изменена другим запросом")
+		return
+	}
+	setETag(w, task)
+	respondWithJSON(w, http.StatusPreconditionFailed, task)
+}
+
+// normalizeRecurrence проверяет правило повторения и приводит его к каноническому виду.
+// Серия повторений отсчитывается от срока задачи, поэтому правило требует due_at.
+func normalizeRecurrence(rrule string, dueAt *time.Time) (string, error) {
+	if strings.TrimSpace(rrule) == "" {
+		return "", nil
+	}
+	rule, err := ical.ParseRule(rrule)
+	if err != nil {
+		return "", fmt.Errorf("неверное правило повторения: %w", err)
+	}
+	if dueAt == nil {
+		return "", errors.New("для повторяющейся задачи нужен срок due_at")
+	}
+	return rule.String(), nil
+}
+
+// maxEstimate - наибольшая допустимая оценка задачи.
+const maxEstimate = 1000000
+
+// validateEstimate проверяет оценку трудоемкости задачи.
+func validateEstimate(estimate *float64) error {
+	if estimate != nil && (math.IsNaN(*estimate) || *estimate < 0 || *estimate > maxEstimate) {
+		return fmt.Errorf("оценка должна быть числом от 0 до %d", maxEstimate)
+	}
+	return nil
+}
+
+// setETag передает версию задачи в заголовке ETag.
+func setETag(w http.ResponseWriter, task *models.Task) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(task.Version)))
+}
+
+// ifMatchVersion возвращает версию задачи из заголовка If-Match.
+// 0 означает, что заголовок не передан или равен "*", и версия не проверяется.
+func ifMatchVersion(r *http.Request) (int, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	unquoted, err := strconv.Unquote(v)
+	if err != nil {
+		return 0, errors.New("If-Match должен содержать ETag задачи в кавычках")
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, errors.New("Неверный ETag в If-Match")
+	}
+	return version, nil
+}
@@ -0,0 +1,365 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TemplateHandler обрабатывает HTTP запросы, связанные с шаблонами задач.
+type TemplateHandler struct {
+	Templates storage.TemplateStore
+	Tasks     storage.TaskStore
+	Fields    storage.FieldStore
+}
+
+// NewTemplateHandler создает новый экземпляр TemplateHandler.
+func NewTemplateHandler(templates storage.TemplateStore, tasks storage.TaskStore, fields storage.FieldStore) *TemplateHandler {
+	return &TemplateHandler{Templates: templates, Tasks: tasks, Fields: fields}
+}
+
+// CreateTemplate godoc
+// @Summary Создать шаблон
+// @Description Сохраняет набор задач как шаблон. Сроки задаются в часах от момента создания задач из шаблона
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param template body models.TemplatePayload true "Данные шаблона"
+// @Success 201 {object} models.Template "Шаблон создан"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates [post]
+func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.TemplatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	tpl, err := newTemplate(userID, payload)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := h.Templates.CreateTemplate(r.Context(), tpl); err != nil {
+		log.Printf("Ошибка при создании шаблона: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать шаблон")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, tpl)
+}
+
+// CreateTemplateFromTasks godoc
+// @Summary Сохранить задачи как шаблон
+// @Description Создает шаблон из существующих задач. Сроки задач пересчитываются в часы относительно relative_to
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param template body models.TemplateFromTasksPayload true "Задачи и название шаблона"
+// @Success 201 {object} models.Template "Шаблон создан"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates/from-tasks [post]
+func (h *TemplateHandler) CreateTemplateFromTasks(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.TemplateFromTasksPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	// Явно перечисленные задачи берутся и из архива
+	tasks, err := h.Tasks.GetAllTasks(r.Context(), userID, len(payload.TaskIDs) > 0)
+	if err != nil {
+		log.Printf("Ошибка при получении задач для шаблона: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать шаблон")
+		return
+	}
+	if len(payload.TaskIDs) > 0 {
+		byID := make(map[int]models.Task, len(tasks))
+		for _, task := range tasks {
+			byID[task.ID] = task
+		}
+		tasks = tasks[:0]
+		for _, id := range payload.TaskIDs {
+			task, ok := byID[id]
+			if !ok {
+				respondWithError(w, http.StatusNotFound, fmt.Sprintf("Задача %d не найдена", id))
+				return
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	relativeTo := time.Now()
+	if payload.RelativeTo != nil {
+		relativeTo = *payload.RelativeTo
+	}
+	tplTasks := make([]models.TemplateTask, 0, len(tasks))
+	for _, task := range tasks {
+		t := models.TemplateTask{Title: task.Title, Description: task.Description, Status: task.Status, RRule: task.RRule}
+		if task.DueAt != nil {
+			hours := int(math.Round(task.DueAt.Sub(relativeTo).Hours()))
+			t.DueInHours = &hours
+		}
+		tplTasks = append(tplTasks, t)
+	}
+	tpl, err := newTemplate(userID, models.TemplatePayload{Name: payload.Name, Description: payload.Description, Tasks: tplTasks})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := h.Templates.CreateTemplate(r.Context(), tpl); err != nil {
+		log.Printf("Ошибка при создании шаблона: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать шаблон")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, tpl)
+}
+
+// GetTemplates godoc
+// @Summary Получить шаблоны
+// @Description Возвращает шаблоны пользователя
+// @Tags templates
+// @Produce json
+// @Success 200 {array} models.Template "Список шаблонов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates [get]
+func (h *TemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	templates, err := h.Templates.GetTemplates(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении шаблонов: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить шаблоны")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, templates)
+}
+
+// GetTemplate godoc
+// @Summary Получить шаблон
+// @Tags templates
+// @Produce json
+// @Param templateID path int true "ID шаблона"
+// @Success 200 {object} models.Template "Шаблон"
+// @Failure 400 {object} map[string]string "Неверный ID шаблона"
+// @Failure 404 {object} map[string]string "Шаблон не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates/{templateID} [get]
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := templateRequest(w, r)
+	if !ok {
+		return
+	}
+	tpl, err := h.Templates.GetTemplate(r.Context(), id, userID)
+	if err != nil {
+		respondTemplateError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tpl)
+}
+
+// UpdateTemplate godoc
+// @Summary Изменить шаблон
+// @Description Заменяет название, описание и задачи шаблона. Задачи, уже созданные из шаблона, не меняются
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param templateID path int true "ID шаблона"
+// @Param template body models.TemplatePayload true "Новые данные шаблона"
+// @Success 200 {object} models.Template "Измененный шаблон"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Шаблон не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates/{templateID} [put]
+func (h *TemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := templateRequest(w, r)
+	if !ok {
+		return
+	}
+	var payload models.TemplatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	tpl, err := newTemplate(userID, payload)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tpl.ID = id
+	if err := h.Templates.UpdateTemplate(r.Context(), tpl); err != nil {
+		respondTemplateError(w, err, id)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tpl)
+}
+
+// DeleteTemplate godoc
+// @Summary Удалить шаблон
+// @Description Удаляет шаблон; задачи, созданные из него, остаются
+// @Tags templates
+// @Param templateID path int true "ID шаблона"
+// @Success 204 "Шаблон удален"
+// @Failure 400 {object} map[string]string "Неверный ID шаблона"
+// @Failure 404 {object} map[string]string "Шаблон не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates/{templateID} [delete]
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := templateRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Templates.DeleteTemplate(r.Context(), id, userID); err != nil {
+		respondTemplateError(w, err, id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InstantiateTemplate godoc
+// @Summary Создать задачи из шаблона
+// @Description Создает задачи шаблона одной транзакцией: либо все, либо ни одной. Сроки отсчитываются от start
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param templateID path int true "ID шаблона"
+// @Param params body models.TemplateInstantiatePayload false "Параметры"
+// @Success 201 {array} models.Task "Созданные задачи"
+// @Failure 400 {object} map[string]string "Неверный формат запроса или не заполнены обязательные пользовательские поля"
+// @Failure 404 {object} map[string]string "Шаблон не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /templates/{templateID}/instantiate [post]
+func (h *TemplateHandler) InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := templateRequest(w, r)
+	if !ok {
+		return
+	}
+	var payload models.TemplateInstantiatePayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+			return
+		}
+	}
+	defer r.Body.Close()
+	tpl, err := h.Templates.GetTemplate(r.Context(), id, userID)
+	if err != nil {
+		respondTemplateError(w, err, id)
+		return
+	}
+
+	start := time.Now().UTC()
+	if payload.Start != nil {
+		start = *payload.Start
+	}
+	tasks := make([]models.Task, 0, len(tpl.Tasks))
+	for _, t := range tpl.Tasks {
+		task := models.Task{Title: t.Title, Description: t.Description, Status: t.Status, RRule: t.RRule}
+		if t.DueInHours != nil {
+			due := start.Add(time.Duration(*t.DueInHours) * time.Hour)
+			task.DueAt = &due
+		}
+		tasks = append(tasks, task)
+	}
+	if err := NormalizeTaskCustomFields(r.Context(), h.Fields, userID, tasks); err != nil {
+		if errors.Is(err, ErrInvalidCustomFields) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Ошибка при создании задач из шаблона %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать задачи из шаблона")
+		return
+	}
+	if err := h.Tasks.ImportTasks(r.Context(), userID, tasks); err != nil {
+		log.Printf("Ошибка при создании задач из шаблона %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать задачи из шаблона")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, tasks)
+}
+
+// templateRequest извлекает пользователя и ID шаблона; при ошибке ответ уже отправлен.
+func templateRequest(w http.ResponseWriter, r *http.Request) (userID int, id int, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	id, err = strconv.Atoi(chi.URLParam(r, "templateID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID шаблона")
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func respondTemplateError(w http.ResponseWriter, err error, id int) {
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Шаблон не найден")
+		return
+	}
+	log.Printf("Ошибка при работе с шаблоном %d: %v", id, err)
+	respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+}
+
+// newTemplate проверяет данные шаблона и нормализует правила повторения задач.
+func newTemplate(userID int, payload models.TemplatePayload) (*models.Template, error) {
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		return nil, errors.New("Название шаблона не может быть пустым")
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		return nil, errors.New("Название шаблона длиннее 255 символов")
+	}
+	if len(payload.Tasks) > maxImportTasks {
+		return nil, fmt.Errorf("Не больше %d задач в шаблоне", maxImportTasks)
+	}
+	tasks := make([]models.TemplateTask, len(payload.Tasks))
+	for i, t := range payload.Tasks {
+		t.Title = strings.TrimSpace(t.Title)
+		if errs := validateExportedTask(models.ExportedTask{Title: t.Title, Status: t.Status}); len(errs) > 0 {
+			return nil, fmt.Errorf("задача %d: %s", i+1, strings.Join(errs, "; "))
+		}
+		// Для проверки правила важен только факт наличия срока
+		var due *time.Time
+		if t.DueInHours != nil {
+			due = &time.Time{}
+		}
+		rrule, err := normalizeRecurrence(t.RRule, due)
+		if err != nil {
+			return nil, fmt.Errorf("задача %d: %s", i+1, err.Error())
+		}
+		t.RRule = rrule
+		tasks[i] = t
+	}
+	return &models.Template{Name: name, Description: payload.Description, Tasks: tasks, UserID: userID}, nil
+}
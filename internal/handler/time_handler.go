@@ -0,0 +1,392 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// maxTimeEntryDuration ограничивает длину ручной записи времени.
+	maxTimeEntryDuration = 24 * time.Hour
+	maxTimeEntryNote     = 1000
+)
+
+// TimeHandler обрабатывает HTTP запросы учета времени.
+type TimeHandler struct {
+	Time storage.TimeStore
+}
+
+// NewTimeHandler создает новый экземпляр TimeHandler.
+func NewTimeHandler(store storage.TimeStore) *TimeHandler {
+	return &TimeHandler{Time: store}
+}
+
+// StartTimer godoc
+// @Summary Запустить таймер
+// @Description Запускает таймер по задаче. У пользователя может быть только один запущенный таймер; таймер хранится в базе и переживает перезапуск сервера
+// @Tags time
+// @Accept json
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param timer body models.TimerStartPayload false "Заметка"
+// @Success 201 {object} models.TimeEntry "Таймер запущен"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 409 {object} models.TimeEntry "Уже запущен другой таймер, в ответе запущенный таймер"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/timer/start [post]
+func (h *TimeHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := timeTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	var payload models.TimerStartPayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+			return
+		}
+	}
+	defer r.Body.Close()
+	if utf8.RuneCountInString(payload.Note) > maxTimeEntryNote {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Заметка длиннее %d символов", maxTimeEntryNote))
+		return
+	}
+	entry, err := h.Time.StartTimer(r.Context(), userID, taskID, payload.Note)
+	if errors.Is(err, storage.ErrTimerRunning) {
+		running, getErr := h.Time.GetRunningTimer(r.Context(), userID)
+		if getErr == nil {
+			respondWithJSON(w, http.StatusConflict, running)
+			return
+		}
+		// Таймер мог быть остановлен между запросами
+		respondWithError(w, http.StatusConflict, "Уже запущен другой таймер")
+		return
+	}
+	if err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, entry)
+}
+
+// StopTimer godoc
+// @Summary Остановить таймер
+// @Tags time
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Success 200 {object} models.TimeEntry "Остановленная запись"
+// @Failure 400 {object} map[string]string "Неверный ID задачи"
+// @Failure 404 {object} map[string]string "Таймер по задаче не запущен"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/timer/stop [post]
+func (h *TimeHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := timeTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	entry, err := h.Time.StopTimer(r.Context(), userID, taskID)
+	if err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, entry)
+}
+
+// GetRunningTimer godoc
+// @Summary Запущенный таймер
+// @Tags time
+// @Produce json
+// @Success 200 {object} models.TimeEntry "Запущенный таймер"
+// @Success 204 "Таймер не запущен"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /timer [get]
+func (h *TimeHandler) GetRunningTimer(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	entry, err := h.Time.GetRunningTimer(r.Context(), userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, entry)
+}
+
+// GetTaskTime godoc
+// @Summary Время по задаче
+// @Description Возвращает записи времени по задаче и их сумму, включая запущенный таймер
+// @Tags time
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Success 200 {object} models.TaskTime "Время по задаче"
+// @Failure 400 {object} map[string]string "Неверный ID задачи"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/time [get]
+func (h *TimeHandler) GetTaskTime(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := timeTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	total, err := h.Time.GetTaskTime(r.Context(), taskID, userID)
+	if err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, total)
+}
+
+// CreateTimeEntry godoc
+// @Summary Добавить запись времени
+// @Description Добавляет ручную запись времени по задаче
+// @Tags time
+// @Accept json
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Param entry body models.TimeEntryPayload true "Интервал и заметка"
+// @Success 201 {object} models.TimeEntry "Запись создана"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/time-entries [post]
+func (h *TimeHandler) CreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := timeTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	entry, ok := decodeTimeEntry(w, r)
+	if !ok {
+		return
+	}
+	entry.UserID, entry.TaskID = userID, taskID
+	if err := h.Time.CreateTimeEntry(r.Context(), entry); err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, entry)
+}
+
+// UpdateTimeEntry godoc
+// @Summary Изменить запись времени
+// @Description Заменяет интервал и заметку записи. Запущенный таймер при этом останавливается
+// @Tags time
+// @Accept json
+// @Produce json
+// @Param entryID path int true "ID записи"
+// @Param entry body models.TimeEntryPayload true "Интервал и заметка"
+// @Success 200 {object} models.TimeEntry "Измененная запись"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 404 {object} map[string]string "Запись не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /time-entries/{entryID} [put]
+func (h *TimeHandler) UpdateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := timeEntryRequest(w, r)
+	if !ok {
+		return
+	}
+	entry, ok := decodeTimeEntry(w, r)
+	if !ok {
+		return
+	}
+	entry.ID, entry.UserID = id, userID
+	if err := h.Time.UpdateTimeEntry(r.Context(), entry); err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, entry)
+}
+
+// DeleteTimeEntry godoc
+// @Summary Удалить запись времени
+// @Tags time
+// @Param entryID path int true "ID записи"
+// @Success 204 "Запись удалена"
+// @Failure 400 {object} map[string]string "Неверный ID записи"
+// @Failure 404 {object} map[string]string "Запись не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /time-entries/{entryID} [delete]
+func (h *TimeHandler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := timeEntryRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Time.DeleteTimeEntry(r.Context(), id, userID); err != nil {
+		respondTimeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTimesheet godoc
+// @Summary Табель
+// @Description Возвращает записи времени пользователя за период (дни UTC) с итогами по задачам и дням. Запись относится к дню, в который она начата; запущенный таймер учитывается до текущего момента
+// @Tags time
+// @Produce json
+// @Produce text/csv
+// @Param from query string false "Первый день (2006-01-02), по умолчанию 29 дней до to"
+// @Param to query string false "Последний день (2006-01-02), по умолчанию сегодня"
+// @Param format query string false "json (по умолчанию) или csv"
+// @Success 200 {object} models.Timesheet "Табель"
+// @Failure 400 {object} map[string]string "Неверные параметры запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /timesheet [get]
+func (h *TimeHandler) GetTimesheet(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respondWithError(w, http.StatusBadRequest, "Формат должен быть json или csv")
+		return
+	}
+	from, to, err := parseDayRange(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entries, err := h.Time.GetTimeEntries(r.Context(), userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Ошибка при получении табеля пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить табель")
+		return
+	}
+	if format != "csv" {
+		respondWithJSON(w, http.StatusOK, buildTimesheet(from, to, entries))
+		return
+	}
+
+	filename := fmt.Sprintf("timesheet-%s-%s.csv", from.Format("20060102"), to.Format("20060102"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "task_id", "task_title", "started_at", "ended_at", "duration_hours", "note"})
+	for _, e := range entries {
+		cw.Write([]string{e.StartedAt.UTC().Format("2006-01-02"), strconv.Itoa(e.TaskID), e.TaskTitle,
+			formatCSVTime(&e.StartedAt), formatCSVTime(e.EndedAt), strconv.FormatFloat(float64(e.DurationSeconds)/3600, 'f', 2, 64), e.Note})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Ошибка при отправке табеля пользователя %d: %v", userID, err)
+	}
+}
+
+// buildTimesheet суммирует записи по задачам и дням; дни без записей заполняются нулями.
+func buildTimesheet(from, to time.Time, entries []models.TimeEntry) *models.Timesheet {
+	sheet := &models.Timesheet{
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Tasks:   []models.TimesheetTask{},
+		Days:    []models.TimesheetDay{},
+		Entries: entries,
+	}
+	byTask := map[int]*models.TimesheetTask{}
+	byDay := map[string]int64{}
+	for _, e := range entries {
+		sheet.TotalSeconds += e.DurationSeconds
+		byDay[e.StartedAt.UTC().Format("2006-01-02")] += e.DurationSeconds
+		task, ok := byTask[e.TaskID]
+		if !ok {
+			task = &models.TimesheetTask{TaskID: e.TaskID, TaskTitle: e.TaskTitle}
+			byTask[e.TaskID] = task
+		}
+		task.TotalSeconds += e.DurationSeconds
+	}
+	for _, task := range byTask {
+		sheet.Tasks = append(sheet.Tasks, *task)
+	}
+	sort.Slice(sheet.Tasks, func(i, j int) bool {
+		if sheet.Tasks[i].TotalSeconds != sheet.Tasks[j].TotalSeconds {
+			return sheet.Tasks[i].TotalSeconds > sheet.Tasks[j].TotalSeconds
+		}
+		return sheet.Tasks[i].TaskID < sheet.Tasks[j].TaskID
+	})
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		sheet.Days = append(sheet.Days, models.TimesheetDay{Date: date, TotalSeconds: byDay[date]})
+	}
+	return sheet
+}
+
+// decodeTimeEntry читает и проверяет ручную запись времени; при ошибке ответ уже отправлен.
+func decodeTimeEntry(w http.ResponseWriter, r *http.Request) (*models.TimeEntry, bool) {
+	var payload models.TimeEntryPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return nil, false
+	}
+	defer r.Body.Close()
+	switch {
+	case payload.StartedAt.IsZero() || payload.EndedAt.IsZero():
+		respondWithError(w, http.StatusBadRequest, "Нужно указать started_at и ended_at")
+	case payload.EndedAt.Before(payload.StartedAt):
+		respondWithError(w, http.StatusBadRequest, "ended_at не может быть раньше started_at")
+	case payload.EndedAt.Sub(payload.StartedAt) > maxTimeEntryDuration:
+		respondWithError(w, http.StatusBadRequest, "Запись не может быть длиннее 24 часов")
+	case utf8.RuneCountInString(payload.Note) > maxTimeEntryNote:
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Заметка длиннее %d символов", maxTimeEntryNote))
+	default:
+		return &models.TimeEntry{StartedAt: payload.StartedAt, EndedAt: &payload.EndedAt, Note: payload.Note}, true
+	}
+	return nil, false
+}
+
+// timeTaskRequest извлекает пользователя и ID задачи; при ошибке ответ уже отправлен.
+func timeTaskRequest(w http.ResponseWriter, r *http.Request) (userID int, taskID int, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	taskID, err = strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return 0, 0, false
+	}
+	return userID, taskID, true
+}
+
+// timeEntryRequest извлекает пользователя и ID записи времени; при ошибке ответ уже отправлен.
+func timeEntryRequest(w http.ResponseWriter, r *http.Request) (userID int, id int64, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	id, err = strconv.ParseInt(chi.URLParam(r, "entryID"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID записи")
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func respondTimeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Задача, таймер или запись не найдены")
+		return
+	}
+	log.Printf("Ошибка учета времени: %v", err)
+	respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера")
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kanban-backend/internal/storage"
+	"kanban-backend/internal/trello"
+)
+
+// maxTrelloExportSize - экспорт Trello включает историю действий и бывает большим.
+const maxTrelloExportSize = 50 << 20
+
+// TrelloHandler импортирует доски Trello.
+type TrelloHandler struct {
+	Tasks  storage.TaskStore
+	Users  storage.UserStore
+	Fields storage.FieldStore
+}
+
+// NewTrelloHandler создает новый экземпляр TrelloHandler.
+func NewTrelloHandler(tasks storage.TaskStore, users storage.UserStore, fields storage.FieldStore) *TrelloHandler {
+	return &TrelloHandler{Tasks: tasks, Users: users, Fields: fields}
+}
+
+// UserExists возвращает функцию для trello.Convert, проверяющую пользователя по username.
+func UserExists(ctx context.Context, users storage.UserStore) func(username string) bool {
+	return func(username string) bool {
+		_, err := users.GetUserByUsername(ctx, username)
+		return err == nil
+	}
+}
+
+// ImportTrello godoc
+// @Summary Импорт доски Trello
+// @Description Создает задачи из JSON-экспорта доски Trello: карточки становятся задачами, название списка - статусом, закрытые карточки - архивными, срок карточки - сроком задачи. Метки, чек-листы и комментарии пропускаются, участники сопоставляются с пользователями по username. Возвращает сводку импорта
+// @Tags export
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Только построить сводку, не создавая задачи"
+// @Success 200 {object} trello.Report "Сводка (dry_run)"
+// @Success 201 {object} trello.Report "Задачи созданы"
+// @Failure 400 {object} map[string]string "Неверный экспорт Trello или не заполнены обязательные пользовательские поля"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /import/trello [post]
+func (h *TrelloHandler) ImportTrello(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Неверное значение dry_run")
+			return
+		}
+	}
+	body := http.MaxBytesReader(w, r.Body, maxTrelloExportSize)
+	defer body.Close()
+	board, err := trello.Decode(body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tasks, report := trello.Convert(board, UserExists(r.Context(), h.Users))
+	report.DryRun = dryRun
+	if err := NormalizeTaskCustomFields(r.Context(), h.Fields, userID, tasks); err != nil {
+		if errors.Is(err, ErrInvalidCustomFields) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Ошибка импорта доски Trello: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось импортировать доску")
+		return
+	}
+	if dryRun || len(tasks) == 0 {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+	if err := h.Tasks.ImportTasks(r.Context(), userID, tasks); err != nil {
+		log.Printf("Ошибка импорта доски Trello: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось импортировать доску")
+		return
+	}
+	report.Created = len(tasks)
+	for _, t := range tasks {
+		report.TaskIDs = append(report.TaskIDs, t.ID)
+	}
+	respondWithJSON(w, http.StatusCreated, report)
+}
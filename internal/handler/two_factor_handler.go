@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban-backend/internal/auth"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	recoveryCodeCount = 10
+	// После стольких неверных кодов подряд проверка кодов блокируется на secondFactorLockout
+	maxSecondFactorAttempts = 5
+	secondFactorLockout     = 15 * time.Minute
+)
+
+var (
+	errSecondFactorInvalid = errors.New("неверный код")
+	errSecondFactorLocked  = errors.New("слишком много неверных кодов")
+)
+
+// verifySecondFactor проверяет код TOTP или код восстановления пользователя с включенной 2FA.
+// Если 2FA не включена, возвращает ошибку с storage.ErrNotFound.
+func verifySecondFactor(ctx context.Context, store storage.TwoFactorStore, userID int, code string) error {
+	t, err := store.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if t.ConfirmedAt == nil {
+		return storage.ErrNotFound
+	}
+	// Попытка учитывается до проверки кода: иначе параллельные запросы успели бы
+	// перебрать коды между проверкой блокировки и записью неудачи
+	now := time.Now()
+	reserved, err := store.ReserveTwoFactorAttempt(ctx, userID, maxSecondFactorAttempts, now, now.Add(secondFactorLockout))
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return errSecondFactorLocked
+	}
+	code = strings.TrimSpace(code)
+	if counter, ok := auth.ValidateTOTP(t.Secret, code, now, t.LastCounter); ok {
+		used, err := store.UseTOTPCounter(ctx, userID, counter)
+		if err != nil || used {
+			return err
+		}
+	} else if auth.IsRecoveryCode(code) {
+		used, err := store.UseRecoveryCode(ctx, userID, auth.HashRecoveryCode(code))
+		if err != nil || used {
+			return err
+		}
+	}
+	return errSecondFactorInvalid
+}
+
+// respondSecondFactorError отвечает на ошибку verifySecondFactor.
+func respondSecondFactorError(w http.ResponseWriter, userID int, err error) {
+	switch {
+	case errors.Is(err, errSecondFactorInvalid):
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+	case errors.Is(err, errSecondFactorLocked):
+		respondWithError(w, http.StatusTooManyRequests, "Too many invalid codes, try again later")
+	case errors.Is(err, storage.ErrNotFound):
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is not enabled")
+	default:
+		log.Printf("Ошибка при проверке кода 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+	}
+}
+
+// newRecoveryCodes создает коды восстановления и их хеши для хранения.
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	codes, err = auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes = make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = auth.HashRecoveryCode(c)
+	}
+	return codes, hashes, nil
+}
+
+// TwoFactorHandler обрабатывает HTTP запросы подключения и отключения двухфакторной аутентификации.
+type TwoFactorHandler struct {
+	TwoFactor storage.TwoFactorStore
+	Accounts  storage.AccountStore
+	// Issuer - название сервиса в приложении-аутентификаторе
+	Issuer string
+	// Admins - имена пользователей, которым разрешено сбрасывать 2FA других пользователей
+	Admins []string
+}
+
+// NewTwoFactorHandler создает новый экземпляр TwoFactorHandler.
+func NewTwoFactorHandler(twoFactor storage.TwoFactorStore, accounts storage.AccountStore, issuer string, admins []string) *TwoFactorHandler {
+	return &TwoFactorHandler{TwoFactor: twoFactor, Accounts: accounts, Issuer: issuer, Admins: admins}
+}
+
+// GetStatus godoc
+// @Summary Состояние двухфакторной аутентификации
+// @Tags 2fa
+// @Produce json
+// @Success 200 {object} models.TwoFactorStatus "Состояние"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /2fa [get]
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var status models.TwoFactorStatus
+	t, err := h.TwoFactor.GetTOTP(r.Context(), userID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Ошибка при получении 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get two-factor status")
+		return
+	}
+	if t != nil && t.ConfirmedAt != nil {
+		status.Enabled = true
+		status.RecoveryCodesLeft, err = h.TwoFactor.RecoveryCodesLeft(r.Context(), userID)
+		if err != nil {
+			log.Printf("Ошибка при подсчете кодов восстановления пользователя %d: %v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get two-factor status")
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// Enroll godoc
+// @Summary Начать подключение 2FA
+// @Description Создает секрет TOTP и адрес otpauth:// для приложения-аутентификатора. 2FA включается только после POST /2fa/confirm с первым кодом; повторный вызов до подтверждения заменяет секрет
+// @Tags 2fa
+// @Produce json
+// @Success 200 {object} models.TwoFactorEnrollment "Секрет и адрес для QR-кода"
+// @Failure 409 {object} map[string]string "2FA уже включена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /2fa/enroll [post]
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	user, err := h.Accounts.GetAccount(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Ошибка генерации секрета 2FA: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+	if err := h.TwoFactor.StartTOTPEnrollment(r.Context(), userID, secret); err != nil {
+		if errors.Is(err, storage.ErrTwoFactorEnabled) {
+			respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+			return
+		}
+		log.Printf("Ошибка при подключении 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+	// Секрет и коды восстановления показываются один раз: no-store запрещает сохранять ответ
+	// в кешах и для повторов с Idempotency-Key, иначе их копия осталась бы в открытом виде
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    auth.TOTPURI(h.Issuer, user.Username, secret),
+	})
+}
+
+// Confirm godoc
+// @Summary Подтвердить подключение 2FA
+// @Description Проверяет первый код из приложения-аутентификатора, включает 2FA и возвращает коды восстановления. Коды показываются один раз
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param payload body models.TwoFactorCodePayload true "Код из приложения"
+// @Success 200 {object} models.RecoveryCodes "Коды восстановления"
+// @Failure 400 {object} map[string]string "Неверный код"
+// @Failure 409 {object} map[string]string "Подключение не начато или 2FA уже включена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /2fa/confirm [post]
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	t, err := h.TwoFactor.GetTOTP(r.Context(), userID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && t.ConfirmedAt != nil) {
+		respondWithError(w, http.StatusConflict, "No pending enrollment, call /2fa/enroll first")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при получении 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to confirm enrollment")
+		return
+	}
+	counter, ok := auth.ValidateTOTP(t.Secret, strings.TrimSpace(payload.Code), time.Now(), t.LastCounter)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Printf("Ошибка генерации кодов восстановления: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to confirm enrollment")
+		return
+	}
+	if err := h.TwoFactor.ConfirmTOTP(r.Context(), userID, counter, hashes); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respondWithError(w, http.StatusConflict, "No pending enrollment, call /2fa/enroll first")
+			return
+		}
+		log.Printf("Ошибка при включении 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to confirm enrollment")
+		return
+	}
+	log.Printf("Пользователь %d включил 2FA", userID)
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, models.RecoveryCodes{Codes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Новые коды восстановления
+// @Description Заменяет все коды восстановления новыми. Требует действующий код из приложения или код восстановления
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param payload body models.TwoFactorCodePayload true "Код"
+// @Success 200 {object} models.RecoveryCodes "Новые коды восстановления"
+// @Failure 401 {object} map[string]string "Неверный код"
+// @Failure 409 {object} map[string]string "2FA не включена"
+// @Failure 429 {object} map[string]string "Слишком много неверных кодов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if err := verifySecondFactor(r.Context(), h.TwoFactor, userID, payload.Code); err != nil {
+		respondSecondFactorError(w, userID, err)
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = h.TwoFactor.ReplaceRecoveryCodes(r.Context(), userID, hashes)
+	}
+	if err != nil {
+		log.Printf("Ошибка при замене кодов восстановления пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to regenerate recovery codes")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, models.RecoveryCodes{Codes: codes})
+}
+
+// Disable godoc
+// @Summary Отключить 2FA
+// @Description Отключает двухфакторную аутентификацию. Требует действующий код из приложения или код восстановления
+// @Tags 2fa
+// @Accept json
+// @Param payload body models.TwoFactorCodePayload true "Код"
+// @Success 204 "2FA отключена"
+// @Failure 401 {object} map[string]string "Неверный код"
+// @Failure 409 {object} map[string]string "2FA не включена"
+// @Failure 429 {object} map[string]string "Слишком много неверных кодов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /2fa [delete]
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if err := verifySecondFactor(r.Context(), h.TwoFactor, userID, payload.Code); err != nil {
+		respondSecondFactorError(w, userID, err)
+		return
+	}
+	if _, err := h.TwoFactor.DisableTwoFactor(r.Context(), userID); err != nil {
+		log.Printf("Ошибка при отключении 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+	log.Printf("Пользователь %d отключил 2FA", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminReset godoc
+// @Summary Сбросить 2FA пользователя
+// @Description Отключает двухфакторную аутентификацию пользователя, потерявшего доступ к приложению и кодам восстановления. Доступно только администраторам из ADMIN_USERNAMES
+// @Tags 2fa
+// @Param userID path int true "ID пользователя"
+// @Success 204 "2FA сброшена"
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "У пользователя не включена 2FA"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /admin/users/{userID}/2fa [delete]
+func (h *TwoFactorHandler) AdminReset(w http.ResponseWriter, r *http.Request) {
+	adminID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	admin, err := h.Accounts.GetAccount(r.Context(), adminID)
+	if err != nil {
+		log.Printf("Ошибка при получении пользователя %d: %v", adminID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset two-factor authentication")
+		return
+	}
+	if !contains(h.Admins, admin.Username) {
+		respondWithError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	enabled, err := h.TwoFactor.DisableTwoFactor(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при сбросе 2FA пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset two-factor authentication")
+		return
+	}
+	if !enabled {
+		respondWithError(w, http.StatusNotFound, "Two-factor authentication is not enabled for this user")
+		return
+	}
+	log.Printf("Администратор %s (%d) сбросил 2FA пользователя %d", admin.Username, adminID, userID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+type enrollTwoFactor struct {
+	storage.TwoFactorStore
+	secret string
+}
+
+func (s *enrollTwoFactor) StartTOTPEnrollment(ctx context.Context, userID int, secret string) error {
+	s.secret = secret
+	return nil
+}
+
+type fakeAccounts struct {
+	storage.AccountStore
+}
+
+func (fakeAccounts) GetAccount(ctx context.Context, userID int) (*models.User, error) {
+	return &models.User{ID: userID, Username: "alice"}, nil
+}
+
+// Секрет TOTP не должен попадать в сохраненные ответы Idempotency-Key.
+func TestEnrollResponseIsNotStored(t *testing.T) {
+	twoFactor := &enrollTwoFactor{}
+	h := NewTwoFactorHandler(twoFactor, fakeAccounts{}, "Kanban", nil)
+	store := newMemoryIdempotencyStore()
+	handler := NewIdempotencyMiddleware(store).Handler(http.HandlerFunc(h.Enroll))
+
+	rec := idempotentRequest(handler, "enroll-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body)
+	}
+	var enrollment models.TwoFactorEnrollment
+	if err := json.NewDecoder(rec.Body).Decode(&enrollment); err != nil {
+		t.Fatal(err)
+	}
+	if enrollment.Secret == "" || enrollment.Secret != twoFactor.secret {
+		t.Fatalf("в ответе секрет %q, сохранен %q", enrollment.Secret, twoFactor.secret)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control %q, ожидался no-store", got)
+	}
+	if len(store.responses) != 0 {
+		t.Errorf("ответ с секретом TOTP сохранен для повтора")
+	}
+
+	// Повтор с тем же ключом не возвращает прежний секрет
+	again := idempotentRequest(handler, "enroll-1")
+	var second models.TwoFactorEnrollment
+	if err := json.NewDecoder(again.Body).Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if second.Secret == enrollment.Secret {
+		t.Error("повтор вернул прежний секрет")
+	}
+}
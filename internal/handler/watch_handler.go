@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kanban-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WatchHandler обрабатывает HTTP запросы подписки на задачи.
+type WatchHandler struct {
+	Watches storage.WatchStore
+}
+
+// NewWatchHandler создает новый экземпляр WatchHandler.
+func NewWatchHandler(store storage.WatchStore) *WatchHandler {
+	return &WatchHandler{Watches: store}
+}
+
+// WatchTask godoc
+// @Summary Отслеживать задачу
+// @Description Подписывает пользователя на изменения задачи: наблюдатели получают уведомления task_updated, а при изменении сразу нескольких задач - одно сводное уведомление tasks_updated. Отслеживать задачу может ее владелец и пользователи, упомянутые в ней. Создатель и упомянутые пользователи подписываются автоматически
+// @Tags watchers
+// @Param taskID path int true "ID задачи"
+// @Success 204 "Пользователь отслеживает задачу"
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/watch [post]
+func (h *WatchHandler) WatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := watchRequest(w, r)
+	if !ok {
+		return
+	}
+	allowed, err := h.Watches.CanWatch(r.Context(), taskID, userID)
+	if err == nil && !allowed {
+		respondWithError(w, http.StatusNotFound, "Задача не найдена")
+		return
+	}
+	if err == nil {
+		err = h.Watches.Watch(r.Context(), taskID, userID)
+	}
+	if err != nil {
+		log.Printf("Ошибка при подписке на задачу %d: %v", taskID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось подписаться на задачу")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnwatchTask godoc
+// @Summary Перестать отслеживать задачу
+// @Description Отписывает пользователя от задачи. Повторная отписка не считается ошибкой
+// @Tags watchers
+// @Param taskID path int true "ID задачи"
+// @Success 204 "Пользователь больше не отслеживает задачу"
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/watch [delete]
+func (h *WatchHandler) UnwatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := watchRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Watches.Unwatch(r.Context(), taskID, userID); err != nil {
+		log.Printf("Ошибка при отписке от задачи %d: %v", taskID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось отписаться от задачи")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWatchers godoc
+// @Summary Наблюдатели задачи
+// @Description Возвращает пользователей, отслеживающих задачу. Доступно владельцу задачи
+// @Tags watchers
+// @Produce json
+// @Param taskID path int true "ID задачи"
+// @Success 200 {array} models.Watcher "Наблюдатели"
+// @Failure 400 {object} map[string]string "Неверный формат ID"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /tasks/{taskID}/watchers [get]
+func (h *WatchHandler) GetWatchers(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := watchRequest(w, r)
+	if !ok {
+		return
+	}
+	watchers, err := h.Watches.GetWatchers(r.Context(), taskID, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Задача не найдена")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при получении наблюдателей задачи %d: %v", taskID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить наблюдателей")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, watchers)
+}
+
+// GetWatching godoc
+// @Summary Отслеживаемые задачи
+// @Description Возвращает задачи, которые отслеживает пользователь, начиная с последних подписок
+// @Tags watchers
+// @Produce json
+// @Success 200 {array} models.WatchedTask "Отслеживаемые задачи"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /watching [get]
+func (h *WatchHandler) GetWatching(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	watching, err := h.Watches.GetWatching(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении отслеживаемых задач пользователя %d: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить отслеживаемые задачи")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, watching)
+}
+
+func watchRequest(w http.ResponseWriter, r *http.Request) (userID int, taskID int, ok bool) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	taskID, err = strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID задачи")
+		return 0, 0, false
+	}
+	return userID, taskID, true
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+	"kanban-backend/internal/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookHandler обрабатывает HTTP запросы, связанные с исходящими webhook.
+type WebhookHandler struct {
+	Store storage.WebhookStore
+}
+
+// NewWebhookHandler создает новый экземпляр WebhookHandler.
+func NewWebhookHandler(store storage.WebhookStore) *WebhookHandler {
+	return &WebhookHandler{Store: store}
+}
+
+// CreateWebhook godoc
+// @Summary Создать подписку webhook
+// @Description Подписывает URL на события задач пользователя. Запросы подписываются HMAC-SHA256 в заголовке X-Kanban-Signature; секрет возвращается только в этом ответе. Адреса во внутренней сети (loopback, частные, link-local) не принимаются
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookCreatePayload true "Данные подписки"
+// @Success 201 {object} models.Webhook "Подписка создана"
+// @Failure 400 {object} map[string]string "Неверный формат запроса"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var payload models.WebhookCreatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат JSON: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	u, err := webhook.CheckURL(r.Context(), payload.URL)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, t := range payload.Events {
+		if !events.IsKnownType(t) {
+			respondWithError(w, http.StatusBadRequest, "Неизвестный тип события: "+t)
+			return
+		}
+	}
+	if payload.Secret == "" {
+		if payload.Secret, err = webhook.GenerateSecret(); err != nil {
+			log.Printf("Ошибка генерации секрета webhook: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось создать подписку")
+			return
+		}
+	}
+
+	hook := models.Webhook{
+		URL:    u.String(),
+		Events: payload.Events,
+		Secret: payload.Secret,
+		UserID: userID,
+	}
+	if _, err := h.Store.CreateWebhook(r.Context(), &hook); err != nil {
+		log.Printf("Ошибка при создании подписки: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось создать подписку")
+		return
+	}
+	// Секрет подписи показывается один раз и не должен сохраняться ни в кешах, ни для Idempotency-Key
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusCreated, hook)
+}
+
+// GetWebhooks godoc
+// @Summary Получить подписки webhook
+// @Description Возвращает подписки пользователя без секретов
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.Webhook "Список подписок"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	hooks, err := h.Store.GetWebhooks(r.Context(), userID)
+	if err != nil {
+		log.Printf("Ошибка при получении подписок: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить подписки")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, hooks)
+}
+
+// DeleteWebhook godoc
+// @Summary Удалить подписку webhook
+// @Description Удаляет подписку и журнал ее доставок
+// @Tags webhooks
+// @Param webhookID path int true "ID подписки"
+// @Success 204 "Подписка удалена"
+// @Failure 400 {object} map[string]string "Неверный ID подписки"
+// @Failure 404 {object} map[string]string "Подписка не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /webhooks/{webhookID} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID подписки")
+		return
+	}
+	if err := h.Store.DeleteWebhook(r.Context(), id, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Подписка не найдена")
+		} else {
+			log.Printf("Ошибка при удалении подписки %d: %v", id, err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось удалить подписку")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary Журнал доставок webhook
+// @Description Возвращает последние 100 доставок подписки: тело запроса, код ответа, задержку и статус
+// @Tags webhooks
+// @Produce json
+// @Param webhookID path int true "ID подписки"
+// @Success 200 {array} models.WebhookDelivery "Список доставок"
+// @Failure 400 {object} map[string]string "Неверный ID подписки"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /webhooks/{webhookID}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID подписки")
+		return
+	}
+	deliveries, err := h.Store.GetDeliveries(r.Context(), id, userID)
+	if err != nil {
+		log.Printf("Ошибка при получении доставок подписки %d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить журнал доставок")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver godoc
+// @Summary Повторить доставку webhook
+// @Description Ставит в очередь новую доставку с тем же телом запроса
+// @Tags webhooks
+// @Produce json
+// @Param webhookID path int true "ID подписки"
+// @Param deliveryID path int true "ID доставки"
+// @Success 202 {object} models.WebhookDelivery "Доставка поставлена в очередь"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 404 {object} map[string]string "Доставка не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /webhooks/{webhookID}/deliveries/{deliveryID}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID подписки")
+		return
+	}
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Неверный формат ID доставки")
+		return
+	}
+	d, err := h.Store.Redeliver(r.Context(), deliveryID, webhookID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Доставка не найдена")
+		} else {
+			log.Printf("Ошибка при повторной доставке %d: %v", deliveryID, err)
+			respondWithError(w, http.StatusInternalServerError, "Не удалось повторить доставку")
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, d)
+}
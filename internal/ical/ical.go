@@ -0,0 +1,85 @@
+// Package ical формирует календари iCalendar (RFC 5545).
+package ical
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineOctets - максимальная длина строки без CRLF (RFC 5545, 3.1).
+const maxLineOctets = 75
+
+// Writer пишет календарь построчно: экранирует значения, сворачивает длинные строки
+// и завершает каждую строку CRLF. Первая ошибка записи сохраняется и возвращается из Err.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter создает Writer поверх w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Property пишет свойство с уже подготовленным значением, например "BEGIN:VCALENDAR".
+func (w *Writer) Property(name, value string) {
+	w.line(name + ":" + value)
+}
+
+// Text пишет текстовое свойство, экранируя значение.
+func (w *Writer) Text(name, value string) {
+	w.Property(name, EscapeText(value))
+}
+
+// Time пишет свойство с датой-временем в UTC, например DTSTART:20250601T180000Z.
+func (w *Writer) Time(name string, t time.Time) {
+	w.Property(name, FormatTime(t))
+}
+
+// Err возвращает первую ошибку записи.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) line(s string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = io.WriteString(w.w, Fold(s)+"\r\n")
+}
+
+// FormatTime форматирует время в UTC в виде 20250601T180000Z.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// EscapeText экранирует значение типа TEXT (RFC 5545, 3.3.11).
+func EscapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// Fold разбивает строку длиннее 75 октетов на строки продолжения, начинающиеся с пробела.
+// Многобайтовые символы UTF-8 не разрываются.
+func Fold(s string) string {
+	if len(s) <= maxLineOctets {
+		return s
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			// Пробел в начале строки продолжения тоже занимает октет
+			limit = maxLineOctets - 1
+			n = 0
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,344 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Частоты повторения RRULE.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxEmptyPeriods ограничивает перебор периодов, в которых нет ни одного повторения,
+// например FREQ=YEARLY для 29 февраля или BYMONTHDAY=31 в коротких месяцах.
+const maxEmptyPeriods = 1000
+
+// WeekdayNum - элемент BYDAY: день недели и, для MONTHLY, его номер в месяце
+// (1 - первый, -1 - последний, 0 - каждый).
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Rule - правило повторения RFC 5545 (раздел 3.3.10). Поддерживается подмножество:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY и BYMONTHDAY.
+// С FREQ=YEARLY BYDAY и BYMONTHDAY не поддерживаются. Неделя начинается с понедельника (WKST=MO).
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// ParseRule разбирает значение RRULE, например "FREQ=WEEKLY;BYDAY=MO,TH".
+// Префикс "RRULE:" допускается.
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("пустое правило повторения")
+	}
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("неверная часть правила %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("часть %s указана дважды", name)
+		}
+		seen[name] = true
+		switch name {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return nil, fmt.Errorf("частота %s не поддерживается", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL должен быть положительным числом")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT должен быть положительным числом")
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &t
+		case "BYDAY":
+			for _, v := range strings.Split(strings.ToUpper(value), ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("неверный день недели %q", v)
+				}
+				wd, ok := weekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("неверный день недели %q", v)
+				}
+				day := WeekdayNum{Weekday: wd}
+				if num := v[:len(v)-2]; num != "" {
+					n, err := strconv.Atoi(num)
+					if err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("неверный номер дня недели %q", v)
+					}
+					day.N = n
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("неверный день месяца %q", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return nil, fmt.Errorf("поддерживается только WKST=MO")
+			}
+		default:
+			return nil, fmt.Errorf("часть %s не поддерживается", name)
+		}
+	}
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("не указана частота FREQ")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT и UNTIL нельзя указывать вместе")
+	}
+	for _, d := range rule.ByDay {
+		if d.N != 0 && rule.Freq != Monthly {
+			return nil, fmt.Errorf("номер дня недели в BYDAY поддерживается только для FREQ=MONTHLY")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == Weekly {
+		return nil, fmt.Errorf("BYMONTHDAY нельзя использовать с FREQ=WEEKLY")
+	}
+	// Для YEARLY эти части по RFC 5545 размножают повторения по всему году (без BYMONTH -
+	// каждый подходящий день года), а не выбирают годовщину DTSTART
+	if (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0) && rule.Freq == Yearly {
+		return nil, fmt.Errorf("BYDAY и BYMONTHDAY не поддерживаются для FREQ=YEARLY")
+	}
+	return rule, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", v); err == nil {
+		// Дата без времени включает весь день
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL должен быть в формате 20060102 или 20060102T150405Z")
+}
+
+// String возвращает правило в каноническом виде.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+FormatTime(*r.Until))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences возвращает до n повторений серии, начатой в start (DTSTART), строго позже after.
+// Как в RFC 5545, start всегда считается первым повторением, даже если не подходит
+// под BYDAY или BYMONTHDAY, и COUNT отсчитывается от него.
+func (r *Rule) Occurrences(start, after time.Time, n int) []time.Time {
+	var result []time.Time
+	if n <= 0 || (r.Until != nil && start.After(*r.Until)) {
+		return result
+	}
+	if start.After(after) {
+		result = append(result, start)
+		if len(result) == n {
+			return result
+		}
+	}
+	count := 1
+	empty := 0
+	for period := 0; len(result) < n; period++ {
+		candidates := r.period(start, period)
+		if len(candidates) == 0 {
+			if empty++; empty > maxEmptyPeriods {
+				break
+			}
+			continue
+		}
+		empty = 0
+		for _, t := range candidates {
+			if !t.After(start) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return result
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return result
+			}
+			if t.After(after) {
+				result = append(result, t)
+				if len(result) == n {
+					return result
+				}
+			}
+		}
+	}
+	return result
+}
+
+// period возвращает отсортированные повторения в k-м периоде серии.
+func (r *Rule) period(start time.Time, k int) []time.Time {
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, start.Nanosecond(), loc)
+	}
+	step := k * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+step)
+		if r.matchesWeekday(t) && r.matchesMonthDay(t) {
+			days = append(days, t)
+		}
+	case Weekly:
+		// Понедельник недели, в которую попадает start
+		monday := at(y, m, d-(int(start.Weekday())+6)%7+7*step)
+		if len(r.ByDay) == 0 {
+			days = append(days, at(y, m, d+7*step))
+			break
+		}
+		for i := 0; i < 7; i++ {
+			t := monday.AddDate(0, 0, i)
+			if r.matchesWeekday(t) {
+				days = append(days, t)
+			}
+		}
+	case Monthly:
+		first := at(y, m+time.Month(step), 1)
+		switch {
+		case len(r.ByMonthDay) > 0 || len(r.ByDay) > 0:
+			days = r.daysInMonth(first)
+		default:
+			if t := at(first.Year(), first.Month(), d); t.Month() == first.Month() {
+				days = append(days, t)
+			}
+		}
+	case Yearly:
+		if t := at(y+step, m, d); t.Month() == m {
+			days = append(days, t)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// daysInMonth возвращает дни месяца, подходящие под BYMONTHDAY и BYDAY.
+func (r *Rule) daysInMonth(first time.Time) []time.Time {
+	var days []time.Time
+	for t := first; t.Month() == first.Month(); t = t.AddDate(0, 0, 1) {
+		if r.matchesMonthDay(t) && r.matchesMonthWeekday(t) {
+			days = append(days, t)
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthWeekday учитывает номер дня недели в месяце, например 2TU или -1FR.
+func (r *Rule) matchesMonthWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, d := range r.ByDay {
+		if d.Weekday != t.Weekday() {
+			continue
+		}
+		switch {
+		case d.N == 0:
+			return true
+		case d.N > 0 && (t.Day()-1)/7+1 == d.N:
+			return true
+		case d.N < 0 && (last-t.Day())/7+1 == -d.N:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, d := range r.ByMonthDay {
+		if d == t.Day() || (d < 0 && last+d+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParseRuleCanonical(t *testing.T) {
+	cases := map[string]string{
+		"FREQ=DAILY":                           "FREQ=DAILY",
+		"RRULE:freq=weekly;byday=mo,th":        "FREQ=WEEKLY;BYDAY=MO,TH",
+		"FREQ=MONTHLY;BYDAY=-1FR;INTERVAL=2":   "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=5": "FREQ=MONTHLY;COUNT=5;BYMONTHDAY=1,-1",
+		"FREQ=YEARLY;UNTIL=20301231":           "FREQ=YEARLY;UNTIL=20301231T235959Z",
+		"FREQ=WEEKLY;INTERVAL=1;WKST=MO":       "FREQ=WEEKLY",
+		"FREQ=DAILY;UNTIL=20260102T030405Z":    "FREQ=DAILY;UNTIL=20260102T030405Z",
+	}
+	for in, want := range cases {
+		rule, err := ParseRule(in)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", in, err)
+			continue
+		}
+		if got := rule.String(); got != want {
+			t.Errorf("ParseRule(%q) = %s, ожидалось %s", in, got, want)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20300101",
+		"FREQ=DAILY;UNTIL=2030-01-01",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=YEARLY;BYMONTHDAY=1",
+		"FREQ=DAILY;WKST=SU",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;COUNT",
+	} {
+		if _, err := ParseRule(in); err == nil {
+			t.Errorf("ParseRule(%q) принял неверное правило", in)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	cases := []struct {
+		rule  string
+		start time.Time
+		after time.Time
+		n     int
+		want  []time.Time
+	}{
+		{
+			rule: "FREQ=DAILY;INTERVAL=2", start: date(2026, 1, 1), after: date(2026, 1, 1), n: 3,
+			want: []time.Time{date(2026, 1, 3), date(2026, 1, 5), date(2026, 1, 7)},
+		},
+		{
+			// 2 марта 2026 - понедельник
+			rule: "FREQ=WEEKLY;BYDAY=MO,TH", start: date(2026, 3, 2), after: date(2026, 3, 2), n: 3,
+			want: []time.Time{date(2026, 3, 5), date(2026, 3, 9), date(2026, 3, 12)},
+		},
+		{
+			rule: "FREQ=WEEKLY;INTERVAL=2", start: date(2026, 3, 4), after: date(2026, 3, 4), n: 2,
+			want: []time.Time{date(2026, 3, 18), date(2026, 4, 1)},
+		},
+		{
+			rule: "FREQ=MONTHLY;BYDAY=-1FR", start: date(2026, 1, 30), after: date(2026, 1, 30), n: 3,
+			want: []time.Time{date(2026, 2, 27), date(2026, 3, 27), date(2026, 4, 24)},
+		},
+		{
+			rule: "FREQ=MONTHLY;BYDAY=2TU", start: date(2026, 1, 13), after: date(2026, 1, 13), n: 2,
+			want: []time.Time{date(2026, 2, 10), date(2026, 3, 10)},
+		},
+		{
+			// 31-го числа: месяцы без 31 дня пропускаются
+			rule: "FREQ=MONTHLY", start: date(2026, 1, 31), after: date(2026, 1, 31), n: 3,
+			want: []time.Time{date(2026, 3, 31), date(2026, 5, 31), date(2026, 7, 31)},
+		},
+		{
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1", start: date(2026, 1, 31), after: date(2026, 1, 31), n: 3,
+			want: []time.Time{date(2026, 2, 28), date(2026, 3, 31), date(2026, 4, 30)},
+		},
+		{
+			// 29 февраля повторяется только в високосные годы
+			rule: "FREQ=YEARLY", start: date(2024, 2, 29), after: date(2024, 2, 29), n: 2,
+			want: []time.Time{date(2028, 2, 29), date(2032, 2, 29)},
+		},
+		{
+			rule: "FREQ=DAILY;COUNT=3", start: date(2026, 1, 1), after: date(2026, 1, 1), n: 10,
+			want: []time.Time{date(2026, 1, 2), date(2026, 1, 3)},
+		},
+		{
+			rule: "FREQ=DAILY;UNTIL=20260103", start: date(2026, 1, 1), after: date(2026, 1, 1), n: 10,
+			want: []time.Time{date(2026, 1, 2), date(2026, 1, 3)},
+		},
+		{
+			// after позже start: возвращаются только повторения после after
+			rule: "FREQ=DAILY", start: date(2026, 1, 1), after: date(2026, 6, 1), n: 1,
+			want: []time.Time{date(2026, 6, 2)},
+		},
+		{
+			// after раньше start: start - первое повторение
+			rule: "FREQ=WEEKLY", start: date(2026, 1, 1), after: date(2025, 1, 1), n: 2,
+			want: []time.Time{date(2026, 1, 1), date(2026, 1, 8)},
+		},
+	}
+	for _, c := range cases {
+		rule, err := ParseRule(c.rule)
+		if err != nil {
+			t.Fatalf("ParseRule(%q): %v", c.rule, err)
+		}
+		got := rule.Occurrences(c.start, c.after, c.n)
+		if !equalDates(got, c.want) {
+			t.Errorf("%s с %s после %s: %s, ожидалось %s", c.rule, c.start.Format(time.DateOnly),
+				c.after.Format(time.DateOnly), formatDates(got), formatDates(c.want))
+		}
+	}
+}
+
+// DTSTART, не подходящий под BYDAY, все равно первое повторение и учитывается в COUNT (RFC 5545, 3.8.5.3).
+func TestOccurrencesCountsNonMatchingStart(t *testing.T) {
+	rule, err := ParseRule("FREQ=WEEKLY;BYDAY=MO;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date(2026, 3, 4) // среда
+	got := rule.Occurrences(start, start.Add(-time.Second), 10)
+	want := []time.Time{start, date(2026, 3, 9), date(2026, 3, 16)}
+	if !equalDates(got, want) {
+		t.Errorf("получено %s, ожидалось %s", formatDates(got), formatDates(want))
+	}
+}
+
+func TestOccurrencesKeepsTimeOfDay(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	start := time.Date(2026, 1, 1, 18, 30, 0, 0, loc)
+	rule, err := ParseRule("FREQ=MONTHLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rule.Occurrences(start, start, 1)
+	if len(got) != 1 || !got[0].Equal(time.Date(2026, 2, 1, 18, 30, 0, 0, loc)) {
+		t.Errorf("получено %s", formatDates(got))
+	}
+}
+
+func equalDates(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatDates(list []time.Time) string {
+	s := make([]string, len(list))
+	for i, t := range list {
+		s[i] = t.Format(time.DateOnly)
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// accountSubjects - темы писем со ссылками по назначению токена.
+var accountSubjects = map[string]string{
+	models.AccountTokenVerifyEmail:   "Подтвердите адрес почты",
+	models.AccountTokenResetPassword: "Сброс пароля",
+}
+
+type accountData struct {
+	Subject   string
+	Username  string
+	Email     string
+	URL       string
+	ExpiresIn string
+}
+
+// AccountMail строит письмо со ссылкой для подтверждения адреса или сброса пароля.
+// Такие письма служебные, поэтому в них нет ссылок для отписки.
+func AccountMail(purpose string, user *models.User, to string, link string, ttl time.Duration) (models.OutboxMail, error) {
+	subject, ok := accountSubjects[purpose]
+	if !ok {
+		return models.OutboxMail{}, fmt.Errorf("неизвестное назначение письма %q", purpose)
+	}
+	data := accountData{
+		Subject:   subject,
+		Username:  user.Username,
+		Email:     to,
+		URL:       link,
+		ExpiresIn: formatDuration(ttl),
+	}
+	text, html, err := render(purpose, data)
+	if err != nil {
+		return models.OutboxMail{}, fmt.Errorf("ошибка шаблона письма %s: %w", purpose, err)
+	}
+	return models.OutboxMail{UserID: user.ID, To: to, Subject: subject, Text: text, HTML: html}, nil
+}
+
+// AccountPage - страница, которая открывается по ссылке из письма. Если Action не пуст,
+// на странице есть форма с кнопкой Button, а при Password - и с полем нового пароля.
+type AccountPage struct {
+	Title    string
+	Message  string
+	Action   string
+	Button   string
+	Password bool
+}
+
+// RenderAccountPage выводит страницу подтверждения адреса или сброса пароля.
+func RenderAccountPage(w io.Writer, page AccountPage) error {
+	return htmlTemplates.ExecuteTemplate(w, "account_page.html", page)
+}
+
+// formatDuration записывает срок действия ссылки в часах или минутах.
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		n := int(d / time.Hour)
+		return fmt.Sprintf("%d %s", n, plural(n, "час", "часа", "часов"))
+	}
+	n := int(d.Round(time.Minute) / time.Minute)
+	return fmt.Sprintf("%d %s", n, plural(n, "минуту", "минуты", "минут"))
+}
+
+func plural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	}
+	return many
+}
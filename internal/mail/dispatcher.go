@@ -0,0 +1,126 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// recipientBatchSize - сколько получателей обрабатывается за один запуск.
+const recipientBatchSize = 100
+
+// Dispatcher превращает уведомления во входящих в письма в очереди mail_outbox.
+type Dispatcher struct {
+	Store storage.MailStore
+	// Secret подписывает ссылки для отписки
+	Secret []byte
+	// BaseURL - внешний адрес API, на который ведут ссылки для отписки
+	BaseURL string
+}
+
+// NewDispatcher создает новый экземпляр Dispatcher.
+func NewDispatcher(store storage.MailStore, secret []byte, baseURL string) *Dispatcher {
+	return &Dispatcher{Store: store, Secret: secret, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// RunInstant ставит в очередь письма по уведомлениям с режимом instant, по письму на уведомление.
+// Возвращает количество писем.
+func (d *Dispatcher) RunInstant(ctx context.Context) (int64, error) {
+	return d.Store.QueueNotificationMail(ctx, models.MailInstant, time.Now(), recipientBatchSize, d.buildInstant)
+}
+
+// RunDigests ставит в очередь сводки по уведомлениям с режимом daily, созданным до начала
+// текущих суток UTC, поэтому сводка за день уходит первым запуском после полуночи.
+// Уведомления, уже прочитанные в приложении, в сводку не попадают.
+func (d *Dispatcher) RunDigests(ctx context.Context) (int64, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return d.Store.QueueNotificationMail(ctx, models.MailDaily, today, recipientBatchSize, d.buildDigest)
+}
+
+func (d *Dispatcher) buildInstant(r models.MailRecipient, notifications []models.Notification) ([]models.OutboxMail, error) {
+	mails := make([]models.OutboxMail, 0, len(notifications))
+	for _, n := range notifications {
+		mail, err := d.build("notification", r, subject(n), n.Type, []models.Notification{n})
+		if err != nil {
+			return nil, err
+		}
+		mails = append(mails, mail)
+	}
+	return mails, nil
+}
+
+func (d *Dispatcher) buildDigest(r models.MailRecipient, notifications []models.Notification) ([]models.OutboxMail, error) {
+	var unread []models.Notification
+	for _, n := range notifications {
+		if n.ReadAt == nil {
+			unread = append(unread, n)
+		}
+	}
+	if len(unread) == 0 {
+		return nil, nil
+	}
+	mail, err := d.build("digest", r, fmt.Sprintf("Сводка уведомлений: %d", len(unread)), "", unread)
+	if err != nil {
+		return nil, err
+	}
+	return []models.OutboxMail{mail}, nil
+}
+
+// build рендерит письмо. scope - тип уведомлений для ссылки «не присылать такие»,
+// пустой для сводки, где есть только отписка от всех писем.
+func (d *Dispatcher) build(name string, r models.MailRecipient, subject string, scope string, notifications []models.Notification) (models.OutboxMail, error) {
+	data := templateData{
+		Subject:           subject,
+		Username:          r.Username,
+		UnsubscribeAllURL: d.unsubscribeURL(r.UserID, UnsubscribeAll),
+	}
+	if scope != "" {
+		data.UnsubscribeURL = d.unsubscribeURL(r.UserID, scope)
+	}
+	for _, n := range notifications {
+		data.Items = append(data.Items, newTemplateItem(n))
+	}
+	text, html, err := render(name, data)
+	if err != nil {
+		return models.OutboxMail{}, fmt.Errorf("ошибка шаблона письма %s: %w", name, err)
+	}
+	unsubscribe := data.UnsubscribeAllURL
+	if data.UnsubscribeURL != "" {
+		unsubscribe = data.UnsubscribeURL
+	}
+	return models.OutboxMail{
+		UserID:  r.UserID,
+		To:      r.Email,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		// RFC 8058: почтовые клиенты показывают кнопку отписки и отправляют POST без участия пользователя
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribe + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+func (d *Dispatcher) unsubscribeURL(userID int, scope string) string {
+	return d.BaseURL + "/api/v1/unsubscribe?token=" + url.QueryEscape(SignUnsubscribe(d.Secret, userID, scope))
+}
+
+func subject(n models.Notification) string {
+	switch n.Type {
+	case models.NotificationMention:
+		return fmt.Sprintf("%s упоминает вас в задаче «%s»", n.ActorUsername, n.TaskTitle)
+	case models.NotificationDueSoon:
+		return fmt.Sprintf("Скоро срок задачи «%s»", n.TaskTitle)
+	case models.NotificationTaskUpdated:
+		return fmt.Sprintf("Изменена задача «%s»", n.TaskTitle)
+	case models.NotificationTasksUpdated:
+		return "Изменения в отслеживаемых задачах"
+	}
+	return "Новое уведомление"
+}
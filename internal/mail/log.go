@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// LogMailer - Mailer для разработки: вместо отправки пишет письма в лог
+// или, если указан Dir, сохраняет их файлами .eml, которые открывает любой почтовый клиент.
+type LogMailer struct {
+	Dir  string
+	From string
+
+	seq atomic.Int64
+}
+
+// NewLogMailer создает новый экземпляр LogMailer.
+func NewLogMailer(dir, from string) *LogMailer {
+	return &LogMailer{Dir: dir, From: from}
+}
+
+// Send записывает письмо в лог или в файл.
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	if m.Dir == "" {
+		log.Printf("Письмо для %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+		return nil
+	}
+	data, err := compose(m.From, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога писем: %w", err)
+	}
+	name := fmt.Sprintf("%d-%d.eml", time.Now().UnixNano(), m.seq.Add(1))
+	if err := os.WriteFile(filepath.Join(m.Dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи письма: %w", err)
+	}
+	return nil
+}
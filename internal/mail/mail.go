@@ -0,0 +1,111 @@
+// Package mail отправляет уведомления и служебные письма: строит письма из шаблонов,
+// ставит их в очередь mail_outbox и отправляет через Mailer с повторными попытками.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message - письмо для отправки. HTML может быть пустым.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string
+}
+
+// Mailer отправляет письма.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ErrPermanent оборачивает ошибки, после которых повторять отправку бессмысленно,
+// например отказ сервера принять адрес получателя.
+var ErrPermanent = errors.New("постоянная ошибка отправки")
+
+// compose собирает письмо в формате RFC 5322 с частями text/plain и text/html.
+func compose(from string, msg Message, date time.Time) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("неверный адрес отправителя %q: %w", from, err)
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	header := map[string]string{
+		"From":         sender.String(),
+		"To":           msg.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date":         date.Format(time.RFC1123Z),
+		"Message-ID":   messageID(sender.Address),
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + w.Boundary(),
+	}
+	for k, v := range msg.Headers {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var head bytes.Buffer
+	for _, k := range keys {
+		// Значения заголовков могут содержать данные пользователя, переводы строк недопустимы
+		v := strings.NewReplacer("\r", " ", "\n", " ").Replace(header[k])
+		fmt.Fprintf(&head, "%s: %s\r\n", k, v)
+	}
+	head.WriteString("\r\n")
+
+	if err := writePart(w, "text/plain; charset=utf-8", msg.Text); err != nil {
+		return nil, err
+	}
+	if msg.HTML != "" {
+		if err := writePart(w, "text/html; charset=utf-8", msg.HTML); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+func writePart(w *multipart.Writer, contentType string, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// SMTPMailer отправляет письма через SMTP-сервер. STARTTLS используется, если сервер
+// его поддерживает; без Username письма отправляются без аутентификации, как в локальной
+// песочнице вроде MailHog или smtp4dev.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// NewSMTPMailer создает новый экземпляр SMTPMailer.
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	return &SMTPMailer{Addr: addr, From: from, Username: username, Password: password, Timeout: 30 * time.Second}
+}
+
+// Send отправляет письмо. Отказы сервера с кодом 5xx возвращаются как ErrPermanent.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := compose(m.From, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	sender, _ := mail.ParseAddress(m.From)
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return fmt.Errorf("неверный адрес SMTP-сервера %q: %w", m.Addr, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к SMTP-серверу: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("ошибка подключения к SMTP-серверу: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("ошибка STARTTLS: %w", err)
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return smtpError("ошибка аутентификации SMTP", err)
+		}
+	}
+	if err := c.Mail(sender.Address); err != nil {
+		return smtpError("сервер отклонил отправителя", err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return smtpError("сервер отклонил получателя", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return smtpError("ошибка передачи письма", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("ошибка передачи письма: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("сервер отклонил письмо", err)
+	}
+	return c.Quit()
+}
+
+// smtpError помечает ответы 5xx как постоянные ошибки.
+func smtpError(msg string, err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return fmt.Errorf("%w: %s: %v", ErrPermanent, msg, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
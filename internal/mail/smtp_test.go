@@ -0,0 +1,210 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpSink - минимальный SMTP-сервер, который принимает письма в память.
+// rcptReply задает ответ на RCPT TO, чтобы проверить отказы сервера.
+type smtpSink struct {
+	ln        net.Listener
+	rcptReply string
+
+	mu       sync.Mutex
+	from     string
+	rcpt     []string
+	messages []string
+}
+
+func newSMTPSink(t *testing.T, rcptReply string) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, rcptReply: rcptReply}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 sink ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-sink")
+			reply("250 8BITMIME")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = cmd
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, cmd)
+			s.mu.Unlock()
+			reply(s.rcptReply)
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	sink := newSMTPSink(t, "250 OK")
+	m := NewSMTPMailer(sink.ln.Addr().String(), "Kanban <noreply@example.com>", "", "")
+	msg := Message{
+		To:      "user@example.com",
+		Subject: "Новая задача",
+		Text:    "Текст письма",
+		HTML:    "<p>Текст письма</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	// Сервер объявил 8BITMIME, и net/smtp указывает его в MAIL FROM
+	if sink.from != "MAIL FROM:<noreply@example.com> BODY=8BITMIME" {
+		t.Errorf("отправитель %q", sink.from)
+	}
+	if len(sink.rcpt) != 1 || sink.rcpt[0] != "RCPT TO:<user@example.com>" {
+		t.Errorf("получатели %q", sink.rcpt)
+	}
+	if len(sink.messages) != 1 {
+		t.Fatalf("получено %d писем", len(sink.messages))
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(sink.messages[0]))
+	if err != nil {
+		t.Fatalf("письмо не разбирается: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("тема %q, ожидалась %q (%v)", subject, msg.Subject, err)
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<https://example.com/u>" {
+		t.Errorf("заголовок List-Unsubscribe %q", got)
+	}
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		p, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(quotedprintable.NewReader(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, p.Header.Get("Content-Type")+": "+string(b))
+	}
+	want := []string{"text/plain; charset=utf-8: " + msg.Text, "text/html; charset=utf-8: " + msg.HTML}
+	if strings.Join(bodies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("части письма %q, ожидались %q", bodies, want)
+	}
+}
+
+func TestSMTPMailerRejectedRecipient(t *testing.T) {
+	cases := []struct {
+		reply     string
+		permanent bool
+	}{
+		{"550 5.1.1 No such user", true},
+		{"451 4.3.0 Try again later", false},
+	}
+	for _, c := range cases {
+		sink := newSMTPSink(t, c.reply)
+		m := NewSMTPMailer(sink.ln.Addr().String(), "noreply@example.com", "", "")
+		err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "s", Text: "t"})
+		if err == nil {
+			t.Fatalf("ответ %q: письмо считается отправленным", c.reply)
+		}
+		if got := errors.Is(err, ErrPermanent); got != c.permanent {
+			t.Errorf("ответ %q: постоянная ошибка %v, ожидалось %v (%v)", c.reply, got, c.permanent, err)
+		}
+		sink.mu.Lock()
+		if len(sink.messages) != 0 {
+			t.Errorf("ответ %q: письмо передано после отказа", c.reply)
+		}
+		sink.mu.Unlock()
+	}
+}
+
+func TestComposeStripsHeaderLineBreaks(t *testing.T) {
+	data, err := compose("noreply@example.com", Message{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "s",
+		Text:    "t",
+	}, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcc := parsed.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("в письмо попал заголовок Bcc: %q", bcc)
+	}
+}
@@ -0,0 +1,110 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+
+	"kanban-backend/internal/models"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+)
+
+// timeLayout - формат времени в письмах. Часовой пояс пользователя неизвестен, поэтому UTC.
+const timeLayout = "02.01.2006 15:04 UTC"
+
+// labels - подписи типов уведомлений в письмах.
+var labels = map[string]string{
+	models.NotificationMention:      "Упоминание",
+	models.NotificationDueSoon:      "Скоро срок",
+	models.NotificationTaskUpdated:  "Изменение задачи",
+	models.NotificationTasksUpdated: "Изменения задач",
+}
+
+// scopeDescriptions описывают, от чего отписывается пользователь.
+var scopeDescriptions = map[string]string{
+	UnsubscribeAll:                  "письма с уведомлениями",
+	models.NotificationMention:      "упоминания",
+	models.NotificationDueSoon:      "напоминания о сроках",
+	models.NotificationTaskUpdated:  "изменения отслеживаемых задач",
+	models.NotificationTasksUpdated: "сводки изменений отслеживаемых задач",
+}
+
+type templateItem struct {
+	Label     string
+	TaskTitle string
+	Actor     string
+	Text      string
+	DueAt     string
+	CreatedAt string
+}
+
+type templateData struct {
+	Subject           string
+	Username          string
+	Items             []templateItem
+	UnsubscribeURL    string
+	UnsubscribeAllURL string
+}
+
+func newTemplateItem(n models.Notification) templateItem {
+	item := templateItem{
+		Label:     labels[n.Type],
+		TaskTitle: n.TaskTitle,
+		Actor:     n.ActorUsername,
+		Text:      n.Text,
+		CreatedAt: n.CreatedAt.UTC().Format(timeLayout),
+	}
+	if n.DueAt != nil {
+		item.DueAt = n.DueAt.UTC().Format(timeLayout)
+	}
+	return item
+}
+
+// render строит текстовую и HTML-версии письма по шаблону name ("notification", "digest",
+// "verify_email" или "reset_password").
+func render(name string, data any) (text string, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&textBuf, name+".txt", data); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&htmlBuf, name+".html", data); err != nil {
+		return "", "", err
+	}
+	return textBuf.String(), htmlBuf.String(), nil
+}
+
+// UnsubscribePage - данные страницы отписки.
+type UnsubscribePage struct {
+	// Done - отписка выполнена; иначе страница предлагает подтвердить ее
+	Done   bool
+	Scope  string
+	Action string
+}
+
+// Description описывает уведомления, от которых отписывается пользователь.
+func (p UnsubscribePage) Description() string {
+	if d, ok := scopeDescriptions[p.Scope]; ok {
+		return d
+	}
+	return scopeDescriptions[UnsubscribeAll]
+}
+
+// RenderUnsubscribePage выводит страницу отписки.
+func RenderUnsubscribePage(w io.Writer, page UnsubscribePage) error {
+	return htmlTemplates.ExecuteTemplate(w, "unsubscribe.html", page)
+}
+
+// IsUnsubscribeScope сообщает, можно ли отписаться от scope.
+func IsUnsubscribeScope(scope string) bool {
+	_, ok := scopeDescriptions[scope]
+	return ok
+}
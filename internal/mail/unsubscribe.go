@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// UnsubscribeAll - область отписки от всех типов уведомлений.
+const UnsubscribeAll = "all"
+
+var errInvalidToken = errors.New("неверная ссылка для отписки")
+
+// SignUnsubscribe возвращает токен ссылки для отписки пользователя от уведомлений типа scope
+// (или от всех, если scope - UnsubscribeAll). Токен подписан HMAC-SHA256 и не истекает,
+// чтобы ссылки в старых письмах продолжали работать.
+func SignUnsubscribe(secret []byte, userID int, scope string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(userID) + ":" + scope))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(unsubscribeMAC(secret, payload))
+}
+
+// VerifyUnsubscribe проверяет подпись токена и возвращает пользователя и область отписки.
+func VerifyUnsubscribe(secret []byte, token string) (userID int, scope string, err error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", errInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, unsubscribeMAC(secret, payload)) {
+		return 0, "", errInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, "", errInvalidToken
+	}
+	id, scope, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, "", errInvalidToken
+	}
+	userID, err = strconv.Atoi(id)
+	if err != nil {
+		return 0, "", errInvalidToken
+	}
+	return userID, scope, nil
+}
+
+func unsubscribeMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("unsubscribe:" + payload))
+	return mac.Sum(nil)
+}
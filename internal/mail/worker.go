@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	batchSize    = 20
+	// claimLease - на сколько письмо скрывается от других воркеров, пока идет отправка.
+	claimLease  = 2 * time.Minute
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 2 * time.Hour
+)
+
+// Worker отправляет письма из очереди mail_outbox, как webhook.Worker отправляет доставки.
+type Worker struct {
+	Store       storage.MailStore
+	Mailer      Mailer
+	MaxAttempts int
+}
+
+// NewWorker создает новый экземпляр Worker. После maxAttempts неудачных попыток
+// или постоянной ошибки письмо помечается как dead и больше не отправляется.
+func NewWorker(store storage.MailStore, mailer Mailer, maxAttempts int) *Worker {
+	return &Worker{Store: store, Mailer: mailer, MaxAttempts: maxAttempts}
+}
+
+// Run обрабатывает очередь до отмены ctx.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.processBatch(ctx)
+		}
+	}
+}
+
+func (w *Worker) processBatch(ctx context.Context) {
+	mails, err := w.Store.ClaimMail(ctx, batchSize, claimLease)
+	if err != nil {
+		log.Printf("Ошибка получения писем из очереди: %v", err)
+		return
+	}
+	for i := range mails {
+		w.send(ctx, &mails[i])
+		if err := w.Store.RecordMailAttempt(ctx, &mails[i]); err != nil {
+			log.Printf("Ошибка сохранения результата отправки письма: %v", err)
+		}
+	}
+}
+
+// send выполняет одну попытку отправки и заполняет ее результат в m.
+func (w *Worker) send(ctx context.Context, m *models.OutboxMail) {
+	m.Attempts++
+	m.LastError = ""
+	err := w.Mailer.Send(ctx, Message{To: m.To, Subject: m.Subject, Text: m.Text, HTML: m.HTML, Headers: m.Headers})
+	if err == nil {
+		now := time.Now()
+		m.Status = models.MailSent
+		m.SentAt = &now
+		return
+	}
+	m.LastError = err.Error()
+	if errors.Is(err, ErrPermanent) || m.Attempts >= w.MaxAttempts {
+		m.Status = models.MailDead
+		log.Printf("Письмо %d отброшено после %d попыток: %v", m.ID, m.Attempts, err)
+		return
+	}
+	m.NextAttemptAt = time.Now().Add(backoff(m.Attempts))
+}
+
+// backoff возвращает паузу перед следующей попыткой: 30s, 1m, 2m... но не больше двух часов.
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id, id);
+CREATE INDEX IF NOT EXISTS idx_task_events_user_id ON task_events(user_id, id);
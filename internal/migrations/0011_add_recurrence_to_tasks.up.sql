@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS rrule TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_start TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence_created BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_tasks_recurring ON tasks(due_at) WHERE rrule <> '' AND NOT occurrence_created;
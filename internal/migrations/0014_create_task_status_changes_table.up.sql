@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS task_status_changes (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_task_status_changes_task_id ON task_status_changes(task_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_task_status_changes_user_id ON task_status_changes(user_id, changed_at);
+
+-- История статусов до появления таблицы восстанавливается из журнала изменений
+INSERT INTO task_status_changes (task_id, user_id, from_status, to_status, changed_at)
+SELECT e.task_id, e.user_id, e.before->>'status', e.after->>'status', e.created_at
+FROM task_events e JOIN tasks t ON t.id = e.task_id
+WHERE e.after ? 'status' AND NOT EXISTS (SELECT 1 FROM task_status_changes)
+ORDER BY e.id;
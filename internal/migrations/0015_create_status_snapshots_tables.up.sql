@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS status_snapshot_days (
+    day DATE PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS task_status_snapshots (
+    day DATE NOT NULL REFERENCES status_snapshot_days(day) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (user_id, day, status)
+);
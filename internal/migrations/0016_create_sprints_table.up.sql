@@ -0,0 +1,16 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate DOUBLE PRECISION;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sprint_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_tasks_sprint_id ON tasks(sprint_id) WHERE sprint_id IS NOT NULL;
+CREATE TABLE IF NOT EXISTS sprints (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    goal TEXT NOT NULL DEFAULT '',
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    stats JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (start_date <= end_date)
+);
+CREATE INDEX IF NOT EXISTS idx_sprints_user_id ON sprints(user_id, start_date);
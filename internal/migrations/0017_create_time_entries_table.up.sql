@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS time_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+-- Не больше одного запущенного таймера на пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_time_entries_user_id ON time_entries(user_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id, started_at);
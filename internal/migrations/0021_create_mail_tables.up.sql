@@ -0,0 +1,28 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(254);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(lower(email));
+-- Уведомления, созданные до появления почты, не рассылаются
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS mailed BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE notifications ALTER COLUMN mailed SET DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_notifications_unmailed ON notifications(user_id) WHERE NOT mailed;
+CREATE TABLE IF NOT EXISTS notification_mail_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    mode VARCHAR(10) NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
+CREATE TABLE IF NOT EXISTS mail_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    to_address VARCHAR(254) NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL DEFAULT '',
+    headers JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_pending ON mail_outbox(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+-- История статусов переживает окончательное удаление задачи из корзины: на ней построены метрики за прошлые периоды
+ALTER TABLE task_status_changes DROP CONSTRAINT IF EXISTS task_status_changes_task_id_fkey;
@@ -0,0 +1,5 @@
+-- Учтенное время остается в табелях после окончательного удаления задачи из корзины,
+-- поэтому название задачи копируется в запись
+ALTER TABLE time_entries DROP CONSTRAINT IF EXISTS time_entries_task_id_fkey;
+ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS task_title VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE time_entries e SET task_title = t.title FROM tasks t WHERE t.id = e.task_id AND e.task_title = '';
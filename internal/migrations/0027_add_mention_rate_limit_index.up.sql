@@ -0,0 +1,2 @@
+-- Ограничение частоты упоминаний считает уведомления mention по автору
+CREATE INDEX IF NOT EXISTS idx_notifications_mention_actor ON notifications(actor_id, created_at) WHERE type = 'mention';
@@ -0,0 +1,3 @@
+-- Уникален только подтвержденный адрес, неподтвержденный адрес не занимает его у других
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_email ON users(lower(email)) WHERE email_verified_at IS NOT NULL;
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Назначения одноразовых токенов из писем.
+const (
+	AccountTokenVerifyEmail   = "verify_email"
+	AccountTokenResetPassword = "reset_password"
+)
+
+// AccountToken - одноразовый токен подтверждения адреса или сброса пароля.
+// В базе хранится только хеш токена; сам токен есть лишь в ссылке из письма.
+type AccountToken struct {
+	Hash    string
+	UserID  int
+	Purpose string
+	// Email - адрес, на который отправлена ссылка; токен недействителен, если адрес сменился
+	Email     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// StreamTicket - одноразовый билет для подключения к /ws или /events без заголовка Authorization.
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordForgotPayload - запрос ссылки для сброса пароля.
+type PasswordForgotPayload struct {
+	Email string `json:"email"`
+}
+
+// PasswordResetPayload - установка нового пароля по токену из письма.
+type PasswordResetPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// EmailVerifyPayload - подтверждение адреса по токену из письма.
+type EmailVerifyPayload struct {
+	Token string `json:"token"`
+}
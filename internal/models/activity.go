@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TaskActivity - запись журнала изменений задачи.
+// swagger:model TaskActivity
+type TaskActivity struct {
+	// Уникальный идентификатор записи
+	// example: 10
+	ID int64 `json:"id"`
+
+	// ID задачи (задача может быть уже удалена)
+	// example: 5
+	TaskID int `json:"task_id"`
+
+	// ID пользователя, выполнившего изменение
+	// example: 42
+	ActorID int `json:"actor_id"`
+
+	// Тип изменения, совпадает с типом события
+	// example: task.created
+	Type string `json:"type"`
+
+	// Значения измененных полей до изменения
+	Before json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+
+	// Значения измененных полей после изменения
+	After json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ActivityPage - страница журнала изменений.
+// swagger:model ActivityPage
+type ActivityPage struct {
+	Items []TaskActivity `json:"items"`
+
+	// Значение параметра before для следующей страницы; отсутствует на последней странице
+	// example: 9
+	NextBefore *int64 `json:"next_before,omitempty"`
+}
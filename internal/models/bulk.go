@@ -0,0 +1,77 @@
+package models
+
+// Операции массового изменения задач.
+const (
+	BulkOpUpdate    = "update"
+	BulkOpArchive   = "archive"
+	BulkOpUnarchive = "unarchive"
+	BulkOpDelete    = "delete"
+	BulkOpRestore   = "restore"
+)
+
+// Статусы результата массовой операции над одной задачей.
+const (
+	BulkItemOK      = "ok"
+	BulkItemError   = "error"
+	BulkItemSkipped = "skipped"
+)
+
+// BulkTaskFields - поля задачи для операции update; поля без значения не меняются.
+// swagger:model BulkTaskFields
+type BulkTaskFields struct {
+	// example: Новое название
+	Title *string `json:"title,omitempty"`
+	// example: Новое описание
+	Description *string `json:"description,omitempty"`
+	// example: completed
+	Status *string `json:"status,omitempty"`
+}
+
+// BulkOperation - одна операция над несколькими задачами.
+// swagger:model BulkOperation
+type BulkOperation struct {
+	// Операция: update, archive, unarchive, delete, restore
+	// required: true
+	// example: archive
+	Op string `json:"op"`
+
+	// ID задач, к которым применяется операция
+	// required: true
+	// example: [1,2,3]
+	TaskIDs []int `json:"task_ids"`
+
+	// Новые значения полей для операции update
+	Fields *BulkTaskFields `json:"fields,omitempty"`
+}
+
+// BulkRequest - тело запроса массового изменения задач.
+// swagger:model BulkRequest
+type BulkRequest struct {
+	Operations []BulkOperation `json:"operations"`
+
+	// По умолчанию все операции выполняются в одной транзакции и отменяются при первой ошибке.
+	// С continue_on_error ошибочные задачи пропускаются, остальные изменения сохраняются.
+	ContinueOnError bool `json:"continue_on_error"`
+}
+
+// BulkItemResult - результат операции над одной задачей.
+// swagger:model BulkItemResult
+type BulkItemResult struct {
+	// example: archive
+	Op string `json:"op"`
+	// example: 1
+	TaskID int `json:"task_id"`
+	// ok, error или skipped (не выполнялась из-за ошибки в другой задаче)
+	// example: ok
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Task   *Task  `json:"task,omitempty"`
+}
+
+// BulkResponse - результат массового изменения задач.
+// swagger:model BulkResponse
+type BulkResponse struct {
+	// Были ли изменения сохранены
+	Committed bool             `json:"committed"`
+	Results   []BulkItemResult `json:"results"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CalendarToken описывает секретный токен доступа к календарной ленте задач.
+// В базе хранится только хеш токена.
+// swagger:model CalendarToken
+type CalendarToken struct {
+	// Уникальный идентификатор токена
+	// example: 1
+	ID int `json:"id"`
+
+	// Название, чтобы отличать ленты в разных приложениях
+	// example: Телефон
+	Name string `json:"name"`
+
+	// Секретный токен, возвращается только при создании
+	Token string `json:"token,omitempty"`
+
+	// Адрес ленты относительно /api/v1, возвращается только при создании
+	// example: /calendar/2b7e1516...ics
+	FeedPath string `json:"feed_path,omitempty"`
+
+	// ID пользователя-владельца токена
+	// example: 42
+	UserID int `json:"user_id"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CalendarTokenCreatePayload определяет поля для создания токена ленты.
+// swagger:model CalendarTokenCreatePayload
+type CalendarTokenCreatePayload struct {
+	// Название токена (опционально)
+	// example: Телефон
+	Name string `json:"name"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// StatusCount - количество задач в статусе на конец дня (UTC).
+type StatusCount struct {
+	Day    time.Time
+	Status string
+	Count  int
+}
+
+// CumulativeFlowDay - количество задач по статусам на конец дня.
+// swagger:model CumulativeFlowDay
+type CumulativeFlowDay struct {
+	// example: 2025-05-18
+	Date string `json:"date"`
+	// Количество задач по статусам; статусы без задач имеют значение 0
+	// example: {"pending": 4, "completed": 10}
+	Counts map[string]int `json:"counts"`
+}
+
+// CumulativeFlow - данные диаграммы совокупного потока.
+// swagger:model CumulativeFlow
+type CumulativeFlow struct {
+	// example: 2025-05-01
+	From string `json:"from"`
+	// example: 2025-05-31
+	To string `json:"to"`
+	// Статусы в порядке слоев диаграммы
+	// example: ["pending","in_progress","completed"]
+	Statuses []string            `json:"statuses"`
+	Days     []CumulativeFlowDay `json:"days"`
+}
@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+// Типы пользовательских полей и форматы их значений в Task.CustomFields.
+const (
+	// CustomFieldText - строка.
+	CustomFieldText = "text"
+	// CustomFieldNumber - число.
+	CustomFieldNumber = "number"
+	// CustomFieldDate - дата в формате 2006-01-02.
+	CustomFieldDate = "date"
+	// CustomFieldSelect - один из вариантов options.
+	CustomFieldSelect = "select"
+	// CustomFieldMultiSelect - массив различных вариантов options.
+	CustomFieldMultiSelect = "multi_select"
+	// CustomFieldUser - ID пользователя.
+	CustomFieldUser = "user"
+	// CustomFieldURL - абсолютный URL http или https.
+	CustomFieldURL = "url"
+)
+
+// CustomField - определение пользовательского поля задач.
+// Досок нет, поэтому поля определяются для всех задач пользователя.
+// swagger:model CustomField
+type CustomField struct {
+	// example: 1
+	ID int `json:"id"`
+
+	// example: 42
+	UserID int `json:"user_id"`
+
+	// Ключ значения в custom_fields задачи; не меняется после создания
+	// example: severity
+	Key string `json:"key"`
+
+	// example: Критичность
+	Name string `json:"name"`
+
+	// text, number, date, select, multi_select, user или url; не меняется после создания
+	// example: select
+	Type string `json:"type"`
+
+	// Значение обязательно при создании и изменении задачи
+	Required bool `json:"required"`
+
+	// Варианты для select и multi_select
+	// example: ["low","medium","high"]
+	Options []string `json:"options,omitempty"`
+
+	// Наименьшее значение для number
+	// example: 0
+	Min *float64 `json:"min,omitempty"`
+
+	// Наибольшее значение для number
+	// example: 100
+	Max *float64 `json:"max,omitempty"`
+
+	// Наибольшая длина для text
+	// example: 200
+	MaxLength *int `json:"max_length,omitempty"`
+
+	// Регулярное выражение (RE2), которому должно целиком соответствовать значение text
+	// example: ^[A-Z]+-[0-9]+$
+	Pattern string `json:"pattern,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CustomFieldPayload определяет поля для создания и изменения пользовательского поля.
+// При изменении key и type игнорируются.
+// swagger:model CustomFieldPayload
+type CustomFieldPayload struct {
+	// example: severity
+	Key string `json:"key"`
+	// example: Критичность
+	Name string `json:"name"`
+	// example: select
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	// example: 200
+	MaxLength *int   `json:"max_length,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+}
+
+// TaskQuery - параметры выборки списка задач.
+type TaskQuery struct {
+	IncludeArchived bool
+	Filters         []CustomFieldFilter
+	// Sort - сортировка по пользовательскому полю; nil - по времени создания
+	Sort *CustomFieldSort
+}
+
+// Операторы фильтров по пользовательским полям.
+const (
+	FilterEq  = "eq"
+	FilterGte = "gte"
+	FilterLte = "lte"
+)
+
+// CustomFieldFilter - условие на значение пользовательского поля.
+// Для multi_select eq означает, что среди выбранных вариантов есть Value.
+type CustomFieldFilter struct {
+	Key   string
+	Type  string
+	Op    string
+	Value interface{}
+}
+
+// CustomFieldSort - сортировка по пользовательскому полю; задачи без значения идут последними.
+type CustomFieldSort struct {
+	Key  string
+	Type string
+	Desc bool
+}
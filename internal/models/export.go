@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// ExportVersion - текущая версия формата экспорта задач.
+// Версия увеличивается при любом несовместимом изменении формата;
+// импорт принимает только поддерживаемые версии.
+const ExportVersion = 1
+
+// TaskExport - документ экспорта задач в формате JSON (версия 1):
+//
+//	{
+//	  "version": 1,
+//	  "exported_at": "2025-05-18T10:00:00Z",
+//	  "tasks": [
+//	    {"id": 1, "title": "...", "description": "...", "status": "pending",
+//	     "due_at": "2025-06-01T18:00:00Z", "rrule": "FREQ=WEEKLY", "estimate": 3, "archived_at": null}
+//	  ]
+//	}
+//
+// CSV содержит строку заголовка id,title,description,status,archived_at,due_at,rrule,estimate и по строке на задачу;
+// archived_at и due_at записываются в RFC 3339 или пустой строкой, estimate - числом или пустой строкой.
+// Значения пользовательских полей (custom_fields) переносятся только в JSON и при импорте
+// проверяются по полям пользователя, в которого выполняется импорт.
+// Необязательные поля могут отсутствовать при импорте в обоих форматах.
+// swagger:model TaskExport
+type TaskExport struct {
+	// Версия формата
+	// example: 1
+	Version int `json:"version"`
+
+	ExportedAt time.Time `json:"exported_at"`
+
+	Tasks []ExportedTask `json:"tasks"`
+}
+
+// ExportedTask - задача в документе экспорта. ID указывается для справки:
+// при импорте задачи получают новые идентификаторы.
+// swagger:model ExportedTask
+type ExportedTask struct {
+	// example: 1
+	ID int `json:"id,omitempty"`
+	// example: Купить молоко
+	Title string `json:"title"`
+	// example: Нежирное, 1 литр
+	Description string `json:"description,omitempty"`
+	// example: pending
+	Status string `json:"status,omitempty"`
+
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Правило повторения; при импорте серия начинается с due_at
+	// example: FREQ=WEEKLY
+	RRule string `json:"rrule,omitempty"`
+
+	// example: 3
+	Estimate *float64 `json:"estimate,omitempty"`
+
+	// example: {"severity": "high"}
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// ImportRowError - ошибки проверки одной задачи при импорте.
+// swagger:model ImportRowError
+type ImportRowError struct {
+	// Номер задачи в документе, начиная с 1 (для CSV - номер строки данных без заголовка)
+	// example: 3
+	Row int `json:"row"`
+
+	Errors []string `json:"errors"`
+}
+
+// ImportReport - результат импорта задач.
+// swagger:model ImportReport
+type ImportReport struct {
+	// Запрос выполнен в режиме проверки, задачи не созданы
+	DryRun bool `json:"dry_run"`
+
+	// Сколько задач прочитано из документа
+	// example: 10
+	Total int `json:"total"`
+
+	// Сколько задач создано
+	// example: 10
+	Created int `json:"created"`
+
+	// Ошибки проверки; если они есть, ни одна задача не создается
+	Errors []ImportRowError `json:"errors"`
+
+	// ID созданных задач в порядке документа
+	TaskIDs []int `json:"task_ids,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// StoredResponse - сохраненный ответ на запрос с заголовком Idempotency-Key.
+type StoredResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Header     map[string][]string `json:"header"`
+	Body       []byte              `json:"body"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Режимы отправки уведомлений по почте.
+const (
+	// MailInstant - письмо на каждое уведомление.
+	MailInstant = "instant"
+	// MailDaily - уведомления за день приходят одним письмом-сводкой.
+	MailDaily = "daily"
+	// MailOff - уведомления этого типа по почте не приходят.
+	MailOff = "off"
+)
+
+// MailModes - допустимые режимы отправки.
+var MailModes = []string{MailInstant, MailDaily, MailOff}
+
+// DefaultMailModes - режимы отправки по типам уведомлений, пока пользователь их не изменил.
+// Изменения отслеживаемых задач по умолчанию приходят сводкой, чтобы не засыпать почту.
+var DefaultMailModes = map[string]string{
+	NotificationMention:      MailInstant,
+	NotificationDueSoon:      MailInstant,
+	NotificationTaskUpdated:  MailDaily,
+	NotificationTasksUpdated: MailDaily,
+}
+
+// MailSettings - почтовый адрес и режимы отправки уведомлений пользователя.
+// swagger:model MailSettings
+type MailSettings struct {
+	// Адрес для уведомлений; null - уведомления по почте не отправляются.
+	// Письма приходят только на подтвержденный адрес; после смены адреса его нужно подтвердить заново
+	// example: alice@example.com
+	Email *string `json:"email"`
+
+	// Адрес подтвержден по ссылке из письма; при изменении настроек не учитывается
+	// example: true
+	EmailVerified bool `json:"email_verified"`
+
+	// Режим отправки (instant, daily или off) по типу уведомления
+	// example: {"mention": "instant", "due_soon": "instant", "task_updated": "daily", "tasks_updated": "off"}
+	Preferences map[string]string `json:"preferences"`
+}
+
+// Статусы писем в очереди отправки.
+const (
+	MailPending = "pending"
+	MailSent    = "sent"
+	MailDead    = "dead"
+)
+
+// OutboxMail - письмо в очереди отправки mail_outbox.
+type OutboxMail struct {
+	ID      int64
+	UserID  int
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers - дополнительные заголовки письма, например List-Unsubscribe
+	Headers map[string]string
+
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        *time.Time
+	CreatedAt     time.Time
+}
+
+// MailRecipient - получатель письма с уведомлениями.
+type MailRecipient struct {
+	UserID   int
+	Username string
+	Email    string
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// FlowMetricsQuery - параметры расчета метрик потока.
+// Статус задачи играет роль колонки доски.
+type FlowMetricsQuery struct {
+	From time.Time
+	To   time.Time
+	// Статусы выполненной работы, например completed
+	DoneStatuses []string
+	// Статусы, в которых работа еще не начата, например pending
+	BacklogStatuses []string
+}
+
+// Percentiles - перцентили длительности в часах; nil, если данных нет.
+// swagger:model Percentiles
+type Percentiles struct {
+	// example: 20.5
+	P50 *float64 `json:"p50"`
+	// example: 46
+	P85 *float64 `json:"p85"`
+	// example: 70.25
+	P95 *float64 `json:"p95"`
+}
+
+// WeeklyThroughput - количество задач, выполненных за неделю.
+// swagger:model WeeklyThroughput
+type WeeklyThroughput struct {
+	// Понедельник недели (UTC)
+	WeekStart time.Time `json:"week_start"`
+	// example: 12
+	Completed int `json:"completed"`
+}
+
+// WorkItemAge - возраст незавершенной задачи, считая от начала работы над ней.
+// swagger:model WorkItemAge
+type WorkItemAge struct {
+	// example: 7
+	TaskID int `json:"task_id"`
+	// example: in_progress
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"started_at"`
+	// example: 30.5
+	AgeHours float64 `json:"age_hours"`
+}
+
+// FlowMetrics - метрики потока задач за период.
+// Время выполнения (lead time) считается от создания задачи до перехода в выполненный статус,
+// время цикла (cycle time) - от первого выхода из статусов бэклога.
+// swagger:model FlowMetrics
+type FlowMetrics struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	DoneStatuses    []string  `json:"done_statuses"`
+	BacklogStatuses []string  `json:"backlog_statuses"`
+
+	// Количество задач, выполненных за период
+	// example: 40
+	Completed int `json:"completed"`
+
+	LeadTime  Percentiles `json:"lead_time_hours"`
+	CycleTime Percentiles `json:"cycle_time_hours"`
+
+	Throughput []WeeklyThroughput `json:"weekly_throughput"`
+
+	// Возраст задач в работе на момент запроса: перцентили и самые старые задачи
+	WorkItemAge Percentiles   `json:"work_item_age_hours"`
+	InProgress  []WorkItemAge `json:"in_progress"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Типы уведомлений.
+const (
+	// NotificationMention - пользователя упомянули через @username в описании задачи.
+	NotificationMention = "mention"
+	// NotificationDueSoon - срок задачи скоро наступит.
+	NotificationDueSoon = "due_soon"
+	// NotificationTaskUpdated - отслеживаемая задача изменилась.
+	NotificationTaskUpdated = "task_updated"
+	// NotificationTasksUpdated - сводка об изменении сразу нескольких отслеживаемых задач;
+	// task_id указывает на последнюю измененную задачу.
+	NotificationTasksUpdated = "tasks_updated"
+)
+
+// NotificationTypes - все типы уведомлений.
+var NotificationTypes = []string{NotificationMention, NotificationDueSoon, NotificationTaskUpdated, NotificationTasksUpdated}
+
+// Notification - уведомление во входящих пользователя.
+// swagger:model Notification
+type Notification struct {
+	// example: 15
+	ID int64 `json:"id"`
+
+	// Получатель уведомления
+	// example: 42
+	UserID int `json:"user_id"`
+
+	// mention, due_soon, task_updated или tasks_updated
+	// example: mention
+	Type string `json:"type"`
+
+	// example: 7
+	TaskID int `json:"task_id"`
+
+	// Название задачи на момент уведомления
+	// example: Подготовить релиз
+	TaskTitle string `json:"task_title"`
+
+	// Пользователь, вызвавший уведомление; отсутствует у due_soon
+	// example: 12
+	ActorID *int `json:"actor_id,omitempty"`
+
+	// example: alice
+	ActorUsername string `json:"actor_username,omitempty"`
+
+	// Строка описания с упоминанием или описание изменений
+	// example: @bob проверь, пожалуйста
+	Text string `json:"text,omitempty"`
+
+	// Срок задачи для due_soon
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// DedupKey отличает повторные уведомления одного типа по одной задаче:
+	// уведомление с тем же ключом не создается повторно.
+	DedupKey string `json:"-"`
+}
+
+// NotificationPage - страница входящих.
+// swagger:model NotificationPage
+type NotificationPage struct {
+	Items []Notification `json:"items"`
+
+	// Значение параметра before для следующей страницы; отсутствует на последней странице
+	// example: 9
+	NextBefore *int64 `json:"next_before,omitempty"`
+}
+
+// NotificationCount - количество непрочитанных уведомлений.
+// swagger:model NotificationCount
+type NotificationCount struct {
+	// example: 3
+	Unread int `json:"unread"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// Типы триггеров правил автоматизации.
+const (
+	// RuleTriggerEvent срабатывает на события задач.
+	RuleTriggerEvent = "event"
+	// RuleTriggerDuePassed срабатывает один раз для каждой задачи, срок которой прошел.
+	RuleTriggerDuePassed = "due_passed"
+)
+
+// Rule - правило автоматизации "когда X, если Y, то Z" для задач пользователя.
+// swagger:model Rule
+type Rule struct {
+	// Уникальный идентификатор правила
+	// example: 1
+	ID int `json:"id"`
+
+	// example: Архивировать выполненные
+	Name string `json:"name"`
+
+	// Выключенные правила не срабатывают
+	// example: true
+	Enabled bool `json:"enabled"`
+
+	Trigger RuleTrigger `json:"trigger"`
+
+	// Все условия должны выполняться; пустой список - без условий
+	Conditions []RuleCondition `json:"conditions"`
+
+	// Действия выполняются по порядку до первой ошибки
+	Actions []RuleAction `json:"actions"`
+
+	// ID пользователя-владельца правила
+	// example: 42
+	UserID int `json:"user_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RuleTrigger описывает, когда проверяется правило.
+// swagger:model RuleTrigger
+type RuleTrigger struct {
+	// event или due_passed
+	// example: event
+	Type string `json:"type"`
+
+	// Типы событий для type=event; пустой список означает все события
+	// example: ["task.updated"]
+	Events []string `json:"events,omitempty"`
+}
+
+// RuleCondition - условие на поле задачи.
+// Поля: title, description, status, due_at, archived.
+// Операторы: eq, neq, contains (строки), is_set, is_not_set, before_now, after_now (due_at).
+// swagger:model RuleCondition
+type RuleCondition struct {
+	// example: status
+	Field string `json:"field"`
+	// example: eq
+	Op string `json:"op"`
+	// example: completed
+	Value string `json:"value,omitempty"`
+}
+
+// RuleAction - действие правила.
+// Типы: set_status (value), archive, unarchive, delete, webhook (url, secret).
+// swagger:model RuleAction
+type RuleAction struct {
+	// example: set_status
+	Type string `json:"type"`
+
+	// Новый статус для set_status
+	// example: completed
+	Value string `json:"value,omitempty"`
+
+	// URL для webhook
+	// example: https://chat.example.com/hooks/kanban
+	URL string `json:"url,omitempty"`
+
+	// Секрет подписи HMAC-SHA256 для webhook (опционально). В ответах не возвращается
+	Secret string `json:"secret,omitempty"`
+
+	// Задан ли секрет подписи webhook (только в ответах)
+	HasSecret bool `json:"has_secret,omitempty"`
+}
+
+// RulePayload определяет поля для создания и изменения правила.
+// swagger:model RulePayload
+type RulePayload struct {
+	// required: true
+	// example: Архивировать выполненные
+	Name string `json:"name"`
+
+	// По умолчанию true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	Trigger    RuleTrigger     `json:"trigger"`
+	Conditions []RuleCondition `json:"conditions"`
+	Actions    []RuleAction    `json:"actions"`
+}
+
+// Результаты выполнения правила.
+const (
+	RuleExecutionSucceeded = "succeeded"
+	RuleExecutionFailed    = "failed"
+	// RuleExecutionSkipped - правило не выполнено защитой от зацикливания.
+	RuleExecutionSkipped = "skipped"
+)
+
+// RuleExecution - запись журнала выполнения правила.
+// swagger:model RuleExecution
+type RuleExecution struct {
+	// example: 10
+	ID int64 `json:"id"`
+	// example: 1
+	RuleID int `json:"rule_id"`
+	// example: 7
+	TaskID int `json:"task_id"`
+
+	// Событие или триггер, вызвавший правило
+	// example: task.updated
+	Trigger string `json:"trigger"`
+
+	// succeeded, failed или skipped
+	// example: succeeded
+	Status string `json:"status"`
+
+	// Выполненные действия
+	// example: ["set_status","archive"]
+	Actions []string `json:"actions"`
+
+	Error string `json:"error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import "time"
+
+// Sprint - итерация с фиксированными датами начала и окончания.
+// Задача входит не больше чем в один спринт (Task.SprintID).
+// swagger:model Sprint
+type Sprint struct {
+	// example: 3
+	ID int `json:"id"`
+
+	// example: 42
+	UserID int `json:"user_id"`
+
+	// example: Спринт 12
+	Name string `json:"name"`
+
+	// Цель спринта
+	// example: Выпустить экспорт в CSV
+	Goal string `json:"goal,omitempty"`
+
+	// Первый день спринта (UTC)
+	// example: 2025-06-02
+	StartDate string `json:"start_date"`
+
+	// Последний день спринта включительно (UTC)
+	// example: 2025-06-15
+	EndDate string `json:"end_date"`
+
+	// Время закрытия; закрытый спринт нельзя пополнять
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+
+	// Итоги, зафиксированные при закрытии
+	Stats *SprintStats `json:"stats,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SprintStats - итоги закрытого спринта.
+// swagger:model SprintStats
+type SprintStats struct {
+	// Статусы, считавшиеся выполненными при закрытии
+	// example: ["completed"]
+	DoneStatuses []string `json:"done_statuses"`
+
+	// example: 8
+	CompletedTasks int `json:"completed_tasks"`
+	// example: 21
+	CompletedPoints float64 `json:"completed_points"`
+
+	// example: 2
+	UnfinishedTasks int `json:"unfinished_tasks"`
+	// example: 5
+	UnfinishedPoints float64 `json:"unfinished_points"`
+
+	// Спринт, в который перенесены невыполненные задачи; пусто - задачи вернулись в бэклог
+	// example: 4
+	RolledOverTo *int `json:"rolled_over_to,omitempty"`
+}
+
+// SprintPayload определяет поля для создания и изменения спринта.
+// swagger:model SprintPayload
+type SprintPayload struct {
+	// required: true
+	// example: Спринт 12
+	Name string `json:"name"`
+
+	// example: Выпустить экспорт в CSV
+	Goal string `json:"goal,omitempty"`
+
+	// required: true
+	// example: 2025-06-02
+	StartDate string `json:"start_date"`
+
+	// required: true
+	// example: 2025-06-15
+	EndDate string `json:"end_date"`
+}
+
+// SprintTasksPayload - задачи, добавляемые в спринт.
+// swagger:model SprintTasksPayload
+type SprintTasksPayload struct {
+	// example: [7, 8, 12]
+	TaskIDs []int `json:"task_ids"`
+}
+
+// SprintClosePayload - параметры закрытия спринта.
+// swagger:model SprintClosePayload
+type SprintClosePayload struct {
+	// Спринт для невыполненных задач; если не указан, берется ближайший следующий
+	// незакрытый спринт, а при его отсутствии задачи возвращаются в бэклог
+	// example: 4
+	NextSprintID *int `json:"next_sprint_id,omitempty"`
+
+	// Выполненные статусы, по умолчанию completed
+	// example: ["completed"]
+	DoneStatuses []string `json:"done_statuses,omitempty"`
+}
+
+// SprintDay - объем спринта и выполненная часть на конец дня (UTC).
+// Задачи без оценки учитываются в количестве задач, но не в очках.
+// swagger:model SprintDay
+type SprintDay struct {
+	// example: 2025-06-03
+	Date string `json:"date"`
+
+	// example: 10
+	ScopeTasks int `json:"scope_tasks"`
+	// example: 26
+	ScopePoints float64 `json:"scope_points"`
+
+	// example: 3
+	DoneTasks int `json:"done_tasks"`
+	// example: 8
+	DonePoints float64 `json:"done_points"`
+}
+
+// SprintBurnup - данные диаграммы сгорания вверх: объем и выполненная часть по дням.
+// swagger:model SprintBurnup
+type SprintBurnup struct {
+	// example: 3
+	SprintID int `json:"sprint_id"`
+
+	Days []SprintDay `json:"days"`
+}
+
+// BurndownDay - остаток работы на конец дня и идеальная линия сгорания.
+// swagger:model BurndownDay
+type BurndownDay struct {
+	// example: 2025-06-03
+	Date string `json:"date"`
+
+	// example: 7
+	RemainingTasks int `json:"remaining_tasks"`
+	// example: 18
+	RemainingPoints float64 `json:"remaining_points"`
+
+	// Идеальный остаток: равномерное сгорание объема первого дня к последнему дню спринта
+	// example: 9.29
+	IdealTasks float64 `json:"ideal_tasks"`
+	// example: 24.14
+	IdealPoints float64 `json:"ideal_points"`
+}
+
+// SprintBurndown - данные диаграммы сгорания спринта.
+// swagger:model SprintBurndown
+type SprintBurndown struct {
+	// example: 3
+	SprintID int `json:"sprint_id"`
+
+	Days []BurndownDay `json:"days"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Task представляет собой задачу в списке дел.
 // swagger:model Task
 type Task struct {
@@ -23,6 +25,41 @@ type Task struct {
 	// ID пользователя-владельца задачи
 	// example: 42
 	UserID int `json:"user_id"`
+
+	// Время архивации; архивные задачи скрыты из списка, если не указан include_archived
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// Время перемещения в корзину
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Срок выполнения (опционально)
+	// example: 2025-06-01T18:00:00Z
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Правило повторения RFC 5545 (RRULE); следующее повторение создается,
+	// когда задача выполнена или наступил ее срок
+	// example: FREQ=WEEKLY;BYDAY=MO
+	RRule string `json:"rrule,omitempty"`
+
+	// Начало серии повторений (DTSTART), от него отсчитываются COUNT и INTERVAL
+	// example: 2025-06-02T09:00:00Z
+	RecurrenceStart *time.Time `json:"recurrence_start,omitempty"`
+
+	// Оценка трудоемкости в очках (опционально)
+	// example: 3
+	Estimate *float64 `json:"estimate,omitempty"`
+
+	// Спринт, в который входит задача
+	// example: 3
+	SprintID *int `json:"sprint_id,omitempty"`
+
+	// Значения пользовательских полей по ключам полей
+	// example: {"severity": "high", "customer": "ООО Ромашка"}
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+
+	// Версия задачи, увеличивается при каждом изменении; передается в ETag
+	// example: 3
+	Version int `json:"version"`
 }
 
 // CreateTaskRequest описывает тело запроса для создания задачи.
@@ -46,6 +83,68 @@ type TaskCreatePayload struct {
 	// Описание задачи (опционально)
 	// example: Сразу после ужина
 	Description string `json:"description,omitempty"`
+
+	// Срок выполнения (опционально)
+	// example: 2025-06-01T18:00:00Z
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Правило повторения RFC 5545 (опционально, требует due_at)
+	// example: FREQ=WEEKLY;BYDAY=MO
+	RRule string `json:"rrule,omitempty"`
+
+	// Оценка трудоемкости в очках (опционально)
+	// example: 3
+	Estimate *float64 `json:"estimate,omitempty"`
+
+	// Значения пользовательских полей; обязательные поля должны быть указаны
+	// example: {"severity": "high"}
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// TaskUpdatePayload определяет поля для изменения задачи.
+// swagger:model TaskUpdatePayload
+type TaskUpdatePayload struct {
+	// Название задачи
+	// required: true
+	// example: Помыть посуду
+	Title string `json:"title" validate:"required"`
+
+	// Описание задачи (опционально)
+	// example: И вытереть насухо
+	Description string `json:"description,omitempty"`
+
+	// Статус задачи; если не указан, остается прежним
+	// example: completed
+	Status string `json:"status,omitempty"`
+
+	// Срок выполнения; если не указан, срок снимается
+	// example: 2025-06-01T18:00:00Z
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Правило повторения; если не указано, повторение снимается.
+	// Изменение правила или срока начинает серию заново с due_at
+	// example: FREQ=WEEKLY;BYDAY=MO
+	RRule string `json:"rrule,omitempty"`
+
+	// Оценка трудоемкости в очках; если не указана, оценка снимается
+	// example: 5
+	Estimate *float64 `json:"estimate,omitempty"`
+
+	// Значения пользовательских полей; заменяют прежние значения целиком
+	// example: {"severity": "high"}
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// RecurrencePreview - ближайшие повторения задачи.
+// swagger:model RecurrencePreview
+type RecurrencePreview struct {
+	// example: 7
+	TaskID int `json:"task_id"`
+
+	// example: FREQ=WEEKLY;BYDAY=MO
+	RRule string `json:"rrule"`
+
+	Occurrences []time.Time `json:"occurrences"`
 }
 
 // TaskIDParameter описывает параметр ID задачи в пути URL.
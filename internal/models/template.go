@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// Template - шаблон набора задач. Срок задачи в шаблоне задается относительно
+// момента создания задач из шаблона. Шаблон из одной задачи служит шаблоном карточки.
+// swagger:model Template
+type Template struct {
+	// Уникальный идентификатор шаблона
+	// example: 1
+	ID int `json:"id"`
+
+	// example: Новый релиз
+	Name string `json:"name"`
+
+	// example: Задачи, с которых начинается каждый релиз
+	Description string `json:"description,omitempty"`
+
+	Tasks []TemplateTask `json:"tasks"`
+
+	// ID пользователя-владельца шаблона
+	// example: 42
+	UserID int `json:"user_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateTask - задача в шаблоне.
+// swagger:model TemplateTask
+type TemplateTask struct {
+	// example: Обновить зависимости
+	Title string `json:"title"`
+
+	// example: go get -u ./... и прогнать тесты
+	Description string `json:"description,omitempty"`
+
+	// Статус создаваемой задачи; по умолчанию pending
+	// example: pending
+	Status string `json:"status,omitempty"`
+
+	// Срок в часах от момента создания задач из шаблона (опционально)
+	// example: 48
+	DueInHours *int `json:"due_in_hours,omitempty"`
+
+	// Правило повторения; требует due_in_hours
+	// example: FREQ=WEEKLY
+	RRule string `json:"rrule,omitempty"`
+}
+
+// TemplatePayload определяет поля для создания и изменения шаблона.
+// swagger:model TemplatePayload
+type TemplatePayload struct {
+	// required: true
+	// example: Новый релиз
+	Name string `json:"name"`
+
+	Description string `json:"description,omitempty"`
+
+	Tasks []TemplateTask `json:"tasks"`
+}
+
+// TemplateFromTasksPayload определяет поля для сохранения существующих задач как шаблона.
+// swagger:model TemplateFromTasksPayload
+type TemplateFromTasksPayload struct {
+	// required: true
+	// example: Новый релиз
+	Name string `json:"name"`
+
+	Description string `json:"description,omitempty"`
+
+	// ID задач; если не указаны, берутся все неархивные задачи
+	// example: [1,2,3]
+	TaskIDs []int `json:"task_ids,omitempty"`
+
+	// Момент, относительно которого считаются сроки задач; по умолчанию текущее время
+	RelativeTo *time.Time `json:"relative_to,omitempty"`
+}
+
+// TemplateInstantiatePayload определяет параметры создания задач из шаблона.
+// swagger:model TemplateInstantiatePayload
+type TemplateInstantiatePayload struct {
+	// Момент, от которого отсчитываются сроки; по умолчанию текущее время
+	Start *time.Time `json:"start,omitempty"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+// TimeEntry - отрезок времени, затраченного на задачу: запись таймера или ручная запись.
+// У запущенного таймера нет ended_at; его длительность считается до текущего момента.
+// swagger:model TimeEntry
+type TimeEntry struct {
+	// example: 15
+	ID int64 `json:"id"`
+
+	// example: 42
+	UserID int `json:"user_id"`
+
+	// example: 7
+	TaskID int `json:"task_id"`
+
+	// Название задачи (в табеле)
+	// example: Подготовить отчет
+	TaskTitle string `json:"task_title,omitempty"`
+
+	// example: 2025-06-02T09:00:00Z
+	StartedAt time.Time `json:"started_at"`
+
+	// example: 2025-06-02T10:30:00Z
+	EndedAt *time.Time `json:"ended_at,omitempty"`
+
+	// Длительность в секундах
+	// example: 5400
+	DurationSeconds int64 `json:"duration_seconds"`
+
+	// Таймер запущен
+	Running bool `json:"running"`
+
+	// example: Созвон с клиентом
+	Note string `json:"note,omitempty"`
+}
+
+// TimeEntryPayload определяет поля ручной записи времени.
+// swagger:model TimeEntryPayload
+type TimeEntryPayload struct {
+	// required: true
+	// example: 2025-06-02T09:00:00Z
+	StartedAt time.Time `json:"started_at"`
+
+	// required: true
+	// example: 2025-06-02T10:30:00Z
+	EndedAt time.Time `json:"ended_at"`
+
+	// example: Созвон с клиентом
+	Note string `json:"note,omitempty"`
+}
+
+// TimerStartPayload - необязательная заметка к запускаемому таймеру.
+// swagger:model TimerStartPayload
+type TimerStartPayload struct {
+	// example: Работа над отчетом
+	Note string `json:"note,omitempty"`
+}
+
+// TaskTime - время, затраченное на задачу.
+// swagger:model TaskTime
+type TaskTime struct {
+	// example: 7
+	TaskID int `json:"task_id"`
+
+	// Сумма всех записей, включая запущенный таймер
+	// example: 9000
+	TotalSeconds int64 `json:"total_seconds"`
+
+	Entries []TimeEntry `json:"entries"`
+}
+
+// TimesheetTask - итог табеля по задаче.
+// swagger:model TimesheetTask
+type TimesheetTask struct {
+	// example: 7
+	TaskID int `json:"task_id"`
+	// example: Подготовить отчет
+	TaskTitle string `json:"task_title"`
+	// example: 9000
+	TotalSeconds int64 `json:"total_seconds"`
+}
+
+// TimesheetDay - итог табеля за день (UTC).
+// swagger:model TimesheetDay
+type TimesheetDay struct {
+	// example: 2025-06-02
+	Date string `json:"date"`
+	// example: 27000
+	TotalSeconds int64 `json:"total_seconds"`
+}
+
+// Timesheet - записи времени пользователя за период с итогами по задачам и дням.
+// Запись относится к дню, в который она начата.
+// swagger:model Timesheet
+type Timesheet struct {
+	// example: 2025-06-01
+	From string `json:"from"`
+	// example: 2025-06-30
+	To string `json:"to"`
+
+	// example: 144000
+	TotalSeconds int64 `json:"total_seconds"`
+
+	Tasks []TimesheetTask `json:"tasks"`
+
+	Days []TimesheetDay `json:"days"`
+
+	Entries []TimeEntry `json:"entries"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// TOTP - секрет двухфакторной аутентификации пользователя.
+// Пока ConfirmedAt не задан, вход по-прежнему выполняется только по паролю.
+type TOTP struct {
+	UserID      int
+	Secret      string
+	ConfirmedAt *time.Time
+	// LastCounter - шаг времени последнего принятого кода; коды этого и прошлых шагов отклоняются
+	LastCounter    int64
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// TwoFactorStatus - состояние двухфакторной аутентификации пользователя.
+// swagger:model TwoFactorStatus
+type TwoFactorStatus struct {
+	// example: true
+	Enabled bool `json:"enabled"`
+	// Сколько неиспользованных кодов восстановления осталось
+	// example: 8
+	RecoveryCodesLeft int `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollment - секрет для приложения-аутентификатора, выданный при подключении 2FA.
+// swagger:model TwoFactorEnrollment
+type TwoFactorEnrollment struct {
+	// Секрет в base32 для ввода вручную
+	// example: JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	Secret string `json:"secret"`
+	// Адрес для QR-кода
+	// example: otpauth://totp/Kanban:alice?algorithm=SHA1&digits=6&issuer=Kanban&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	URI string `json:"otpauth_uri"`
+}
+
+// TwoFactorCodePayload - код из приложения-аутентификатора или код восстановления.
+type TwoFactorCodePayload struct {
+	// example: 123456
+	Code string `json:"code"`
+}
+
+// RecoveryCodes - одноразовые коды восстановления. Показываются один раз, в базе хранятся только хеши.
+// swagger:model RecoveryCodes
+type RecoveryCodes struct {
+	// example: ["t5g9z-me77m", "56gmm-ebjdh"]
+	Codes []string `json:"recovery_codes"`
+}
+
+// LoginChallenge возвращается вместо JWT, если у пользователя включена 2FA.
+// swagger:model LoginChallenge
+type LoginChallenge struct {
+	// example: true
+	TwoFactorRequired bool `json:"two_factor_required"`
+	// Токен для POST /login/2fa
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginPayload - второй шаг входа.
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challenge_token"`
+	// Код из приложения-аутентификатора или код восстановления
+	// example: 123456
+	Code string `json:"code"`
+}
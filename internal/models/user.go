@@ -4,9 +4,13 @@ import "time"
 
 // User представляет пользователя системы.
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"` // Не возвращаем хеш пароля в API
+	ID              int        `json:"id"`
+	Username        string     `json:"username"`
+	Email           *string    `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	PasswordHash    string     `json:"-"` // Не возвращаем хеш пароля в API
+	// TokenVersion увеличивается при сбросе пароля и отзывает ранее выданные JWT
+	TokenVersion int       `json:"-"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -14,6 +18,8 @@ type User struct {
 type UserRegisterPayload struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Email необязателен; на него отправляется письмо для подтверждения
+	Email string `json:"email,omitempty"`
 }
 
 // UserLoginPayload для логина пользователя.
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Watcher - пользователь, отслеживающий задачу.
+// swagger:model Watcher
+type Watcher struct {
+	// example: 12
+	UserID int `json:"user_id"`
+	// example: alice
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WatchedTask - задача, которую отслеживает пользователь.
+// swagger:model WatchedTask
+type WatchedTask struct {
+	// example: 7
+	TaskID int `json:"task_id"`
+	// example: Подготовить релиз
+	TaskTitle string `json:"task_title"`
+	// Владелец задачи
+	// example: 42
+	OwnerID   int       `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WatchUpdate - накопленное изменение отслеживаемой задачи, еще не отправленное наблюдателю.
+// Повторные изменения одной задачи схлопываются в одно.
+type WatchUpdate struct {
+	UserID    int
+	TaskID    int
+	TaskTitle string
+	ActorID   int
+	// EventType - тип последнего события по задаче
+	EventType string
+	UpdatedAt time.Time
+}
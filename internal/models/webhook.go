@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Webhook описывает подписку на события задач пользователя.
+// swagger:model Webhook
+type Webhook struct {
+	// Уникальный идентификатор подписки
+	// example: 1
+	ID int `json:"id"`
+
+	// URL, на который отправляются события
+	// example: https://ci.example.com/hooks/kanban
+	URL string `json:"url"`
+
+	// Типы событий; пустой список означает все события
+	// example: ["task.created"]
+	Events []string `json:"events"`
+
+	// Секрет для подписи HMAC-SHA256, возвращается только при создании
+	Secret string `json:"secret,omitempty"`
+
+	// Активна ли подписка
+	// example: true
+	Active bool `json:"active"`
+
+	// ID пользователя-владельца подписки
+	// example: 42
+	UserID int `json:"user_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookCreatePayload определяет поля для создания подписки.
+// swagger:model WebhookCreatePayload
+type WebhookCreatePayload struct {
+	// URL получателя
+	// required: true
+	// example: https://ci.example.com/hooks/kanban
+	URL string `json:"url"`
+
+	// Типы событий; пустой список означает все события
+	// example: ["task.created","task.deleted"]
+	Events []string `json:"events"`
+
+	// Секрет для подписи (опционально, иначе будет сгенерирован)
+	Secret string `json:"secret,omitempty"`
+}
+
+// Статусы доставки webhook.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery описывает доставку одного события на webhook и результат последней попытки.
+// swagger:model WebhookDelivery
+type WebhookDelivery struct {
+	ID            int64      `json:"id"`
+	WebhookID     int        `json:"webhook_id"`
+	EventID       int64      `json:"event_id,omitempty"`
+	EventType     string     `json:"event_type"`
+	RequestBody   string     `json:"request_body"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	ResponseCode  *int       `json:"response_code,omitempty"`
+	LatencyMs     *int       `json:"latency_ms,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+
+	// Секрет подписки, нужен воркеру для подписи запроса
+	Secret string `json:"-"`
+	// URL подписки, нужен воркеру для отправки
+	URL string `json:"-"`
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxMentions - сколько упоминаний из одного текста учитывается.
+	maxMentions = 20
+	// maxExcerpt - длина фрагмента текста с упоминанием в уведомлении.
+	maxExcerpt     = 200
+	maxUsernameLen = 64
+)
+
+// mentionPattern находит @username, перед которым нет буквы, цифры или точки,
+// поэтому адреса вида user@example.com не считаются упоминаниями.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}_][\p{L}\p{N}_.\-]*)`)
+
+// Mentions возвращает различные имена пользователей, упомянутые в text, в порядке появления.
+// Точки и дефисы в конце имени отбрасываются: они обычно завершают предложение.
+func Mentions(text string) []string {
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimRight(m[1], ".-")
+		if name == "" || utf8.RuneCountInString(name) > maxUsernameLen || containsString(names, name) {
+			continue
+		}
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// excerpt возвращает первую строку text, в которой упомянут username, сокращенную до maxExcerpt символов.
+func excerpt(text string, username string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if !containsString(Mentions(line), username) {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if utf8.RuneCountInString(line) > maxExcerpt {
+			line = string([]rune(line)[:maxExcerpt-1]) + "…"
+		}
+		return line
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
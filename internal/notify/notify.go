@@ -0,0 +1,296 @@
+// Package notify создает уведомления во входящих пользователей по событиям задач,
+// изменениям отслеживаемых задач и приближению сроков и отправляет их получателям
+// в реальном времени.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+const (
+	queueSize        = 1000
+	dueSoonBatchSize = 100
+
+	// watchQuiet - сколько изменения отслеживаемых задач должны не поступать,
+	// чтобы накопленное отправилось наблюдателю одной пачкой.
+	watchQuiet = time.Minute
+	// watchBatchSize - сколько наблюдателей обрабатывается за один запуск RunWatchDigest.
+	watchBatchSize = 100
+	// maxSingleUpdates - при большем числе измененных задач наблюдатель получает
+	// одно сводное уведомление вместо уведомления по каждой задаче.
+	maxSingleUpdates = 3
+	maxSummaryTitles = 5
+
+	// Задачи принадлежат одному пользователю, и упомянуть можно любого, поэтому
+	// частота уведомлений об упоминаниях от одного автора ограничена: всего
+	// не больше mentionActorLimit и одному получателю не больше mentionRecipientLimit
+	// за mentionWindow. Упоминания сверх лимита пропускаются.
+	mentionWindow         = time.Hour
+	mentionActorLimit     = 30
+	mentionRecipientLimit = 5
+)
+
+// updateTexts описывают изменения отслеживаемой задачи по типу последнего события.
+var updateTexts = map[string]string{
+	events.TaskUpdated:    "Задача изменена",
+	events.TaskDeleted:    "Задача перемещена в корзину",
+	events.TaskRestored:   "Задача восстановлена из корзины",
+	events.TaskArchived:   "Задача перемещена в архив",
+	events.TaskUnarchived: "Задача возвращена из архива",
+}
+
+// Notifier подключается к потоку событий как events.Publisher, как и движок правил:
+// события обрабатываются в фоне в той же реплике, которая их опубликовала.
+// Упоминание пользователя в задаче создает уведомление один раз: повторное
+// сохранение описания с тем же упоминанием уведомление не дублирует.
+// Создатель задачи и упомянутые в ней пользователи становятся ее наблюдателями.
+type Notifier struct {
+	Store   storage.NotificationStore
+	Watches storage.WatchStore
+	// Push доставляет созданные уведомления подключенным клиентам
+	Push events.Publisher
+	// DueSoon - за сколько до срока задачи владелец получает уведомление due_soon
+	DueSoon time.Duration
+
+	queue chan events.Event
+}
+
+// NewNotifier создает новый экземпляр Notifier.
+func NewNotifier(store storage.NotificationStore, watches storage.WatchStore, push events.Publisher, dueSoon time.Duration) *Notifier {
+	return &Notifier{
+		Store:   store,
+		Watches: watches,
+		Push:    push,
+		DueSoon: dueSoon,
+		queue:   make(chan events.Event, queueSize),
+	}
+}
+
+// Publish ставит событие задачи в очередь на обработку.
+func (n *Notifier) Publish(_ context.Context, ev events.Event) error {
+	if !events.IsKnownType(ev.Type) {
+		return nil
+	}
+	select {
+	case n.queue <- ev:
+		return nil
+	default:
+		return errors.New("очередь уведомлений переполнена, событие пропущено")
+	}
+}
+
+// Run обрабатывает очередь событий до отмены ctx.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-n.queue:
+			n.handleEvent(ctx, ev)
+		}
+	}
+}
+
+// handleEvent подписывает создателя на новую задачу, запоминает изменения для наблюдателей
+// и уведомляет пользователей, упомянутых в описании задачи.
+// Задачи принадлежат одному пользователю, поэтому автор изменения - владелец задачи.
+func (n *Notifier) handleEvent(ctx context.Context, ev events.Event) {
+	var task models.Task
+	if err := json.Unmarshal(ev.Data, &task); err != nil || task.ID == 0 {
+		log.Printf("Уведомления: событие %s задачи %d без данных задачи", ev.Type, ev.TaskID)
+		return
+	}
+	if ev.Type == events.TaskCreated {
+		if err := n.Watches.Watch(ctx, task.ID, ev.UserID); err != nil {
+			log.Printf("Уведомления: %v", err)
+		}
+	} else {
+		update := models.WatchUpdate{TaskID: task.ID, TaskTitle: task.Title, ActorID: ev.UserID, EventType: ev.Type}
+		if err := n.Watches.QueueUpdate(ctx, update); err != nil {
+			log.Printf("Уведомления: %v", err)
+		}
+	}
+	if task.DeletedAt == nil && (ev.Type == events.TaskCreated || ev.Type == events.TaskUpdated) {
+		n.notifyMentions(ctx, ev.UserID, &task)
+	}
+}
+
+// notifyMentions уведомляет упомянутых в описании пользователей и подписывает их на задачу
+// в пределах лимитов частоты упоминаний.
+func (n *Notifier) notifyMentions(ctx context.Context, actorID int, task *models.Task) {
+	names := Mentions(task.Description)
+	if len(names) == 0 {
+		return
+	}
+	ids, err := n.Store.GetUserIDsByUsernames(ctx, names)
+	if err != nil {
+		log.Printf("Уведомления: %v", err)
+		return
+	}
+	sent, err := n.Store.CountMentionsByActor(ctx, actorID, time.Now().Add(-mentionWindow))
+	if err != nil {
+		log.Printf("Уведомления: %v", err)
+		return
+	}
+	total := 0
+	for _, c := range sent {
+		total += c
+	}
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok || id == actorID {
+			continue
+		}
+		if total >= mentionActorLimit || sent[id] >= mentionRecipientLimit {
+			log.Printf("Уведомления: пользователь %d превысил лимит упоминаний, упоминание %s в задаче %d пропущено", actorID, name, task.ID)
+			continue
+		}
+		created := n.notify(ctx, &models.Notification{
+			UserID:    id,
+			Type:      models.NotificationMention,
+			TaskID:    task.ID,
+			TaskTitle: task.Title,
+			ActorID:   &actorID,
+			Text:      excerpt(task.Description, name),
+		})
+		if !created {
+			continue
+		}
+		total++
+		sent[id]++
+		if err := n.Watches.Watch(ctx, task.ID, id); err != nil {
+			log.Printf("Уведомления: %v", err)
+		}
+	}
+}
+
+// RunWatchDigest отправляет наблюдателям накопленные изменения отслеживаемых задач.
+// Изменения забираются, только когда они перестали поступать на watchQuiet, поэтому
+// массовое изменение задач приводит к одному сводному уведомлению на наблюдателя.
+// Возвращает количество созданных уведомлений.
+func (n *Notifier) RunWatchDigest(ctx context.Context) (int64, error) {
+	updates, err := n.Watches.TakeUpdates(ctx, time.Now().Add(-watchQuiet), watchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	byUser := make(map[int][]models.WatchUpdate)
+	var users []int
+	for _, u := range updates {
+		if _, ok := byUser[u.UserID]; !ok {
+			users = append(users, u.UserID)
+		}
+		byUser[u.UserID] = append(byUser[u.UserID], u)
+	}
+	var created int64
+	for _, userID := range users {
+		for _, notification := range digest(byUser[userID]) {
+			if n.notify(ctx, notification) {
+				created++
+			}
+		}
+	}
+	return created, nil
+}
+
+// digest превращает изменения одного наблюдателя в уведомления: по одному на задачу
+// или одно сводное, если задач больше maxSingleUpdates.
+func digest(updates []models.WatchUpdate) []*models.Notification {
+	sort.Slice(updates, func(i, j int) bool { return updates[i].UpdatedAt.After(updates[j].UpdatedAt) })
+	// Ключ отличает пачку от предыдущих пачек по тем же задачам
+	dedupKey := strconv.FormatInt(updates[0].UpdatedAt.UnixNano(), 10)
+	if len(updates) <= maxSingleUpdates {
+		notifications := make([]*models.Notification, 0, len(updates))
+		for _, u := range updates {
+			actorID := u.ActorID
+			notifications = append(notifications, &models.Notification{
+				UserID:    u.UserID,
+				Type:      models.NotificationTaskUpdated,
+				TaskID:    u.TaskID,
+				TaskTitle: u.TaskTitle,
+				ActorID:   &actorID,
+				Text:      updateTexts[u.EventType],
+				DedupKey:  dedupKey,
+			})
+		}
+		return notifications
+	}
+	titles := make([]string, 0, maxSummaryTitles)
+	for _, u := range updates {
+		if len(titles) == maxSummaryTitles {
+			break
+		}
+		titles = append(titles, u.TaskTitle)
+	}
+	text := fmt.Sprintf("Изменено задач: %d: %s", len(updates), strings.Join(titles, ", "))
+	if len(updates) > maxSummaryTitles {
+		text += " и другие"
+	}
+	latest := updates[0]
+	actorID := latest.ActorID
+	return []*models.Notification{{
+		UserID:    latest.UserID,
+		Type:      models.NotificationTasksUpdated,
+		TaskID:    latest.TaskID,
+		TaskTitle: latest.TaskTitle,
+		ActorID:   &actorID,
+		Text:      text,
+		DedupKey:  dedupKey,
+	}}
+}
+
+// RunDueSoon уведомляет владельцев задач, срок которых наступит в течение DueSoon.
+// Для каждого срока задачи уведомление создается один раз, в том числе при нескольких репликах.
+// Возвращает количество созданных уведомлений.
+func (n *Notifier) RunDueSoon(ctx context.Context) (int64, error) {
+	tasks, err := n.Store.GetDueSoonTasks(ctx, time.Now(), n.DueSoon, dueSoonBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var created int64
+	for _, task := range tasks {
+		ok := n.notify(ctx, &models.Notification{
+			UserID:    task.UserID,
+			Type:      models.NotificationDueSoon,
+			TaskID:    task.ID,
+			TaskTitle: task.Title,
+			DueAt:     task.DueAt,
+			DedupKey:  task.DueAt.UTC().Format(time.RFC3339Nano),
+		})
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// notify сохраняет уведомление и, если оно новое, отправляет его получателю.
+func (n *Notifier) notify(ctx context.Context, notification *models.Notification) bool {
+	created, err := n.Store.CreateNotification(ctx, notification)
+	if err != nil {
+		log.Printf("Уведомления: %v", err)
+		return false
+	}
+	if !created {
+		return false
+	}
+	ev, err := events.New(events.NotificationCreated, notification.UserID, notification.TaskID, notification)
+	if err == nil {
+		err = n.Push.Publish(ctx, ev)
+	}
+	if err != nil {
+		log.Printf("Уведомления: ошибка отправки уведомления %d: %v", notification.ID, err)
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+// Package oidc реализует вход через провайдера OpenID Connect по authorization code flow с PKCE:
+// discovery, обмен кода на токены и проверку ID-токена по ключам провайдера (JWKS).
+// Все запросы к провайдеру идут через Config.HTTPClient, поэтому провайдера можно подменить
+// локальным сервером httptest.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseSize ограничивает размер ответов провайдера.
+const maxResponseSize = 1 << 20
+
+// Config - параметры клиента OpenID Connect.
+type Config struct {
+	// Issuer - адрес провайдера; метаданные берутся из Issuer + /.well-known/openid-configuration
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Пусто - публичный клиент, защищенный только PKCE
+	RedirectURL  string
+	Scopes       []string
+	// HTTPClient выполняет запросы к провайдеру; nil - клиент с таймаутом 10 секунд
+	HTTPClient *http.Client
+}
+
+// Metadata - нужная часть метаданных провайдера (OpenID Connect Discovery 1.0).
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider - клиент провайдера OpenID Connect. Метаданные загружаются при первом входе
+// и кешируются, чтобы недоступность провайдера не мешала запуску сервера.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keySet
+}
+
+// NewProvider создает клиент провайдера.
+func NewProvider(cfg Config) *Provider {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Metadata возвращает метаданные провайдера, загружая их при первом обращении.
+func (p *Provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	var m Metadata
+	if err := p.getJSON(ctx, strings.TrimRight(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("ошибка discovery провайдера %s: %w", p.cfg.Issuer, err)
+	}
+	if strings.TrimRight(m.Issuer, "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("провайдер сообщает issuer %q вместо %q", m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("в метаданных провайдера нет authorization_endpoint, token_endpoint или jwks_uri")
+	}
+	if len(m.CodeChallengeMethods) > 0 && !contains(m.CodeChallengeMethods, "S256") {
+		return nil, errors.New("провайдер не поддерживает PKCE S256")
+	}
+	p.metadata = &m
+	p.keys = newKeySet(m.JWKSURI, p.getJSON)
+	return p.metadata, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("неверный authorization_endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", CodeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange обменивает код авторизации на токены и возвращает ID-токен без проверки.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// RFC 6749, 2.3.1: идентификатор и секрет кодируются перед Basic-аутентификацией
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ошибка запроса к token_endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&tr); err != nil {
+		return "", fmt.Errorf("неверный ответ token_endpoint (статус %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return "", fmt.Errorf("провайдер отклонил код (статус %d): %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.IDToken == "" {
+		return "", errors.New("в ответе провайдера нет id_token")
+	}
+	return tr.IDToken, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s вернул статус %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+// RandomString возвращает 256 случайных бит в base64url для state, nonce и PKCE code_verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge вычисляет PKCE code_challenge методом S256 (RFC 7636).
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"kanban-backend/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	t.Helper()
+	srv := oidctest.NewServer()
+	t.Cleanup(srv.Close)
+	p := NewProvider(Config{
+		Issuer:       srv.URL,
+		ClientID:     srv.ClientID,
+		ClientSecret: srv.ClientSecret,
+		RedirectURL:  "https://kanban.example.com/api/v1/oidc/callback",
+		HTTPClient:   srv.Client(),
+	})
+	return srv, p
+}
+
+// login проходит вход до получения ID-токена и возвращает его вместе с nonce.
+func login(t *testing.T, srv *oidctest.Server, p *Provider, claims jwt.MapClaims) (rawIDToken string, nonce string) {
+	t.Helper()
+	ctx := context.Background()
+	nonce, verifier := mustRandom(t), mustRandom(t)
+	authURL, err := p.AuthCodeURL(ctx, "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	redirect, err := srv.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawIDToken, err = p.Exchange(ctx, redirect.Query().Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	return rawIDToken, nonce
+}
+
+func mustRandom(t *testing.T) string {
+	t.Helper()
+	s, err := RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	_, p := newTestProvider(t)
+	authURL, err := p.AuthCodeURL(context.Background(), "st", "nc", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "kanban",
+		"redirect_uri":          "https://kanban.example.com/api/v1/oidc/callback",
+		"scope":                 "openid email profile",
+		"state":                 "st",
+		"nonce":                 "nc",
+		"code_challenge":        CodeChallenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, ожидалось %q", k, q.Get(k), v)
+		}
+	}
+	if q.Get("code_challenge") == "verifier" {
+		t.Error("code_verifier передан провайдеру открыто")
+	}
+}
+
+func TestCodeChallengeRFC7636(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	if got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %s", got)
+	}
+}
+
+func TestLoginFlow(t *testing.T) {
+	srv, p := newTestProvider(t)
+	claims := srv.Claims("user-1")
+	claims["email"] = "ivan@example.com"
+	claims["email_verified"] = "true"
+	claims["preferred_username"] = "ivan"
+	claims["amr"] = []string{"pwd", "otp"}
+	raw, nonce := login(t, srv, p, claims)
+
+	got, err := p.VerifyIDToken(context.Background(), raw, nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if got.Subject != "user-1" || got.Email != "ivan@example.com" || !bool(got.EmailVerified) || got.PreferredUsername != "ivan" {
+		t.Errorf("утверждения %+v", got)
+	}
+	if !got.MultiFactor(nil) {
+		t.Error("amr с otp не считается входом с MFA")
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	srv, p := newTestProvider(t)
+	ctx := context.Background()
+	authURL, err := p.AuthCodeURL(ctx, "st", "nc", "right-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect, err := srv.Authorize(authURL, srv.Claims("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := redirect.Query().Get("code")
+	if _, err := p.Exchange(ctx, code, "wrong-verifier"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("обмен с чужим code_verifier: %v, ожидался отказ invalid_grant", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsNonce(t *testing.T) {
+	srv, p := newTestProvider(t)
+	raw, _ := login(t, srv, p, srv.Claims("user-1"))
+	if _, err := p.VerifyIDToken(context.Background(), raw, "other-nonce"); err == nil {
+		t.Error("принят ID-токен с чужим nonce")
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidClaims(t *testing.T) {
+	srv, p := newTestProvider(t)
+	cases := map[string]func(jwt.MapClaims){
+		"чужой issuer":       func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"чужая audience":     func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"истек":              func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"без срока":          func(c jwt.MapClaims) { delete(c, "exp") },
+		"без sub":            func(c jwt.MapClaims) { delete(c, "sub") },
+		"чужой azp":          func(c jwt.MapClaims) { c["aud"] = []string{"kanban", "other"}; c["azp"] = "other" },
+		"выдан в будущем":    func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		"nonce не совпадает": func(c jwt.MapClaims) { c["nonce"] = "other" },
+	}
+	for name, mutate := range cases {
+		claims := srv.Claims("user-1")
+		claims["nonce"] = "nc"
+		mutate(claims)
+		if _, err := p.VerifyIDToken(context.Background(), srv.Sign(claims), "nc"); err == nil {
+			t.Errorf("%s: ID-токен принят", name)
+		}
+	}
+}
+
+func TestVerifyIDTokenRejectsSymmetricSignature(t *testing.T) {
+	srv, p := newTestProvider(t)
+	claims := srv.Claims("user-1")
+	claims["nonce"] = "nc"
+	// Токен, подписанный HS256 известным клиенту секретом, не должен приниматься
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(srv.ClientSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.VerifyIDToken(context.Background(), raw, "nc"); err == nil {
+		t.Error("принят ID-токен с подписью HS256")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	srv, p := newTestProvider(t)
+	ctx := context.Background()
+	raw, nonce := login(t, srv, p, srv.Claims("user-1"))
+	if _, err := p.VerifyIDToken(ctx, raw, nonce); err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if n := srv.JWKSFetches(); n != 1 {
+		t.Fatalf("JWKS загружен %d раз, ожидался 1", n)
+	}
+
+	// Провайдер сменил ключ: токен с новым kid не принимается, пока не прошел minKeyRefresh,
+	// чтобы токены с произвольным kid не заставляли загружать JWKS на каждый запрос
+	srv.RotateKey(false)
+	raw, nonce = login(t, srv, p, srv.Claims("user-1"))
+	if _, err := p.VerifyIDToken(ctx, raw, nonce); err == nil {
+		t.Fatal("токен с новым kid принят без загрузки ключей")
+	}
+	if n := srv.JWKSFetches(); n != 1 {
+		t.Fatalf("JWKS загружен %d раз до истечения minKeyRefresh", n)
+	}
+
+	p.keys.mu.Lock()
+	p.keys.fetchedAt = time.Now().Add(-minKeyRefresh)
+	p.keys.mu.Unlock()
+	if _, err := p.VerifyIDToken(ctx, raw, nonce); err != nil {
+		t.Fatalf("токен с новым kid не принят после перезагрузки ключей: %v", err)
+	}
+	if n := srv.JWKSFetches(); n != 2 {
+		t.Errorf("JWKS загружен %d раз, ожидалось 2", n)
+	}
+}
+
+func TestMultiFactor(t *testing.T) {
+	cases := []struct {
+		amr  []string
+		acr  string
+		want bool
+	}{
+		{nil, "", false},
+		{[]string{"pwd"}, "", false},
+		{[]string{"pwd", "otp"}, "", true},
+		{[]string{"mfa"}, "", true},
+		{[]string{"hwk", "fpt"}, "", true},
+		// Один способ входа - один фактор, сколько бы надежным он ни был
+		{[]string{"otp"}, "", false},
+		{[]string{"hwk"}, "", false},
+		{[]string{"face"}, "", false},
+		// Несколько способов одного фактора - тоже один фактор
+		{[]string{"pwd", "pin"}, "", false},
+		{[]string{"otp", "sms"}, "", false},
+		{[]string{"pwd"}, "urn:example:mfa", true},
+		{[]string{"pwd"}, "urn:example:basic", false},
+	}
+	for _, c := range cases {
+		claims := &Claims{AuthMethods: c.amr, AuthContext: c.acr}
+		if got := claims.MultiFactor([]string{"urn:example:mfa"}); got != c.want {
+			t.Errorf("amr %v, acr %q: MultiFactor = %v, ожидалось %v", c.amr, c.acr, got, c.want)
+		}
+	}
+}
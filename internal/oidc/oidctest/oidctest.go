@@ -0,0 +1,198 @@
+// Package oidctest - локальный провайдер OpenID Connect для тестов входа через провайдера.
+// Провайдер выдает коды авторизации, проверяет PKCE при обмене кода, подписывает ID-токены
+// текущим ключом RSA и публикует ключи в JWKS; ключ можно сменить через RotateKey.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Server - тестовый провайдер.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	kid         string
+	keySeq      int
+	codes       map[string]grant
+	jwksFetches int
+}
+
+// grant - выданный код авторизации и данные, с которыми его можно обменять.
+type grant struct {
+	redirectURI string
+	challenge   string
+	claims      jwt.MapClaims
+}
+
+// NewServer запускает провайдера с одним ключом подписи.
+func NewServer() *Server {
+	s := &Server{ClientID: "kanban", ClientSecret: "client-secret", keys: map[string]*rsa.PrivateKey{}, codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	s.RotateKey(false)
+	return s
+}
+
+// RotateKey создает новый ключ и подписывает им следующие токены. keepOld оставляет
+// прежние ключи в JWKS, как делают провайдеры в период смены ключей.
+func (s *Server) RotateKey(keepOld bool) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !keepOld {
+		s.keys = map[string]*rsa.PrivateKey{}
+	}
+	s.keySeq++
+	s.kid = "key-" + strconv.Itoa(s.keySeq)
+	s.keys[s.kid] = key
+	return s.kid
+}
+
+// JWKSFetches возвращает, сколько раз загружались ключи.
+func (s *Server) JWKSFetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksFetches
+}
+
+// Claims возвращает стандартные утверждения ID-токена для subject, действующего час.
+func (s *Server) Claims(subject string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": s.URL,
+		"sub": subject,
+		"aud": s.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+// Authorize имитирует вход пользователя на странице провайдера: принимает адрес из
+// Provider.AuthCodeURL и возвращает адрес возврата с кодом и state. Nonce из запроса
+// добавляется в claims ID-токена, который будет выдан за этот код.
+func (s *Server) Authorize(authURL string, claims jwt.MapClaims) (*url.URL, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		return nil, err
+	}
+	if claims["nonce"] == nil {
+		claims["nonce"] = q.Get("nonce")
+	}
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = grant{redirectURI: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), claims: claims}
+	s.mu.Unlock()
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	return redirect, nil
+}
+
+// Sign подписывает claims текущим ключом.
+func (s *Server) Sign(claims jwt.MapClaims) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signLocked(claims)
+}
+
+func (s *Server) signLocked(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	raw, err := token.SignedString(s.keys[s.kid])
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                           s.URL,
+		"authorization_endpoint":           s.URL + "/authorize",
+		"token_endpoint":                   s.URL + "/token",
+		"jwks_uri":                         s.URL + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwksFetches++
+	keys := []map[string]string{}
+	for kid, key := range s.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// token обменивает код на ID-токен. Код одноразовый; code_verifier должен соответствовать
+// code_challenge из запроса авторизации (RFC 7636).
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if id, secret, ok := r.BasicAuth(); !ok || id != s.ClientID || secret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := r.PostForm.Get("code")
+	g, ok := s.codes[code]
+	delete(s.codes, code)
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": s.signLocked(g.claims), "token_type": "Bearer"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,122 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethods - допустимые алгоритмы подписи ID-токена. Симметричные алгоритмы и none
+// не принимаются, чтобы токен нельзя было подписать публичным ключом или не подписывать вовсе.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// clockSkew - допустимое расхождение часов с провайдером.
+const clockSkew = time.Minute
+
+// Claims - утверждения ID-токена, нужные для входа.
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce             string       `json:"nonce"`
+	AuthorizedParty   string       `json:"azp"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	PreferredUsername string       `json:"preferred_username"`
+	Name              string       `json:"name"`
+	// AuthMethods - способы аутентификации у провайдера (RFC 8176)
+	AuthMethods []string `json:"amr"`
+	// AuthContext - класс аутентификации, значения определяет провайдер
+	AuthContext string `json:"acr"`
+}
+
+// authFactors - к какому фактору относятся значения amr из RFC 8176: знание, владение
+// или биометрия. Каждое значение называет один способ входа, поэтому ни одно из них
+// само по себе не доказывает второй фактор: вход только по ключу (hwk) или только
+// по коду из письма (otp) - однофакторный.
+var authFactors = map[string]string{
+	"pwd": "knowledge", "pin": "knowledge", "kba": "knowledge",
+	"otp": "possession", "hwk": "possession", "swk": "possession", "sc": "possession",
+	"sms": "possession", "tel": "possession",
+	"fpt": "inherence", "face": "inherence", "iris": "inherence", "retina": "inherence", "vbm": "inherence",
+}
+
+// MultiFactor сообщает, подтвердил ли провайдер вход несколькими факторами: amr содержит "mfa"
+// или способы хотя бы двух разных факторов, либо acr входит в mfaContexts - классы,
+// которые провайдер выдает только после MFA.
+func (c *Claims) MultiFactor(mfaContexts []string) bool {
+	factors := map[string]bool{}
+	for _, m := range c.AuthMethods {
+		if m == "mfa" {
+			return true
+		}
+		if f, ok := authFactors[m]; ok {
+			factors[f] = true
+		}
+	}
+	if len(factors) >= 2 {
+		return true
+	}
+	if c.AuthContext != "" {
+		for _, acr := range mfaContexts {
+			if c.AuthContext == acr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flexibleBool принимает и true, и "true": некоторые провайдеры передают email_verified строкой.
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = flexibleBool(v)
+	case string:
+		*b = v == "true"
+	}
+	return nil
+}
+
+// VerifyIDToken проверяет подпись ID-токена по JWKS провайдера, issuer, audience, срок действия
+// и nonce из начала входа (OpenID Connect Core 1.0, 3.1.3.7).
+func (p *Provider) VerifyIDToken(ctx context.Context, raw string, nonce string) (*Claims, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID-токен: %w", err)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, errors.New("неверный ID-токен: azp не совпадает с client_id")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("неверный ID-токен: nonce не совпадает")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("неверный ID-токен: нет sub")
+	}
+	return claims, nil
+}
@@ -0,0 +1,250 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+	"kanban-backend/internal/webhook"
+)
+
+const (
+	// MaxDepth - сколько правил может сработать подряд, когда действие одного правила
+	// вызывает событие, на которое срабатывает следующее.
+	MaxDepth = 3
+
+	queueSize    = 1000
+	dueBatchSize = 100
+
+	// RuleHeader содержит ID правила в запросах действия webhook.
+	RuleHeader = "X-Kanban-Rule"
+)
+
+type chainKey struct{}
+
+// withChain запоминает в контексте цепочку правил, действия которых привели к изменению.
+func withChain(ctx context.Context, chain []int) context.Context {
+	return context.WithValue(ctx, chainKey{}, chain)
+}
+
+func chainFrom(ctx context.Context) []int {
+	chain, _ := ctx.Value(chainKey{}).([]int)
+	return chain
+}
+
+func inChain(chain []int, ruleID int) bool {
+	for _, id := range chain {
+		if id == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+type job struct {
+	event events.Event
+	chain []int
+}
+
+// Engine проверяет правила на событиях задач и выполняет их действия.
+// Engine подключается к потоку событий как events.Publisher; события обрабатываются
+// в фоне в той же реплике, которая их опубликовала, поэтому каждое событие
+// обрабатывается ровно одной репликой.
+type Engine struct {
+	Rules  storage.RuleStore
+	Tasks  storage.TaskStore
+	Client *http.Client
+
+	queue chan job
+}
+
+// NewEngine создает новый экземпляр Engine.
+func NewEngine(rules storage.RuleStore, tasks storage.TaskStore) *Engine {
+	return &Engine{
+		Rules:  rules,
+		Tasks:  tasks,
+		Client: webhook.NewClient(10 * time.Second),
+		queue:  make(chan job, queueSize),
+	}
+}
+
+// Publish ставит событие в очередь на проверку правил.
+// Контекст события несет цепочку правил, если событие вызвано действием правила.
+func (e *Engine) Publish(ctx context.Context, ev events.Event) error {
+	select {
+	case e.queue <- job{event: ev, chain: chainFrom(ctx)}:
+		return nil
+	default:
+		return errors.New("очередь правил переполнена, событие пропущено")
+	}
+}
+
+// Run обрабатывает очередь событий до отмены ctx.
+func (e *Engine) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-e.queue:
+			e.handleEvent(ctx, j)
+		}
+	}
+}
+
+func (e *Engine) handleEvent(ctx context.Context, j job) {
+	rules, err := e.Rules.GetEnabledRules(ctx, j.event.UserID, models.RuleTriggerEvent)
+	if err != nil {
+		log.Printf("Правила: ошибка получения правил пользователя %d: %v", j.event.UserID, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	// Условия проверяются на состоянии задачи в момент события
+	var task models.Task
+	if err := json.Unmarshal(j.event.Data, &task); err != nil || task.ID == 0 {
+		log.Printf("Правила: событие %s задачи %d без данных задачи", j.event.Type, j.event.TaskID)
+		return
+	}
+	for _, rule := range rules {
+		if len(rule.Trigger.Events) > 0 && !contains(rule.Trigger.Events, j.event.Type) {
+			continue
+		}
+		e.execute(ctx, rule, &task, j.event.Type, j.chain)
+	}
+}
+
+// RunDue выполняет правила due_passed для задач, срок которых прошел.
+// Каждое правило срабатывает для задачи один раз; отметка о срабатывании ставится до проверки
+// условий, поэтому несколько реплик не выполнят правило дважды.
+// Возвращает количество проверенных пар правило-задача.
+func (e *Engine) RunDue(ctx context.Context) (int64, error) {
+	pairs, err := e.Rules.GetDueRuleTasks(ctx, time.Now(), dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, p := range pairs {
+		fired, err := e.Rules.MarkFired(ctx, p.Rule.ID, p.TaskID)
+		if err != nil {
+			return n, err
+		}
+		if !fired {
+			continue
+		}
+		n++
+		task, err := e.Tasks.GetTaskByID(ctx, p.TaskID, p.Rule.UserID)
+		if err != nil {
+			log.Printf("Правила: задача %d для правила %d: %v", p.TaskID, p.Rule.ID, err)
+			continue
+		}
+		e.execute(ctx, p.Rule, task, models.RuleTriggerDuePassed, nil)
+	}
+	return n, nil
+}
+
+// execute проверяет условия правила и выполняет действия, записывая результат в журнал правила.
+func (e *Engine) execute(ctx context.Context, rule models.Rule, task *models.Task, trigger string, chain []int) {
+	if !Matches(rule.Conditions, task, time.Now()) {
+		return
+	}
+	exec := &models.RuleExecution{RuleID: rule.ID, TaskID: task.ID, Trigger: trigger, Status: models.RuleExecutionSucceeded}
+	switch {
+	case inChain(chain, rule.ID):
+		exec.Status = models.RuleExecutionSkipped
+		exec.Error = "правило уже сработало в этой цепочке событий"
+	case len(chain) >= MaxDepth:
+		exec.Status = models.RuleExecutionSkipped
+		exec.Error = fmt.Sprintf("превышена глубина цепочки правил (%d)", MaxDepth)
+	default:
+		actx := withChain(ctx, append(append([]int(nil), chain...), rule.ID))
+		for _, action := range rule.Actions {
+			if err := e.apply(actx, rule, action, task, trigger); err != nil {
+				exec.Status = models.RuleExecutionFailed
+				exec.Error = fmt.Sprintf("%s: %v", action.Type, err)
+				break
+			}
+			exec.Actions = append(exec.Actions, action.Type)
+		}
+	}
+	if err := e.Rules.AppendExecution(ctx, exec); err != nil {
+		log.Printf("Правила: %v", err)
+	}
+}
+
+func (e *Engine) apply(ctx context.Context, rule models.Rule, action models.RuleAction, task *models.Task, trigger string) error {
+	switch action.Type {
+	case ActionSetStatus:
+		current, err := e.Tasks.GetTaskByID(ctx, task.ID, rule.UserID)
+		if err != nil {
+			return err
+		}
+		if current.Status == action.Value {
+			return nil
+		}
+		_, err = e.Tasks.UpdateTask(ctx, current.ID, rule.UserID, current.Version, models.TaskUpdatePayload{
+			Title:        current.Title,
+			Description:  current.Description,
+			Status:       action.Value,
+			DueAt:        current.DueAt,
+			RRule:        current.RRule,
+			Estimate:     current.Estimate,
+			CustomFields: current.CustomFields,
+		})
+		return err
+	case ActionArchive:
+		_, err := e.Tasks.ArchiveTask(ctx, task.ID, rule.UserID, 0)
+		return err
+	case ActionUnarchive:
+		_, err := e.Tasks.UnarchiveTask(ctx, task.ID, rule.UserID, 0)
+		return err
+	case ActionDelete:
+		return e.Tasks.DeleteTask(ctx, task.ID, rule.UserID, 0)
+	case ActionWebhook:
+		return e.post(ctx, rule, action, task, trigger)
+	}
+	return fmt.Errorf("неизвестное действие %q", action.Type)
+}
+
+// post отправляет задачу на URL действия webhook. Повторных попыток нет:
+// для надежной доставки служат подписки webhook.
+func (e *Engine) post(ctx context.Context, rule models.Rule, action models.RuleAction, task *models.Task, trigger string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"rule_id": rule.ID,
+		"rule":    rule.Name,
+		"trigger": trigger,
+		"task":    task,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kanban-backend-rules")
+	req.Header.Set(RuleHeader, fmt.Sprint(rule.ID))
+	if action.Secret != "" {
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(action.Secret, body))
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("получатель ответил %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package rules выполняет правила автоматизации задач: проверяет условия
+// на событиях задач и по наступлению сроков и выполняет действия.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/webhook"
+)
+
+// Действия правил.
+const (
+	ActionSetStatus = "set_status"
+	ActionArchive   = "archive"
+	ActionUnarchive = "unarchive"
+	ActionDelete    = "delete"
+	ActionWebhook   = "webhook"
+)
+
+const maxConditions, maxActions = 20, 10
+
+var conditionOps = map[string][]string{
+	"title":       {"eq", "neq", "contains"},
+	"description": {"eq", "neq", "contains", "is_set", "is_not_set"},
+	"status":      {"eq", "neq", "contains"},
+	"due_at":      {"is_set", "is_not_set", "before_now", "after_now"},
+	"archived":    {"is_set", "is_not_set"},
+}
+
+// Validate проверяет правило перед сохранением. Адреса действий webhook проверяются
+// на принадлежность внутренней сети, для этого разрешаются их имена.
+func Validate(ctx context.Context, rule *models.Rule) error {
+	name := strings.TrimSpace(rule.Name)
+	if name == "" {
+		return errors.New("название правила не может быть пустым")
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		return errors.New("название правила длиннее 255 символов")
+	}
+	switch rule.Trigger.Type {
+	case models.RuleTriggerEvent:
+		for _, t := range rule.Trigger.Events {
+			if !events.IsKnownType(t) {
+				return fmt.Errorf("неизвестный тип события: %s", t)
+			}
+		}
+	case models.RuleTriggerDuePassed:
+		if len(rule.Trigger.Events) > 0 {
+			return errors.New("события указываются только для триггера event")
+		}
+	default:
+		return errors.New("тип триггера должен быть event или due_passed")
+	}
+
+	if len(rule.Conditions) > maxConditions {
+		return fmt.Errorf("не больше %d условий", maxConditions)
+	}
+	for i, c := range rule.Conditions {
+		ops, ok := conditionOps[c.Field]
+		if !ok {
+			return fmt.Errorf("условие %d: неизвестное поле %q", i+1, c.Field)
+		}
+		if !contains(ops, c.Op) {
+			return fmt.Errorf("условие %d: оператор %q не поддерживается для поля %s", i+1, c.Op, c.Field)
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		return errors.New("нужно хотя бы одно действие")
+	}
+	if len(rule.Actions) > maxActions {
+		return fmt.Errorf("не больше %d действий", maxActions)
+	}
+	for i, a := range rule.Actions {
+		switch a.Type {
+		case ActionSetStatus:
+			if v := strings.TrimSpace(a.Value); v == "" || utf8.RuneCountInString(v) > 50 {
+				return fmt.Errorf("действие %d: статус должен быть непустым и не длиннее 50 символов", i+1)
+			}
+		case ActionArchive, ActionUnarchive, ActionDelete:
+		case ActionWebhook:
+			if _, err := webhook.CheckURL(ctx, a.URL); err != nil {
+				return fmt.Errorf("действие %d: %w", i+1, err)
+			}
+		default:
+			return fmt.Errorf("действие %d: неизвестный тип %q", i+1, a.Type)
+		}
+	}
+	return nil
+}
+
+// Matches сообщает, выполняются ли все условия правила для задачи.
+func Matches(conditions []models.RuleCondition, task *models.Task, now time.Time) bool {
+	for _, c := range conditions {
+		if !matches(c, task, now) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(c models.RuleCondition, task *models.Task, now time.Time) bool {
+	switch c.Field {
+	case "title":
+		return matchString(c, task.Title)
+	case "description":
+		return matchString(c, task.Description)
+	case "status":
+		return matchString(c, task.Status)
+	case "due_at":
+		return matchTime(c, task.DueAt, now)
+	case "archived":
+		return matchTime(c, task.ArchivedAt, now)
+	}
+	return false
+}
+
+func matchString(c models.RuleCondition, v string) bool {
+	switch c.Op {
+	case "eq":
+		return v == c.Value
+	case "neq":
+		return v != c.Value
+	case "contains":
+		return strings.Contains(strings.ToLower(v), strings.ToLower(c.Value))
+	case "is_set":
+		return v != ""
+	case "is_not_set":
+		return v == ""
+	}
+	return false
+}
+
+func matchTime(c models.RuleCondition, v *time.Time, now time.Time) bool {
+	switch c.Op {
+	case "is_set":
+		return v != nil
+	case "is_not_set":
+		return v == nil
+	case "before_now":
+		return v != nil && v.Before(now)
+	case "after_now":
+		return v != nil && v.After(now)
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+var (
+	// ErrTokenInvalid возвращается, если токен из письма не найден, уже использован, истек
+	// или адрес пользователя изменился после отправки письма.
+	ErrTokenInvalid = errors.New("токен недействителен или истек")
+	// ErrTokenThrottled возвращается, если письмо того же назначения отправлено слишком недавно.
+	ErrTokenThrottled = errors.New("письмо уже отправлено, повторите позже")
+)
+
+// AccountStore хранит одноразовые токены подтверждения адреса и сброса пароля
+// и версии сессий пользователей.
+type AccountStore interface {
+	// GetAccount возвращает пользователя с адресом и отметкой о его подтверждении.
+	GetAccount(ctx context.Context, userID int) (*models.User, error)
+	// GetUserByVerifiedEmail ищет пользователя по подтвержденному адресу без учета регистра.
+	GetUserByVerifiedEmail(ctx context.Context, email string) (*models.User, error)
+	// IssueAccountToken в одной транзакции заменяет прежние токены пользователя того же назначения
+	// новым и ставит письмо со ссылкой в очередь. Если предыдущий токен выдан меньше чем
+	// minInterval назад, возвращает ErrTokenThrottled.
+	IssueAccountToken(ctx context.Context, token *models.AccountToken, mail *models.OutboxMail, minInterval time.Duration) error
+	// VerifyEmail использует токен подтверждения и отмечает адрес подтвержденным.
+	VerifyEmail(ctx context.Context, tokenHash string) (*models.User, error)
+	// ResetPassword использует токен сброса, меняет пароль и отзывает все выданные JWT.
+	ResetPassword(ctx context.Context, tokenHash string, password string) (*models.User, error)
+	// SessionVersion возвращает текущую версию сессий пользователя для проверки JWT.
+	SessionVersion(ctx context.Context, userID int) (int, error)
+	// PurgeExpiredTokens удаляет истекшие токены и возвращает их количество.
+	PurgeExpiredTokens(ctx context.Context) (int64, error)
+}
+
+// StreamTicketStore хранит одноразовые билеты для подключения к потокам событий.
+// Браузер не может передать заголовок Authorization при открытии WebSocket или EventSource,
+// поэтому клиент получает билет обычным запросом с JWT и передает его в адресе.
+type StreamTicketStore interface {
+	// IssueStreamTicket сохраняет хеш билета пользователя.
+	IssueStreamTicket(ctx context.Context, ticketHash string, userID int, expiresAt time.Time) error
+	// UseStreamTicket удаляет действующий билет и возвращает его пользователя.
+	// Если билет не найден, уже использован или истек, возвращает ErrTokenInvalid.
+	UseStreamTicket(ctx context.Context, ticketHash string) (int, error)
+}
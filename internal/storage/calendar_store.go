@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"context"
+
+	"kanban-backend/internal/models"
+)
+
+// CalendarTokenStore хранит токены доступа к календарным лентам.
+type CalendarTokenStore interface {
+	// CreateToken сохраняет хеш token.Token.
+	CreateToken(ctx context.Context, token *models.CalendarToken) (int, error)
+	GetTokens(ctx context.Context, userID int) ([]models.CalendarToken, error)
+	DeleteToken(ctx context.Context, id int, userID int) error
+	// LookupToken возвращает ID владельца токена или ErrNotFound, если токен неизвестен или отозван.
+	LookupToken(ctx context.Context, token string) (int, error)
+}
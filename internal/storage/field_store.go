@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"kanban-backend/internal/models"
+)
+
+// ErrFieldExists возвращается при создании поля с уже занятым ключом.
+var ErrFieldExists = errors.New("поле с таким ключом уже существует")
+
+// FieldStore хранит определения пользовательских полей задач.
+type FieldStore interface {
+	CreateField(ctx context.Context, field *models.CustomField) error
+	GetFields(ctx context.Context, userID int) ([]models.CustomField, error)
+	GetField(ctx context.Context, id int, userID int) (*models.CustomField, error)
+	// UpdateField меняет все свойства поля, кроме ключа и типа.
+	UpdateField(ctx context.Context, field *models.CustomField) error
+	// DeleteField удаляет поле вместе с его значениями у задач.
+	DeleteField(ctx context.Context, id int, userID int) error
+	// ExistingUserIDs возвращает те из ids, которым соответствуют пользователи.
+	ExistingUserIDs(ctx context.Context, ids []int) ([]int, error)
+}
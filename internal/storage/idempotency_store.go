@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"kanban-backend/internal/models"
+)
+
+// ErrIdempotencyKeyReused возвращается, если ключ идемпотентности уже использован с другим запросом.
+var ErrIdempotencyKeyReused = errors.New("ключ идемпотентности использован с другим запросом")
+
+// IdempotencyStore хранит ответы на запросы с ключом идемпотентности.
+type IdempotencyStore interface {
+	// Do выполняет fn не более одного раза для пары (userID, key).
+	// Параллельные вызовы с тем же ключом ждут завершения первого. Если ответ уже сохранен,
+	// он возвращается с replayed = true без вызова fn. Если fn вернула nil, ответ не сохраняется
+	// и повтор запроса выполнит fn снова.
+	Do(ctx context.Context, userID int, key string, fingerprint string, fn func() *models.StoredResponse) (resp *models.StoredResponse, replayed bool, err error)
+}
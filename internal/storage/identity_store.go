@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+
+	"kanban-backend/internal/models"
+)
+
+// IdentityStore связывает пользователей с учетными записями внешних провайдеров входа (OIDC).
+type IdentityStore interface {
+	// GetUserByIdentity возвращает пользователя, привязанного к учетной записи subject провайдера issuer.
+	GetUserByIdentity(ctx context.Context, issuer string, subject string) (*models.User, error)
+	// LinkIdentity привязывает учетную запись провайдера к существующему пользователю.
+	LinkIdentity(ctx context.Context, issuer string, subject string, userID int) error
+	// CreateUserWithIdentity создает пользователя без пароля и привязывает к нему учетную запись провайдера.
+	// Если имя занято, к нему добавляется номер; адрес, подтвержденный другим пользователем, не сохраняется.
+	CreateUserWithIdentity(ctx context.Context, user *models.User, issuer string, subject string) error
+}
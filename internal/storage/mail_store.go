@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// ErrEmailTaken возвращается, если почтовый адрес уже подтвержден другим пользователем.
+var ErrEmailTaken = errors.New("адрес уже подтвержден другим пользователем")
+
+// MailBuilder строит письма для получателя по его уведомлениям.
+type MailBuilder func(recipient models.MailRecipient, notifications []models.Notification) ([]models.OutboxMail, error)
+
+// MailStore хранит почтовые настройки пользователей и очередь писем.
+type MailStore interface {
+	GetMailSettings(ctx context.Context, userID int) (*models.MailSettings, error)
+	// UpdateMailSettings заменяет адрес и режимы отправки; типы, не указанные в настройках,
+	// возвращаются к режимам по умолчанию.
+	UpdateMailSettings(ctx context.Context, userID int, settings *models.MailSettings) error
+	// SetMailMode устанавливает режим отправки для перечисленных типов уведомлений.
+	SetMailMode(ctx context.Context, userID int, types []string, mode string) error
+
+	// QueueNotificationMail в одной транзакции выбирает еще не отправленные по почте уведомления,
+	// созданные до before, не более чем limit пользователей, у которых для типа уведомления
+	// выбран режим mode, строит письма через build и ставит их в очередь.
+	// Уведомления с режимом off и уведомления пользователей без подтвержденного адреса
+	// отмечаются как обработанные.
+	// Возвращает количество поставленных в очередь писем.
+	QueueNotificationMail(ctx context.Context, mode string, before time.Time, limit int, build MailBuilder) (int64, error)
+	// ClaimMail захватывает готовые к отправке письма на время lease.
+	ClaimMail(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMail, error)
+	// RecordMailAttempt сохраняет результат попытки отправки.
+	RecordMailAttempt(ctx context.Context, m *models.OutboxMail) error
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// NotificationStore хранит входящие уведомления пользователей.
+type NotificationStore interface {
+	// CreateNotification сохраняет уведомление. Возвращает false, если уведомление
+	// того же типа по той же задаче с тем же DedupKey уже было.
+	CreateNotification(ctx context.Context, n *models.Notification) (bool, error)
+	// GetNotifications возвращает уведомления от новых к старым, начиная с ID меньше before (0 - с самого нового).
+	GetNotifications(ctx context.Context, userID int, unreadOnly bool, before int64, limit int) (*models.NotificationPage, error)
+	MarkRead(ctx context.Context, id int64, userID int) error
+	// MarkAllRead отмечает прочитанными все уведомления и возвращает их количество.
+	MarkAllRead(ctx context.Context, userID int) (int64, error)
+	UnreadCount(ctx context.Context, userID int) (int, error)
+
+	// CountMentionsByActor возвращает, сколько уведомлений об упоминании создано
+	// по действиям actorID начиная с since, по каждому получателю.
+	CountMentionsByActor(ctx context.Context, actorID int, since time.Time) (map[int]int, error)
+
+	// GetUserIDsByUsernames возвращает ID существующих пользователей по именам.
+	GetUserIDsByUsernames(ctx context.Context, usernames []string) (map[string]int, error)
+	// GetDueSoonTasks возвращает незавершенные задачи со сроком в (now, now+window],
+	// о которых владелец еще не получал уведомление due_soon для текущего срока.
+	GetDueSoonTasks(ctx context.Context, now time.Time, window time.Duration, limit int) ([]models.Task, error)
+}
@@ -0,0 +1,268 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// AccountStore реализует storage.AccountStore для PostgreSQL.
+type AccountStore struct {
+	db *sql.DB
+}
+
+// NewAccountStore создает новый экземпляр AccountStore.
+func NewAccountStore(db *sql.DB) *AccountStore {
+	return &AccountStore{db: db}
+}
+
+// Migrate добавляет пользователям отметку о подтверждении адреса и версию сессий
+// и создает таблицу токенов из писем. Адреса, указанные раньше, считаются неподтвержденными.
+// Уникален только подтвержденный адрес: иначе любой мог бы занять чужой адрес, не подтверждая его.
+func (s *AccountStore) Migrate(ctx context.Context) error {
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+	DROP INDEX IF EXISTS idx_users_email;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_email ON users(lower(email)) WHERE email_verified_at IS NOT NULL;
+	CREATE TABLE IF NOT EXISTS account_tokens (
+		token_hash CHAR(64) PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		purpose VARCHAR(20) NOT NULL,
+		email VARCHAR(254) NOT NULL,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens(user_id, purpose);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+const accountColumns = `id, username, email, email_verified_at, token_version, created_at`
+
+func scanAccount(row *sql.Row) (*models.User, error) {
+	u := &models.User{}
+	var email sql.NullString
+	var verifiedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.Username, &email, &verifiedAt, &u.TokenVersion, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+	if email.Valid {
+		u.Email = &email.String
+	}
+	if verifiedAt.Valid {
+		u.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return u, nil
+}
+
+// GetAccount возвращает пользователя с адресом и отметкой о его подтверждении.
+func (s *AccountStore) GetAccount(ctx context.Context, userID int) (*models.User, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	u, err := scanAccount(s.db.QueryRowContext(getCtx, `SELECT `+accountColumns+` FROM users WHERE id = $1`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("пользователь %d: %w", userID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя %d: %w", userID, err)
+	}
+	return u, nil
+}
+
+// GetUserByVerifiedEmail ищет пользователя по подтвержденному адресу без учета регистра.
+func (s *AccountStore) GetUserByVerifiedEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `SELECT ` + accountColumns + ` FROM users WHERE lower(email) = lower($1) AND email_verified_at IS NOT NULL`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	u, err := scanAccount(s.db.QueryRowContext(getCtx, query, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("пользователь с адресом: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске пользователя по адресу: %w", err)
+	}
+	return u, nil
+}
+
+// IssueAccountToken сохраняет новый токен вместо прежних и ставит письмо в очередь.
+// Строка пользователя блокируется, чтобы параллельные запросы не обошли ограничение частоты.
+func (s *AccountStore) IssueAccountToken(ctx context.Context, token *models.AccountToken, mail *models.OutboxMail, minInterval time.Duration) error {
+	issueCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(issueCtx, s.db, func(tx *sql.Tx) error {
+		var id int
+		err := tx.QueryRowContext(issueCtx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, token.UserID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("пользователь %d: %w", token.UserID, storage.ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при получении пользователя %d: %w", token.UserID, err)
+		}
+		var recent bool
+		err = tx.QueryRowContext(issueCtx, `SELECT EXISTS (
+			SELECT 1 FROM account_tokens WHERE user_id = $1 AND purpose = $2 AND created_at > $3
+		)`, token.UserID, token.Purpose, time.Now().Add(-minInterval)).Scan(&recent)
+		if err != nil {
+			return fmt.Errorf("ошибка при проверке токенов пользователя %d: %w", token.UserID, err)
+		}
+		if recent {
+			return storage.ErrTokenThrottled
+		}
+		if _, err := tx.ExecContext(issueCtx, `DELETE FROM account_tokens WHERE user_id = $1 AND purpose = $2`,
+			token.UserID, token.Purpose); err != nil {
+			return fmt.Errorf("ошибка при удалении прежних токенов пользователя %d: %w", token.UserID, err)
+		}
+		query := `INSERT INTO account_tokens (token_hash, user_id, purpose, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING created_at`
+		err = tx.QueryRowContext(issueCtx, query, token.Hash, token.UserID, token.Purpose, token.Email, token.ExpiresAt).
+			Scan(&token.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении токена: %w", err)
+		}
+		return insertMail(issueCtx, tx, mail)
+	})
+}
+
+// useToken удаляет действующий токен и возвращает пользователя и адрес, на который он был отправлен.
+func useToken(ctx context.Context, tx *sql.Tx, tokenHash string, purpose string) (userID int, email string, err error) {
+	query := `DELETE FROM account_tokens WHERE token_hash = $1 AND purpose = $2 AND expires_at > now()
+	RETURNING user_id, email`
+	err = tx.QueryRowContext(ctx, query, tokenHash, purpose).Scan(&userID, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", storage.ErrTokenInvalid
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("ошибка при проверке токена: %w", err)
+	}
+	return userID, email, nil
+}
+
+// VerifyEmail отмечает адрес подтвержденным, если он не менялся после отправки письма.
+// Если тот же адрес уже подтвердил другой пользователь, возвращается storage.ErrEmailTaken.
+func (s *AccountStore) VerifyEmail(ctx context.Context, tokenHash string) (*models.User, error) {
+	verifyCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var u *models.User
+	err := withTx(verifyCtx, s.db, func(tx *sql.Tx) error {
+		userID, email, err := useToken(verifyCtx, tx, tokenHash, models.AccountTokenVerifyEmail)
+		if err != nil {
+			return err
+		}
+		query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND lower(email) = lower($2) RETURNING ` + accountColumns
+		u, err = scanAccount(tx.QueryRowContext(verifyCtx, query, userID, email))
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrTokenInvalid
+		}
+		if isUniqueViolation(err) {
+			return storage.ErrEmailTaken
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при подтверждении адреса пользователя %d: %w", userID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ResetPassword устанавливает новый пароль и увеличивает версию сессий,
+// после чего JWT, выданные до сброса, перестают приниматься.
+func (s *AccountStore) ResetPassword(ctx context.Context, tokenHash string, password string) (*models.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("bcrypt error: %w", err)
+	}
+	resetCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var u *models.User
+	err = withTx(resetCtx, s.db, func(tx *sql.Tx) error {
+		userID, email, err := useToken(resetCtx, tx, tokenHash, models.AccountTokenResetPassword)
+		if err != nil {
+			return err
+		}
+		query := `UPDATE users SET password_hash = $3, token_version = token_version + 1
+		WHERE id = $1 AND lower(email) = lower($2) AND email_verified_at IS NOT NULL RETURNING ` + accountColumns
+		u, err = scanAccount(tx.QueryRowContext(resetCtx, query, userID, email, string(hash)))
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrTokenInvalid
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при смене пароля пользователя %d: %w", userID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SessionVersion возвращает текущую версию сессий пользователя.
+func (s *AccountStore) SessionVersion(ctx context.Context, userID int) (int, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var version int
+	err := s.db.QueryRowContext(getCtx, `SELECT token_version FROM users WHERE id = $1`, userID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("пользователь %d: %w", userID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении версии сессий пользователя %d: %w", userID, err)
+	}
+	return version, nil
+}
+
+// streamTicketPurpose - назначение билетов для подключения к потокам событий.
+// Билеты хранятся вместе с токенами из писем и удаляются вместе с ними после истечения.
+const streamTicketPurpose = "stream_ticket"
+
+// IssueStreamTicket сохраняет хеш билета. В отличие от токенов из писем прежние билеты
+// не отзываются: каждая вкладка клиента получает свой.
+func (s *AccountStore) IssueStreamTicket(ctx context.Context, ticketHash string, userID int, expiresAt time.Time) error {
+	issueCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	query := `INSERT INTO account_tokens (token_hash, user_id, purpose, email, expires_at) VALUES ($1, $2, $3, '', $4)`
+	if _, err := s.db.ExecContext(issueCtx, query, ticketHash, userID, streamTicketPurpose, expiresAt); err != nil {
+		return fmt.Errorf("ошибка при выдаче билета пользователю %d: %w", userID, err)
+	}
+	return nil
+}
+
+// UseStreamTicket удаляет действующий билет и возвращает его пользователя.
+func (s *AccountStore) UseStreamTicket(ctx context.Context, ticketHash string) (int, error) {
+	useCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	query := `DELETE FROM account_tokens WHERE token_hash = $1 AND purpose = $2 AND expires_at > now()
+	RETURNING user_id`
+	var userID int
+	err := s.db.QueryRowContext(useCtx, query, ticketHash, streamTicketPurpose).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrTokenInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при проверке билета: %w", err)
+	}
+	return userID, nil
+}
+
+// PurgeExpiredTokens удаляет истекшие токены из писем и билеты.
+func (s *AccountStore) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	purgeCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := s.db.ExecContext(purgeCtx, `DELETE FROM account_tokens WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении истекших токенов: %w", err)
+	}
+	return result.RowsAffected()
+}
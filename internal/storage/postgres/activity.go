@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// execQuerier - общее подмножество *sql.DB и *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// withTx выполняет fn в транзакции: коммитит при успехе и откатывает при ошибке.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// taskDiff возвращает значения полей задачи, которые отличаются между before и after.
+// Для создания before равен nil, для удаления after равен nil - тогда в diff попадают все поля.
+func taskDiff(before, after *models.Task) (json.RawMessage, json.RawMessage, error) {
+	b, err := taskFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := taskFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b != nil && a != nil {
+		for k, v := range b {
+			if reflect.DeepEqual(v, a[k]) {
+				delete(b, k)
+				delete(a, k)
+			}
+		}
+	}
+	return marshalFields(b), marshalFields(a), nil
+}
+
+func taskFields(task *models.Task) (map[string]interface{}, error) {
+	if task == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации задачи: %w", err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("ошибка сериализации задачи: %w", err)
+	}
+	return fields, nil
+}
+
+func marshalFields(fields map[string]interface{}) json.RawMessage {
+	if fields == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(fields) // map из JSON всегда сериализуется обратно
+	return raw
+}
+
+// recordActivity добавляет запись в журнал изменений в рамках транзакции изменения задачи.
+func recordActivity(ctx context.Context, q execQuerier, eventType string, actorID int, before, after *models.Task) error {
+	beforeJSON, afterJSON, err := taskDiff(before, after)
+	if err != nil {
+		return err
+	}
+	task := after
+	if task == nil {
+		task = before
+	}
+	query := `INSERT INTO task_events (task_id, user_id, actor_id, type, before, after) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = q.ExecContext(ctx, query, task.ID, task.UserID, actorID, eventType, nullJSON(beforeJSON), nullJSON(afterJSON))
+	if err != nil {
+		return fmt.Errorf("ошибка записи в журнал изменений задачи %d: %w", task.ID, err)
+	}
+	if after != nil && (before == nil || before.Status != after.Status) {
+		return recordStatusChange(ctx, q, before, after)
+	}
+	return nil
+}
+
+func nullJSON(raw json.RawMessage) interface{} {
+	if raw == nil {
+		return nil
+	}
+	return string(raw)
+}
+
+// GetTaskActivity возвращает журнал изменений задачи, начиная с новых.
+// before - ID записи, с которой начинается следующая страница (0 - с самой новой).
+func (s *TaskStore) GetTaskActivity(ctx context.Context, taskID int, userID int, before int64, limit int) (*models.ActivityPage, error) {
+	query := `SELECT id, task_id, actor_id, type, before, after, created_at FROM task_events
+	WHERE task_id = $1 AND user_id = $2 AND ($3::bigint = 0 OR id < $3::bigint)
+	ORDER BY id DESC LIMIT $4`
+	return s.activityPage(ctx, query, limit, taskID, userID, before, limit+1)
+}
+
+// GetActivity возвращает журнал изменений всех задач пользователя, начиная с новых.
+func (s *TaskStore) GetActivity(ctx context.Context, userID int, before int64, limit int) (*models.ActivityPage, error) {
+	query := `SELECT id, task_id, actor_id, type, before, after, created_at FROM task_events
+	WHERE user_id = $1 AND ($2::bigint = 0 OR id < $2::bigint)
+	ORDER BY id DESC LIMIT $3`
+	return s.activityPage(ctx, query, limit, userID, before, limit+1)
+}
+
+// GetTaskModifiedTimes возвращает время последнего изменения каждой задачи пользователя по журналу.
+// Задачи, созданные до появления журнала, в результат не попадают.
+func (s *TaskStore) GetTaskModifiedTimes(ctx context.Context, userID int) (map[int]time.Time, error) {
+	query := `SELECT task_id, max(created_at) FROM task_events WHERE user_id = $1 GROUP BY task_id`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении времени изменения задач: %w", err)
+	}
+	defer rows.Close()
+	times := map[int]time.Time{}
+	for rows.Next() {
+		var id int
+		var t time.Time
+		if err := rows.Scan(&id, &t); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении времени изменения задачи: %w", err)
+		}
+		times[id] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении времени изменения задач: %w", err)
+	}
+	return times, nil
+}
+
+// activityPage читает на одну запись больше limit, чтобы понять, есть ли следующая страница.
+func (s *TaskStore) activityPage(ctx context.Context, query string, limit int, args ...interface{}) (*models.ActivityPage, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении журнала изменений: %w", err)
+	}
+	defer rows.Close()
+	page := &models.ActivityPage{Items: []models.TaskActivity{}}
+	for rows.Next() {
+		var a models.TaskActivity
+		var before, after []byte
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.ActorID, &a.Type, &before, &after, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи журнала: %w", err)
+		}
+		a.Before, a.After = before, after
+		page.Items = append(page.Items, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по журналу изменений: %w", err)
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		next := page.Items[limit-1].ID
+		page.NextBefore = &next
+	}
+	return page, nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// bulkChange возвращает тип события и изменение задачи для массовой операции.
+func bulkChange(op models.BulkOperation) (string, func(task *models.Task) error, error) {
+	switch op.Op {
+	case models.BulkOpUpdate:
+		if op.Fields == nil {
+			return "", nil, fmt.Errorf("для операции update нужны fields")
+		}
+		return events.TaskUpdated, updateTask(op.Fields.Title, op.Fields.Description, op.Fields.Status), nil
+	case models.BulkOpArchive:
+		return events.TaskArchived, archiveTask, nil
+	case models.BulkOpUnarchive:
+		return events.TaskUnarchived, unarchiveTask, nil
+	case models.BulkOpDelete:
+		return events.TaskDeleted, deleteTask, nil
+	case models.BulkOpRestore:
+		return events.TaskRestored, restoreTask, nil
+	}
+	return "", nil, fmt.Errorf("операция %q не поддерживается", op.Op)
+}
+
+// BulkChangeTasks применяет операции к задачам пользователя в одной транзакции.
+// Задачи других пользователей считаются не найденными.
+//
+// Без continueOnError первая ошибка в задаче отменяет все изменения: в ответе Committed = false,
+// ошибочная задача помечена как error, все остальные - как skipped.
+// С continueOnError каждая задача выполняется в своей точке сохранения, и ошибка
+// откатывает только ее. События публикуются после фиксации транзакции.
+func (s *TaskStore) BulkChangeTasks(ctx context.Context, userID int, ops []models.BulkOperation, continueOnError bool) (*models.BulkResponse, error) {
+	bulkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp := &models.BulkResponse{Results: []models.BulkItemResult{}}
+	type change struct {
+		eventType string
+		task      *models.Task
+	}
+	var changes []change
+	failed := false
+
+	err := withTx(bulkCtx, s.db, func(tx *sql.Tx) error {
+		for _, op := range ops {
+			eventType, fn, err := bulkChange(op)
+			if err != nil {
+				return err
+			}
+			for _, id := range op.TaskIDs {
+				result := models.BulkItemResult{Op: op.Op, TaskID: id}
+				if failed {
+					result.Status = models.BulkItemSkipped
+					resp.Results = append(resp.Results, result)
+					continue
+				}
+				if continueOnError {
+					if _, err := tx.ExecContext(bulkCtx, `SAVEPOINT bulk_item`); err != nil {
+						return fmt.Errorf("ошибка создания точки сохранения: %w", err)
+					}
+				}
+
+				task, changed, err := changeTaskTx(bulkCtx, tx, id, userID, 0, eventType, fn)
+				if err != nil {
+					result.Status = models.BulkItemError
+					if errors.Is(err, storage.ErrNotFound) {
+						result.Error = "задача не найдена"
+					} else {
+						log.Printf("Ошибка массовой операции %s над задачей %d: %v", op.Op, id, err)
+						result.Error = "внутренняя ошибка"
+					}
+					resp.Results = append(resp.Results, result)
+					if !continueOnError {
+						failed = true
+						continue
+					}
+					if _, err := tx.ExecContext(bulkCtx, `ROLLBACK TO SAVEPOINT bulk_item`); err != nil {
+						return fmt.Errorf("ошибка отката к точке сохранения: %w", err)
+					}
+					continue
+				}
+
+				if continueOnError {
+					if _, err := tx.ExecContext(bulkCtx, `RELEASE SAVEPOINT bulk_item`); err != nil {
+						return fmt.Errorf("ошибка освобождения точки сохранения: %w", err)
+					}
+				}
+				result.Status = models.BulkItemOK
+				result.Task = task
+				resp.Results = append(resp.Results, result)
+				if changed {
+					changes = append(changes, change{eventType: eventType, task: task})
+				}
+			}
+		}
+		if failed {
+			return errBulkAborted
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkAborted) {
+		for i := range resp.Results {
+			if resp.Results[i].Status == models.BulkItemOK {
+				resp.Results[i].Status = models.BulkItemSkipped
+				resp.Results[i].Task = nil
+			}
+		}
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Committed = true
+	for _, c := range changes {
+		s.publish(ctx, c.eventType, userID, c.task.ID, c.task)
+	}
+	return resp, nil
+}
+
+// errBulkAborted откатывает транзакцию массовой операции после ошибки в одной из задач.
+var errBulkAborted = errors.New("массовая операция отменена")
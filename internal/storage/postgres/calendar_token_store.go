@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// CalendarTokenStore реализует storage.CalendarTokenStore для PostgreSQL.
+type CalendarTokenStore struct {
+	db *sql.DB
+}
+
+// NewCalendarTokenStore создает новый экземпляр CalendarTokenStore.
+func NewCalendarTokenStore(db *sql.DB) *CalendarTokenStore {
+	return &CalendarTokenStore{db: db}
+}
+
+// Migrate создает таблицу токенов календарных лент, если она не существует.
+func (s *CalendarTokenStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS calendar_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS idx_calendar_tokens_user_id ON calendar_tokens(user_id);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// hashToken возвращает SHA-256 токена. Токены случайные и длинные, поэтому соль не нужна.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken сохраняет хеш нового токена.
+func (s *CalendarTokenStore) CreateToken(ctx context.Context, token *models.CalendarToken) (int, error) {
+	query := `INSERT INTO calendar_tokens (user_id, name, token_hash) VALUES ($1, $2, $3) RETURNING id, created_at`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.db.QueryRowContext(createCtx, query, token.UserID, token.Name, hashToken(token.Token)).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании токена календаря: %w", err)
+	}
+	return token.ID, nil
+}
+
+// GetTokens возвращает токены пользователя без секретов.
+func (s *CalendarTokenStore) GetTokens(ctx context.Context, userID int) ([]models.CalendarToken, error) {
+	query := `SELECT id, user_id, name, created_at, last_used_at FROM calendar_tokens WHERE user_id = $1 ORDER BY id`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении токенов календаря: %w", err)
+	}
+	defer rows.Close()
+	tokens := []models.CalendarToken{}
+	for rows.Next() {
+		var t models.CalendarToken
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования токена календаря: %w", err)
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = &lastUsedAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по токенам календаря: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteToken отзывает токен.
+func (s *CalendarTokenStore) DeleteToken(ctx context.Context, id int, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(deleteCtx, `DELETE FROM calendar_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении токена календаря %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("токен календаря %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// LookupToken находит владельца токена и отмечает время последнего использования.
+func (s *CalendarTokenStore) LookupToken(ctx context.Context, token string) (int, error) {
+	query := `UPDATE calendar_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE token_hash = $1 RETURNING user_id`
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var userID int
+	err := s.db.QueryRowContext(lookupCtx, query, hashToken(token)).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("токен календаря: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при проверке токена календаря: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// statusCountsAt - количество задач в каждом статусе на конец дня (UTC) для дней из $2.
+// Статус задачи на конец дня - статус последнего перехода до начала следующего дня;
+// задачи, находившиеся в корзине на конец дня, не учитываются. Нахождение в корзине берется
+// из журнала изменений, а не из tasks, чтобы окончательное удаление задачи из корзины
+// не меняло данные за прошлые дни. $1 - user_id или NULL для всех пользователей.
+const statusCountsAt = `
+	SELECT d.day, s.user_id, s.to_status, COUNT(*)
+	FROM unnest($2::date[]) AS d(day)
+	JOIN LATERAL (
+		SELECT DISTINCT ON (c.task_id) c.task_id, c.user_id, c.to_status
+		FROM task_status_changes c
+		WHERE ($1::integer IS NULL OR c.user_id = $1)
+		AND c.changed_at < (d.day + 1)::timestamp AT TIME ZONE 'UTC'
+		ORDER BY c.task_id, c.changed_at DESC, c.id DESC
+	) s ON TRUE
+	WHERE NOT COALESCE((
+		SELECT e.after ? 'deleted_at' FROM task_events e
+		WHERE e.task_id = s.task_id AND (e.before ? 'deleted_at' OR e.after ? 'deleted_at')
+		AND e.created_at < (d.day + 1)::timestamp AT TIME ZONE 'UTC'
+		ORDER BY e.id DESC LIMIT 1
+	), FALSE)
+	GROUP BY d.day, s.user_id, s.to_status`
+
+// MigrateSnapshots создает таблицы ежедневных снимков количества задач по статусам.
+func (s *TaskStore) MigrateSnapshots(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS status_snapshot_days (
+		day DATE PRIMARY KEY,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS task_status_snapshots (
+		day DATE NOT NULL REFERENCES status_snapshot_days(day) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL,
+		status VARCHAR(50) NOT NULL,
+		count INTEGER NOT NULL,
+		PRIMARY KEY (user_id, day, status)
+	);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// SnapshotStatusCounts сохраняет количество задач по статусам на конец вчерашнего дня (UTC)
+// для всех пользователей. Снимок за день делается один раз: строка status_snapshot_days
+// вставляется в той же транзакции, и другая реплика, пытаясь вставить ее же, ничего не делает.
+// Возвращает количество сохраненных строк.
+func (s *TaskStore) SnapshotStatusCounts(ctx context.Context, now time.Time) (int64, error) {
+	day := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	snapshotCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	var n int64
+	err := withTx(snapshotCtx, s.db, func(tx *sql.Tx) error {
+		var inserted time.Time
+		err := tx.QueryRowContext(snapshotCtx, `INSERT INTO status_snapshot_days (day) VALUES ($1::date) ON CONFLICT DO NOTHING RETURNING day`,
+			day.Format("2006-01-02")).Scan(&inserted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при создании снимка за %s: %w", day.Format("2006-01-02"), err)
+		}
+		result, err := tx.ExecContext(snapshotCtx, `INSERT INTO task_status_snapshots (day, user_id, status, count) `+statusCountsAt,
+			nil, pq.Array([]string{day.Format("2006-01-02")}))
+		if err != nil {
+			return fmt.Errorf("ошибка при создании снимка за %s: %w", day.Format("2006-01-02"), err)
+		}
+		n, _ = result.RowsAffected()
+		return nil
+	})
+	return n, err
+}
+
+// GetStatusCounts возвращает количество задач пользователя по статусам на конец каждого дня
+// из [from, to]. Дни, для которых есть снимок, читаются из него, остальные считаются по истории статусов.
+// Дни без задач в результат не попадают.
+func (s *TaskStore) GetStatusCounts(ctx context.Context, userID int, from, to time.Time) ([]models.StatusCount, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	snapshotted := map[string]bool{}
+	rows, err := s.db.QueryContext(getCtx, `SELECT day FROM status_snapshot_days WHERE day BETWEEN $1::date AND $2::date`,
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении дней со снимками: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования дня снимка: %w", err)
+		}
+		snapshotted[day.Format("2006-01-02")] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по дням снимков: %w", err)
+	}
+	var live []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if day := d.Format("2006-01-02"); !snapshotted[day] {
+			live = append(live, day)
+		}
+	}
+
+	counts := []models.StatusCount{}
+	scan := func(query string, args ...interface{}) error {
+		rows, err := s.db.QueryContext(getCtx, query, args...)
+		if err != nil {
+			return fmt.Errorf("ошибка при подсчете задач по статусам: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var c models.StatusCount
+			var owner int
+			if err := rows.Scan(&c.Day, &owner, &c.Status, &c.Count); err != nil {
+				return fmt.Errorf("ошибка сканирования количества задач: %w", err)
+			}
+			counts = append(counts, c)
+		}
+		return rows.Err()
+	}
+	if len(snapshotted) > 0 {
+		err := scan(`SELECT day, user_id, status, count FROM task_status_snapshots
+		WHERE user_id = $1 AND day BETWEEN $2::date AND $3::date`, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(live) > 0 {
+		if err := scan(statusCountsAt, userID, pq.Array(live)); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/events"
+)
+
+// eventLogLimit - сколько последних событий хранится для каждого пользователя.
+const eventLogLimit = 1000
+
+// EventLogStore реализует events.Log для PostgreSQL.
+// Журнал ограничен eventLogLimit событиями на пользователя.
+type EventLogStore struct {
+	db *sql.DB
+}
+
+// NewEventLogStore создает новый экземпляр EventLogStore.
+func NewEventLogStore(db *sql.DB) *EventLogStore {
+	return &EventLogStore{db: db}
+}
+
+// Migrate создает таблицу журнала событий, если она не существует.
+func (s *EventLogStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS event_log (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(64) NOT NULL,
+		task_id INTEGER NOT NULL,
+		data JSONB,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_event_log_user_id ON event_log(user_id, id);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// Append сохраняет событие и удаляет вытесненные старые события пользователя.
+func (s *EventLogStore) Append(ctx context.Context, e *events.Event) error {
+	appendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var data interface{}
+	if len(e.Data) > 0 {
+		data = string(e.Data)
+	}
+	query := `INSERT INTO event_log (user_id, type, task_id, data, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := s.db.QueryRowContext(appendCtx, query, e.UserID, e.Type, e.TaskID, data, e.CreatedAt).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи события: %w", err)
+	}
+
+	pruneQuery := `
+	DELETE FROM event_log
+	WHERE user_id = $1 AND id <= (
+		SELECT id FROM event_log WHERE user_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1
+	)`
+	if _, err := s.db.ExecContext(appendCtx, pruneQuery, e.UserID, eventLogLimit); err != nil {
+		return fmt.Errorf("ошибка при очистке журнала событий: %w", err)
+	}
+	return nil
+}
+
+// Since возвращает события пользователя с ID больше lastID в порядке возрастания.
+func (s *EventLogStore) Since(ctx context.Context, userID int, lastID int64) ([]events.Event, bool, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Если журнал заполнен и самое старое событие новее lastID,
+	// часть событий между ними могла быть вытеснена
+	var oldestID sql.NullInt64
+	var total int
+	err := s.db.QueryRowContext(getCtx, `SELECT MIN(id), COUNT(*) FROM event_log WHERE user_id = $1`, userID).Scan(&oldestID, &total)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка при чтении журнала событий: %w", err)
+	}
+	complete := !(total >= eventLogLimit && oldestID.Valid && oldestID.Int64 > lastID)
+
+	query := `SELECT id, user_id, type, task_id, data, created_at FROM event_log WHERE user_id = $1 AND id > $2 ORDER BY id`
+	rows, err := s.db.QueryContext(getCtx, query, userID, lastID)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка при чтении журнала событий: %w", err)
+	}
+	defer rows.Close()
+	result := []events.Event{}
+	for rows.Next() {
+		var e events.Event
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.TaskID, &data, &e.CreatedAt); err != nil {
+			return nil, false, fmt.Errorf("ошибка сканирования события: %w", err)
+		}
+		e.Data = data
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("ошибка при итерации по журналу событий: %w", err)
+	}
+	return result, complete, nil
+}
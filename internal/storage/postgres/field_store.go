@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// FieldStore реализует storage.FieldStore для PostgreSQL.
+// Значения полей хранятся в tasks.custom_fields, поэтому удаление поля
+// меняет задачи через changeTaskTx с записью в журнал.
+type FieldStore struct {
+	db    *sql.DB
+	tasks *TaskStore
+}
+
+// NewFieldStore создает новый экземпляр FieldStore поверх хранилища задач.
+func NewFieldStore(tasks *TaskStore) *FieldStore {
+	return &FieldStore{db: tasks.DB(), tasks: tasks}
+}
+
+// Migrate создает таблицу определений полей, если она не существует.
+func (s *FieldStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS custom_fields (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		key VARCHAR(40) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		required BOOLEAN NOT NULL DEFAULT FALSE,
+		options TEXT[] NOT NULL DEFAULT '{}',
+		min DOUBLE PRECISION,
+		max DOUBLE PRECISION,
+		max_length INTEGER,
+		pattern TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, key)
+	);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+const fieldColumns = `id, user_id, key, name, type, required, options, min, max, max_length, pattern, created_at`
+
+func scanField(row interface{ Scan(...interface{}) error }) (*models.CustomField, error) {
+	field := &models.CustomField{}
+	var min, max sql.NullFloat64
+	var maxLength sql.NullInt64
+	if err := row.Scan(&field.ID, &field.UserID, &field.Key, &field.Name, &field.Type, &field.Required,
+		pq.Array(&field.Options), &min, &max, &maxLength, &field.Pattern, &field.CreatedAt); err != nil {
+		return nil, err
+	}
+	if min.Valid {
+		field.Min = &min.Float64
+	}
+	if max.Valid {
+		field.Max = &max.Float64
+	}
+	if maxLength.Valid {
+		n := int(maxLength.Int64)
+		field.MaxLength = &n
+	}
+	if len(field.Options) == 0 {
+		field.Options = nil
+	}
+	return field, nil
+}
+
+// CreateField добавляет определение поля.
+func (s *FieldStore) CreateField(ctx context.Context, field *models.CustomField) error {
+	query := `INSERT INTO custom_fields (user_id, key, name, type, required, options, min, max, max_length, pattern)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.db.QueryRowContext(createCtx, query, field.UserID, field.Key, field.Name, field.Type, field.Required,
+		pq.Array(nonNilStrings(field.Options)), field.Min, field.Max, field.MaxLength, field.Pattern).Scan(&field.ID, &field.CreatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("поле %s: %w", field.Key, storage.ErrFieldExists)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при создании поля: %w", err)
+	}
+	return nil
+}
+
+func nonNilStrings(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// GetFields возвращает определения полей пользователя в порядке создания.
+func (s *FieldStore) GetFields(ctx context.Context, userID int) ([]models.CustomField, error) {
+	query := `SELECT ` + fieldColumns + ` FROM custom_fields WHERE user_id = $1 ORDER BY id`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении полей: %w", err)
+	}
+	defer rows.Close()
+	fields := []models.CustomField{}
+	for rows.Next() {
+		field, err := scanField(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования поля: %w", err)
+		}
+		fields = append(fields, *field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по полям: %w", err)
+	}
+	return fields, nil
+}
+
+// GetField возвращает определение поля пользователя по ID.
+func (s *FieldStore) GetField(ctx context.Context, id int, userID int) (*models.CustomField, error) {
+	query := `SELECT ` + fieldColumns + ` FROM custom_fields WHERE id = $1 AND user_id = $2`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	field, err := scanField(s.db.QueryRowContext(getCtx, query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("поле %d: %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении поля %d: %w", id, err)
+	}
+	return field, nil
+}
+
+// UpdateField меняет свойства поля, кроме ключа и типа. Уже сохраненные
+// значения не перепроверяются: новые правила действуют при следующем изменении задачи.
+func (s *FieldStore) UpdateField(ctx context.Context, field *models.CustomField) error {
+	query := `UPDATE custom_fields SET name = $3, required = $4, options = $5, min = $6, max = $7, max_length = $8, pattern = $9
+	WHERE id = $1 AND user_id = $2 RETURNING ` + fieldColumns
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	updated, err := scanField(s.db.QueryRowContext(updateCtx, query, field.ID, field.UserID, field.Name, field.Required,
+		pq.Array(nonNilStrings(field.Options)), field.Min, field.Max, field.MaxLength, field.Pattern))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("поле %d: %w", field.ID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при изменении поля %d: %w", field.ID, err)
+	}
+	*field = *updated
+	return nil
+}
+
+// DeleteField удаляет поле и его значения у всех задач пользователя, включая задачи в корзине.
+func (s *FieldStore) DeleteField(ctx context.Context, id int, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var changed []*models.Task
+	err := withTx(deleteCtx, s.db, func(tx *sql.Tx) error {
+		var key string
+		err := tx.QueryRowContext(deleteCtx, `DELETE FROM custom_fields WHERE id = $1 AND user_id = $2 RETURNING key`, id, userID).Scan(&key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("поле %d: %w", id, storage.ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при удалении поля %d: %w", id, err)
+		}
+		rows, err := tx.QueryContext(deleteCtx, `SELECT id FROM tasks WHERE user_id = $1 AND custom_fields ? $2 ORDER BY id`, userID, key)
+		if err != nil {
+			return fmt.Errorf("ошибка при поиске значений поля %s: %w", key, err)
+		}
+		var taskIDs []int
+		for rows.Next() {
+			var taskID int
+			if err := rows.Scan(&taskID); err != nil {
+				rows.Close()
+				return fmt.Errorf("ошибка сканирования задачи: %w", err)
+			}
+			taskIDs = append(taskIDs, taskID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("ошибка при поиске значений поля %s: %w", key, err)
+		}
+		for _, taskID := range taskIDs {
+			task, ok, err := changeTaskTx(deleteCtx, tx, taskID, userID, 0, events.TaskUpdated, removeCustomField(key))
+			if err != nil {
+				return err
+			}
+			if ok && task.DeletedAt == nil {
+				changed = append(changed, task)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, task := range changed {
+		s.tasks.publish(ctx, events.TaskUpdated, userID, task.ID, task)
+	}
+	return nil
+}
+
+// removeCustomField возвращает изменение, удаляющее значение поля key.
+func removeCustomField(key string) func(task *models.Task) error {
+	return func(task *models.Task) error {
+		if _, ok := task.CustomFields[key]; !ok {
+			return errNoChange
+		}
+		values := make(map[string]interface{}, len(task.CustomFields))
+		for k, v := range task.CustomFields {
+			if k != key {
+				values[k] = v
+			}
+		}
+		if len(values) == 0 {
+			values = nil
+		}
+		task.CustomFields = values
+		return nil
+	}
+}
+
+// ExistingUserIDs возвращает те из ids, которым соответствуют пользователи.
+func (s *FieldStore) ExistingUserIDs(ctx context.Context, ids []int) ([]int, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	ids64 := make([]int64, len(ids))
+	for i, id := range ids {
+		ids64[i] = int64(id)
+	}
+	rows, err := s.db.QueryContext(getCtx, `SELECT id FROM users WHERE id = ANY($1)`, pq.Array(ids64))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при проверке пользователей: %w", err)
+	}
+	defer rows.Close()
+	var existing []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пользователя: %w", err)
+		}
+		existing = append(existing, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при проверке пользователей: %w", err)
+	}
+	return existing, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// IdempotencyStore реализует storage.IdempotencyStore для PostgreSQL.
+type IdempotencyStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewIdempotencyStore создает новый экземпляр IdempotencyStore.
+// Сохраненные ответы действуют в течение ttl.
+func NewIdempotencyStore(db *sql.DB, ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{db: db, ttl: ttl}
+}
+
+// Migrate создает таблицу ключей идемпотентности, если она не существует.
+func (s *IdempotencyStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		key VARCHAR(255) NOT NULL,
+		fingerprint VARCHAR(64) NOT NULL,
+		status_code INTEGER NOT NULL,
+		header JSONB NOT NULL,
+		body BYTEA NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		PRIMARY KEY (user_id, key)
+	);
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// Do выполняет fn не более одного раза для ключа. Параллельные запросы с одним ключом
+// сериализуются транзакционной advisory-блокировкой, которая держится, пока выполняется fn.
+func (s *IdempotencyStore) Do(ctx context.Context, userID int, key string, fingerprint string, fn func() *models.StoredResponse) (*models.StoredResponse, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, userID, key); err != nil {
+		return nil, false, fmt.Errorf("ошибка блокировки ключа идемпотентности: %w", err)
+	}
+
+	var storedFingerprint string
+	var header []byte
+	resp := &models.StoredResponse{}
+	query := `SELECT fingerprint, status_code, header, body FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at > now()`
+	err = tx.QueryRowContext(ctx, query, userID, key).Scan(&storedFingerprint, &resp.StatusCode, &header, &resp.Body)
+	switch {
+	case err == nil:
+		if storedFingerprint != fingerprint {
+			return nil, false, storage.ErrIdempotencyKeyReused
+		}
+		if err := json.Unmarshal(header, &resp.Header); err != nil {
+			return nil, false, fmt.Errorf("ошибка чтения сохраненного ответа: %w", err)
+		}
+		return resp, true, nil
+	case err != sql.ErrNoRows:
+		return nil, false, fmt.Errorf("ошибка чтения ключа идемпотентности: %w", err)
+	}
+
+	resp = fn()
+	if resp == nil {
+		return nil, false, nil
+	}
+	header, err = json.Marshal(resp.Header)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка сериализации заголовков ответа: %w", err)
+	}
+	// Истекшая запись с тем же ключом перезаписывается
+	insert := `
+	INSERT INTO idempotency_keys (user_id, key, fingerprint, status_code, header, body, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id, key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status_code = EXCLUDED.status_code,
+		header = EXCLUDED.header, body = EXCLUDED.body, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at`
+	_, err = tx.ExecContext(ctx, insert, userID, key, fingerprint, resp.StatusCode, string(header), resp.Body, time.Now().Add(s.ttl))
+	if err != nil {
+		return resp, false, fmt.Errorf("ошибка сохранения ответа: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return resp, false, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return resp, false, nil
+}
+
+// PurgeExpired удаляет истекшие ключи идемпотентности.
+func (s *IdempotencyStore) PurgeExpired(ctx context.Context) (int64, error) {
+	purgeCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := s.db.ExecContext(purgeCtx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении истекших ключей идемпотентности: %w", err)
+	}
+	return result.RowsAffected()
+}
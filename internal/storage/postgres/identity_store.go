@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// maxUsernameAttempts - сколько вариантов имени перебирается при создании пользователя.
+const maxUsernameAttempts = 20
+
+// IdentityStore реализует storage.IdentityStore для PostgreSQL.
+type IdentityStore struct {
+	db *sql.DB
+}
+
+// NewIdentityStore создает новый экземпляр IdentityStore.
+func NewIdentityStore(db *sql.DB) *IdentityStore {
+	return &IdentityStore{db: db}
+}
+
+// Migrate создает таблицу привязок к внешним провайдерам, если она не существует.
+func (s *IdentityStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		issuer VARCHAR(255) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (issuer, subject)
+	);
+	CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// GetUserByIdentity возвращает пользователя по привязке к провайдеру.
+func (s *IdentityStore) GetUserByIdentity(ctx context.Context, issuer string, subject string) (*models.User, error) {
+	query := `SELECT u.id, u.username, u.email, u.email_verified_at, u.token_version, u.created_at
+	FROM user_identities i JOIN users u ON u.id = i.user_id WHERE i.issuer = $1 AND i.subject = $2`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	u, err := scanAccount(s.db.QueryRowContext(getCtx, query, issuer, subject))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("привязка %s к %s: %w", subject, issuer, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске привязки к провайдеру: %w", err)
+	}
+	return u, nil
+}
+
+// LinkIdentity привязывает учетную запись провайдера к пользователю. Существующая привязка не меняется.
+func (s *IdentityStore) LinkIdentity(ctx context.Context, issuer string, subject string, userID int) error {
+	query := `INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	linkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(linkCtx, query, issuer, subject, userID); err != nil {
+		return fmt.Errorf("ошибка при привязке пользователя %d к провайдеру: %w", userID, err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity создает пользователя и привязку в одной транзакции.
+// Пустой password_hash не совпадает ни с одним паролем, поэтому войти по паролю нельзя,
+// пока пользователь не задаст его через сброс пароля.
+func (s *IdentityStore) CreateUserWithIdentity(ctx context.Context, user *models.User, issuer string, subject string) error {
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(createCtx, s.db, func(tx *sql.Tx) error {
+		if user.Email != nil && user.EmailVerifiedAt != nil {
+			var taken bool
+			err := tx.QueryRowContext(createCtx, `SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = lower($1) AND email_verified_at IS NOT NULL)`, *user.Email).Scan(&taken)
+			if err != nil {
+				return fmt.Errorf("ошибка при проверке адреса: %w", err)
+			}
+			if taken {
+				user.Email, user.EmailVerifiedAt = nil, nil
+			}
+		}
+		query := `INSERT INTO users (username, password_hash, email, email_verified_at) VALUES ($1, '', $2, $3)
+		ON CONFLICT (username) DO NOTHING RETURNING id, created_at`
+		base := user.Username
+		for i := 1; ; i++ {
+			if i > maxUsernameAttempts {
+				return fmt.Errorf("не удалось подобрать свободное имя для %q", base)
+			}
+			if i > 1 {
+				user.Username = base + "-" + strconv.Itoa(i)
+			}
+			err := tx.QueryRowContext(createCtx, query, user.Username, user.Email, user.EmailVerifiedAt).Scan(&user.ID, &user.CreatedAt)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("ошибка при создании пользователя: %w", err)
+			}
+			break
+		}
+		if _, err := tx.ExecContext(createCtx, `INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3)`,
+			issuer, subject, user.ID); err != nil {
+			return fmt.Errorf("ошибка при привязке пользователя %d к провайдеру: %w", user.ID, err)
+		}
+		return nil
+	})
+}
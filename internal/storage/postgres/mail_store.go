@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// MailStore реализует storage.MailStore для PostgreSQL.
+type MailStore struct {
+	db *sql.DB
+}
+
+// NewMailStore создает новый экземпляр MailStore.
+func NewMailStore(db *sql.DB) *MailStore {
+	return &MailStore{db: db}
+}
+
+// Migrate добавляет почтовый адрес пользователям и создает таблицы настроек и очереди писем.
+// Уведомления, созданные до появления почты, считаются уже обработанными,
+// поэтому после обновления старые уведомления не рассылаются.
+func (s *MailStore) Migrate(ctx context.Context) error {
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(254);
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS mailed BOOLEAN NOT NULL DEFAULT TRUE;
+	ALTER TABLE notifications ALTER COLUMN mailed SET DEFAULT FALSE;
+	CREATE INDEX IF NOT EXISTS idx_notifications_unmailed ON notifications(user_id) WHERE NOT mailed;
+	CREATE TABLE IF NOT EXISTS notification_mail_preferences (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(20) NOT NULL,
+		mode VARCHAR(10) NOT NULL,
+		PRIMARY KEY (user_id, type)
+	);
+	CREATE TABLE IF NOT EXISTS mail_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		to_address VARCHAR(254) NOT NULL,
+		subject TEXT NOT NULL,
+		text_body TEXT NOT NULL,
+		html_body TEXT NOT NULL DEFAULT '',
+		headers JSONB NOT NULL DEFAULT '{}',
+		status VARCHAR(10) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_error TEXT,
+		sent_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_mail_outbox_pending ON mail_outbox(next_attempt_at) WHERE status = 'pending';`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// GetMailSettings возвращает адрес пользователя и режимы отправки с учетом значений по умолчанию.
+func (s *MailStore) GetMailSettings(ctx context.Context, userID int) (*models.MailSettings, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	settings := &models.MailSettings{Preferences: map[string]string{}}
+	var email sql.NullString
+	err := s.db.QueryRowContext(getCtx, `SELECT email, email_verified_at IS NOT NULL FROM users WHERE id = $1`, userID).
+		Scan(&email, &settings.EmailVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("пользователь %d: %w", userID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении настроек почты: %w", err)
+	}
+	if email.Valid {
+		settings.Email = &email.String
+	}
+	for t, mode := range models.DefaultMailModes {
+		settings.Preferences[t] = mode
+	}
+	rows, err := s.db.QueryContext(getCtx, `SELECT type, mode FROM notification_mail_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении настроек почты: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t, mode string
+		if err := rows.Scan(&t, &mode); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования настройки почты: %w", err)
+		}
+		settings.Preferences[t] = mode
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при получении настроек почты: %w", err)
+	}
+	return settings, nil
+}
+
+// UpdateMailSettings сохраняет адрес и режимы отправки. Храним только режимы,
+// отличные от значений по умолчанию, чтобы смена умолчаний применялась ко всем остальным.
+// Новый адрес считается неподтвержденным; регистр букв при сравнении не учитывается.
+func (s *MailStore) UpdateMailSettings(ctx context.Context, userID int, settings *models.MailSettings) error {
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(updateCtx, s.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(updateCtx, `UPDATE users SET email = $2::text,
+			email_verified_at = CASE WHEN lower(email) = lower($2::text) THEN email_verified_at END
+		WHERE id = $1`, userID, settings.Email)
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении адреса: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении адреса: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("пользователь %d: %w", userID, storage.ErrNotFound)
+		}
+		if _, err := tx.ExecContext(updateCtx, `DELETE FROM notification_mail_preferences WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("ошибка при сохранении настроек почты: %w", err)
+		}
+		for t, mode := range settings.Preferences {
+			if models.DefaultMailModes[t] == mode {
+				continue
+			}
+			if err := setMailMode(updateCtx, tx, userID, t, mode); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetMailMode устанавливает режим отправки для типов уведомлений.
+func (s *MailStore) SetMailMode(ctx context.Context, userID int, types []string, mode string) error {
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(updateCtx, s.db, func(tx *sql.Tx) error {
+		for _, t := range types {
+			if err := setMailMode(updateCtx, tx, userID, t, mode); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func setMailMode(ctx context.Context, q execQuerier, userID int, notificationType string, mode string) error {
+	query := `INSERT INTO notification_mail_preferences (user_id, type, mode) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, type) DO UPDATE SET mode = EXCLUDED.mode`
+	if _, err := q.ExecContext(ctx, query, userID, notificationType, mode); err != nil {
+		return fmt.Errorf("ошибка при сохранении настройки почты %s: %w", notificationType, err)
+	}
+	return nil
+}
+
+// notificationMailModes соединяет уведомления с получателями и режимами отправки;
+// режимы по умолчанию передаются параметрами $1 (типы) и $2 (режимы).
+const notificationMailModes = `notifications n JOIN users u ON u.id = n.user_id
+	LEFT JOIN notification_mail_preferences p ON p.user_id = n.user_id AND p.type = n.type
+	LEFT JOIN unnest($1::text[], $2::text[]) AS d(type, mode) ON d.type = n.type`
+
+const notificationMailMode = `COALESCE(p.mode, d.mode, 'off')`
+
+// QueueNotificationMail ставит в очередь письма по уведомлениям с режимом mode.
+// Строки уведомлений блокируются с SKIP LOCKED, поэтому реплики не отправят одно уведомление дважды.
+func (s *MailStore) QueueNotificationMail(ctx context.Context, mode string, before time.Time, limit int, build storage.MailBuilder) (int64, error) {
+	types := make([]string, 0, len(models.DefaultMailModes))
+	modes := make([]string, 0, len(models.DefaultMailModes))
+	for t, m := range models.DefaultMailModes {
+		types = append(types, t)
+		modes = append(modes, m)
+	}
+	skipQuery := `UPDATE notifications SET mailed = TRUE WHERE id IN (
+		SELECT n.id FROM ` + notificationMailModes + `
+		WHERE NOT n.mailed AND (u.email IS NULL OR u.email_verified_at IS NULL OR ` + notificationMailMode + ` = 'off')
+	)`
+	selectQuery := `WITH recipients AS (
+		SELECT n.user_id FROM ` + notificationMailModes + `
+		WHERE NOT n.mailed AND u.email_verified_at IS NOT NULL AND n.created_at < $3 AND ` + notificationMailMode + ` = $4
+		GROUP BY n.user_id ORDER BY MIN(n.id) LIMIT $5
+	)
+	SELECT n.id, n.user_id, n.type, n.task_id, n.task_title, n.actor_id, COALESCE(a.username, ''),
+		n.text, n.due_at, n.read_at, n.created_at, u.username, u.email
+	FROM ` + notificationMailModes + `
+	JOIN recipients r ON r.user_id = n.user_id
+	LEFT JOIN users a ON a.id = n.actor_id
+	WHERE NOT n.mailed AND u.email_verified_at IS NOT NULL AND n.created_at < $3 AND ` + notificationMailMode + ` = $4
+	ORDER BY n.user_id, n.id
+	FOR UPDATE OF n SKIP LOCKED`
+
+	queueCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var queued int64
+	err := withTx(queueCtx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(queueCtx, skipQuery, pq.Array(types), pq.Array(modes)); err != nil {
+			return fmt.Errorf("ошибка при пропуске уведомлений без почты: %w", err)
+		}
+		rows, err := tx.QueryContext(queueCtx, selectQuery, pq.Array(types), pq.Array(modes), before, mode, limit)
+		if err != nil {
+			return fmt.Errorf("ошибка при выборке уведомлений для почты: %w", err)
+		}
+		var recipients []models.MailRecipient
+		byUser := make(map[int][]models.Notification)
+		var ids []int64
+		for rows.Next() {
+			var n models.Notification
+			var r models.MailRecipient
+			var actorID sql.NullInt64
+			var dueAt, readAt sql.NullTime
+			if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.TaskID, &n.TaskTitle, &actorID, &n.ActorUsername,
+				&n.Text, &dueAt, &readAt, &n.CreatedAt, &r.Username, &r.Email); err != nil {
+				rows.Close()
+				return fmt.Errorf("ошибка сканирования уведомления: %w", err)
+			}
+			if actorID.Valid {
+				id := int(actorID.Int64)
+				n.ActorID = &id
+			}
+			if dueAt.Valid {
+				n.DueAt = &dueAt.Time
+			}
+			if readAt.Valid {
+				n.ReadAt = &readAt.Time
+			}
+			if _, ok := byUser[n.UserID]; !ok {
+				r.UserID = n.UserID
+				recipients = append(recipients, r)
+			}
+			byUser[n.UserID] = append(byUser[n.UserID], n)
+			ids = append(ids, n.ID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("ошибка при выборке уведомлений для почты: %w", err)
+		}
+		for _, r := range recipients {
+			mails, err := build(r, byUser[r.UserID])
+			if err != nil {
+				return err
+			}
+			for i := range mails {
+				if err := insertMail(queueCtx, tx, &mails[i]); err != nil {
+					return err
+				}
+				queued++
+			}
+		}
+		if _, err := tx.ExecContext(queueCtx, `UPDATE notifications SET mailed = TRUE WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return fmt.Errorf("ошибка при отметке уведомлений: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return queued, nil
+}
+
+func insertMail(ctx context.Context, q execQuerier, m *models.OutboxMail) error {
+	headers, err := json.Marshal(m.Headers)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации заголовков письма: %w", err)
+	}
+	if m.Headers == nil {
+		headers = []byte("{}")
+	}
+	query := `INSERT INTO mail_outbox (user_id, to_address, subject, text_body, html_body, headers)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, status, next_attempt_at, created_at`
+	err = q.QueryRowContext(ctx, query, m.UserID, m.To, m.Subject, m.Text, m.HTML, string(headers)).
+		Scan(&m.ID, &m.Status, &m.NextAttemptAt, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка при постановке письма в очередь: %w", err)
+	}
+	return nil
+}
+
+// ClaimMail захватывает письма, готовые к отправке, сдвигая next_attempt_at на lease,
+// так же как ClaimDeliveries для webhook.
+func (s *MailStore) ClaimMail(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMail, error) {
+	query := `
+	WITH due AS (
+		SELECT id FROM mail_outbox
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE mail_outbox m SET next_attempt_at = now() + $2 * interval '1 second'
+	FROM due WHERE m.id = due.id
+	RETURNING m.id, COALESCE(m.user_id, 0), m.to_address, m.subject, m.text_body, m.html_body, m.headers,
+		m.status, m.attempts, m.next_attempt_at, COALESCE(m.last_error, ''), m.created_at`
+	claimCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(claimCtx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при захвате писем: %w", err)
+	}
+	defer rows.Close()
+	var mails []models.OutboxMail
+	for rows.Next() {
+		var m models.OutboxMail
+		var headers []byte
+		if err := rows.Scan(&m.ID, &m.UserID, &m.To, &m.Subject, &m.Text, &m.HTML, &headers,
+			&m.Status, &m.Attempts, &m.NextAttemptAt, &m.LastError, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования письма: %w", err)
+		}
+		if err := json.Unmarshal(headers, &m.Headers); err != nil {
+			return nil, fmt.Errorf("ошибка чтения заголовков письма %d: %w", m.ID, err)
+		}
+		mails = append(mails, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по письмам: %w", err)
+	}
+	return mails, nil
+}
+
+// RecordMailAttempt сохраняет статус, счетчик попыток и время следующей попытки.
+func (s *MailStore) RecordMailAttempt(ctx context.Context, m *models.OutboxMail) error {
+	query := `UPDATE mail_outbox SET status = $2, attempts = $3, next_attempt_at = $4, last_error = NULLIF($5, ''), sent_at = $6
+	WHERE id = $1`
+	recordCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(recordCtx, query, m.ID, m.Status, m.Attempts, m.NextAttemptAt, m.LastError, m.SentAt)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении результата отправки письма %d: %w", m.ID, err)
+	}
+	return nil
+}
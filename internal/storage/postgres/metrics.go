@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// maxInProgressItems - сколько самых старых задач в работе возвращается в метриках.
+const maxInProgressItems = 100
+
+// recordStatusChange записывает переход задачи в новый статус; создание задачи
+// записывается как переход из NULL. История статусов - основа метрик потока.
+func recordStatusChange(ctx context.Context, q execQuerier, before, after *models.Task) error {
+	var from interface{}
+	if before != nil {
+		from = before.Status
+	}
+	query := `INSERT INTO task_status_changes (task_id, user_id, from_status, to_status) VALUES ($1, $2, $3, $4)`
+	if _, err := q.ExecContext(ctx, query, after.ID, after.UserID, from, after.Status); err != nil {
+		return fmt.Errorf("ошибка записи истории статусов задачи %d: %w", after.ID, err)
+	}
+	return nil
+}
+
+// flowTasks - задачи пользователя с моментами начала и завершения работы.
+// $1 - user_id, $2 - статусы бэклога, $3 - выполненные статусы.
+// Задача считается выполненной, если она сейчас в выполненном статусе; момент завершения -
+// последний переход в такой статус, поэтому повторно открытые задачи учитываются заново.
+const flowTasks = `
+	flow AS (
+		SELECT t.id, t.status, t.created_at,
+			(SELECT MIN(c.changed_at) FROM task_status_changes c
+			 WHERE c.task_id = t.id AND NOT (c.to_status = ANY($2))) AS started_at,
+			(SELECT MAX(c.changed_at) FROM task_status_changes c
+			 WHERE c.task_id = t.id AND c.to_status = ANY($3)) AS done_at
+		FROM tasks t
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+	)`
+
+// GetFlowMetrics рассчитывает время выполнения, время цикла, недельную пропускную способность
+// и возраст задач в работе. Все агрегаты считаются в SQL.
+func (s *TaskStore) GetFlowMetrics(ctx context.Context, userID int, query models.FlowMetricsQuery) (*models.FlowMetrics, error) {
+	metricsCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	m := &models.FlowMetrics{
+		From:            query.From,
+		To:              query.To,
+		DoneStatuses:    query.DoneStatuses,
+		BacklogStatuses: query.BacklogStatuses,
+		Throughput:      []models.WeeklyThroughput{},
+		InProgress:      []models.WorkItemAge{},
+	}
+	backlog, done := pq.Array(query.BacklogStatuses), pq.Array(query.DoneStatuses)
+
+	var lead, cycle pq.Float64Array
+	completedQuery := `WITH ` + flowTasks + `
+	SELECT COUNT(*),
+		percentile_cont(ARRAY[0.5, 0.85, 0.95]) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM done_at - created_at) / 3600),
+		percentile_cont(ARRAY[0.5, 0.85, 0.95]) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM done_at - COALESCE(started_at, done_at)) / 3600)
+	FROM flow
+	WHERE status = ANY($3) AND done_at >= $4 AND done_at < $5`
+	err := s.db.QueryRowContext(metricsCtx, completedQuery, userID, backlog, done, query.From, query.To).Scan(&m.Completed, &lead, &cycle)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расчета времени выполнения: %w", err)
+	}
+	m.LeadTime = percentiles(lead)
+	m.CycleTime = percentiles(cycle)
+
+	throughputQuery := `WITH ` + flowTasks + `,
+	weeks AS (
+		SELECT generate_series(date_trunc('week', $4::timestamptz AT TIME ZONE 'UTC'), $5::timestamptz AT TIME ZONE 'UTC', interval '1 week') AS week
+	)
+	SELECT w.week, COUNT(f.id)
+	FROM weeks w
+	LEFT JOIN flow f ON f.status = ANY($3) AND f.done_at >= $4 AND f.done_at < $5
+		AND date_trunc('week', f.done_at AT TIME ZONE 'UTC') = w.week
+	GROUP BY w.week ORDER BY w.week`
+	rows, err := s.db.QueryContext(metricsCtx, throughputQuery, userID, backlog, done, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расчета пропускной способности: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w models.WeeklyThroughput
+		if err := rows.Scan(&w.WeekStart, &w.Completed); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пропускной способности: %w", err)
+		}
+		w.WeekStart = time.Date(w.WeekStart.Year(), w.WeekStart.Month(), w.WeekStart.Day(), 0, 0, 0, 0, time.UTC)
+		m.Throughput = append(m.Throughput, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по неделям: %w", err)
+	}
+
+	var age pq.Float64Array
+	ageQuery := `WITH ` + flowTasks + `
+	SELECT percentile_cont(ARRAY[0.5, 0.85, 0.95]) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM now() - started_at) / 3600)
+	FROM flow f JOIN tasks t ON t.id = f.id
+	WHERE f.started_at IS NOT NULL AND NOT (f.status = ANY($2)) AND NOT (f.status = ANY($3)) AND t.archived_at IS NULL`
+	if err := s.db.QueryRowContext(metricsCtx, ageQuery, userID, backlog, done).Scan(&age); err != nil {
+		return nil, fmt.Errorf("ошибка расчета возраста задач: %w", err)
+	}
+	m.WorkItemAge = percentiles(age)
+
+	itemsQuery := `WITH ` + flowTasks + `
+	SELECT f.id, f.status, f.started_at, EXTRACT(EPOCH FROM now() - f.started_at) / 3600
+	FROM flow f JOIN tasks t ON t.id = f.id
+	WHERE f.started_at IS NOT NULL AND NOT (f.status = ANY($2)) AND NOT (f.status = ANY($3)) AND t.archived_at IS NULL
+	ORDER BY f.started_at LIMIT $4`
+	itemRows, err := s.db.QueryContext(metricsCtx, itemsQuery, userID, backlog, done, maxInProgressItems)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задач в работе: %w", err)
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var item models.WorkItemAge
+		if err := itemRows.Scan(&item.TaskID, &item.Status, &item.StartedAt, &item.AgeHours); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования задачи в работе: %w", err)
+		}
+		m.InProgress = append(m.InProgress, item)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по задачам в работе: %w", err)
+	}
+	return m, nil
+}
+
+func percentiles(values pq.Float64Array) models.Percentiles {
+	if len(values) != 3 {
+		return models.Percentiles{}
+	}
+	return models.Percentiles{P50: &values[0], P85: &values[1], P95: &values[2]}
+}
@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// NotificationStore реализует storage.NotificationStore для PostgreSQL.
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// NewNotificationStore создает новый экземпляр NotificationStore.
+func NewNotificationStore(db *sql.DB) *NotificationStore {
+	return &NotificationStore{db: db}
+}
+
+// Migrate создает таблицу уведомлений, если она не существует.
+// task_id без внешнего ключа: уведомление переживает окончательное удаление задачи.
+func (s *NotificationStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS notifications (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(20) NOT NULL,
+		task_id INTEGER NOT NULL,
+		task_title VARCHAR(255) NOT NULL,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		text TEXT NOT NULL DEFAULT '',
+		due_at TIMESTAMP WITH TIME ZONE,
+		dedup_key VARCHAR(64) NOT NULL DEFAULT '',
+		read_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, type, task_id, dedup_key)
+	);
+	CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_notifications_mention_actor ON notifications(actor_id, created_at) WHERE type = 'mention';`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// CreateNotification сохраняет уведомление, если такого же еще не было.
+func (s *NotificationStore) CreateNotification(ctx context.Context, n *models.Notification) (bool, error) {
+	query := `INSERT INTO notifications (user_id, type, task_id, task_title, actor_id, text, due_at, dedup_key)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (user_id, type, task_id, dedup_key) DO NOTHING
+	RETURNING id, created_at, COALESCE((SELECT username FROM users WHERE id = actor_id), '')`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.db.QueryRowContext(createCtx, query, n.UserID, n.Type, n.TaskID, n.TaskTitle, n.ActorID, n.Text, n.DueAt, n.DedupKey).
+		Scan(&n.ID, &n.CreatedAt, &n.ActorUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка при создании уведомления: %w", err)
+	}
+	return true, nil
+}
+
+// GetNotifications возвращает страницу уведомлений, начиная с новых.
+// Читается на одну запись больше limit, чтобы понять, есть ли следующая страница.
+func (s *NotificationStore) GetNotifications(ctx context.Context, userID int, unreadOnly bool, before int64, limit int) (*models.NotificationPage, error) {
+	query := `SELECT n.id, n.user_id, n.type, n.task_id, n.task_title, n.actor_id, COALESCE(u.username, ''),
+		n.text, n.due_at, n.read_at, n.created_at
+	FROM notifications n LEFT JOIN users u ON u.id = n.actor_id
+	WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL) AND ($3::bigint = 0 OR n.id < $3::bigint)
+	ORDER BY n.id DESC LIMIT $4`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID, unreadOnly, before, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении уведомлений: %w", err)
+	}
+	defer rows.Close()
+	page := &models.NotificationPage{Items: []models.Notification{}}
+	for rows.Next() {
+		var n models.Notification
+		var actorID sql.NullInt64
+		var dueAt, readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.TaskID, &n.TaskTitle, &actorID, &n.ActorUsername,
+			&n.Text, &dueAt, &readAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования уведомления: %w", err)
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			n.ActorID = &id
+		}
+		if dueAt.Valid {
+			n.DueAt = &dueAt.Time
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		page.Items = append(page.Items, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по уведомлениям: %w", err)
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		next := page.Items[limit-1].ID
+		page.NextBefore = &next
+	}
+	return page, nil
+}
+
+// MarkRead отмечает уведомление прочитанным. Повторная отметка не меняет время прочтения.
+func (s *NotificationStore) MarkRead(ctx context.Context, id int64, userID int) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2`
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	res, err := s.db.ExecContext(updateCtx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при отметке уведомления %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при отметке уведомления %d: %w", id, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("уведомление %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// MarkAllRead отмечает прочитанными все непрочитанные уведомления пользователя.
+func (s *NotificationStore) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	query := `UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL`
+	updateCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	res, err := s.db.ExecContext(updateCtx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при отметке уведомлений: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// UnreadCount возвращает количество непрочитанных уведомлений пользователя.
+func (s *NotificationStore) UnreadCount(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var n int
+	if err := s.db.QueryRowContext(getCtx, query, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("ошибка при подсчете уведомлений: %w", err)
+	}
+	return n, nil
+}
+
+// CountMentionsByActor считает уведомления об упоминании, созданные по действиям actorID с момента since.
+func (s *NotificationStore) CountMentionsByActor(ctx context.Context, actorID int, since time.Time) (map[int]int, error) {
+	query := `SELECT user_id, COUNT(*) FROM notifications
+	WHERE actor_id = $1 AND type = $2 AND created_at >= $3
+	GROUP BY user_id`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, actorID, models.NotificationMention, since)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при подсчете упоминаний пользователя %d: %w", actorID, err)
+	}
+	defer rows.Close()
+	counts := map[int]int{}
+	for rows.Next() {
+		var userID, n int
+		if err := rows.Scan(&userID, &n); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования упоминаний: %w", err)
+		}
+		counts[userID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при подсчете упоминаний пользователя %d: %w", actorID, err)
+	}
+	return counts, nil
+}
+
+// GetUserIDsByUsernames возвращает ID пользователей с точным совпадением имени.
+func (s *NotificationStore) GetUserIDsByUsernames(ctx context.Context, usernames []string) (map[string]int, error) {
+	ids := make(map[string]int, len(usernames))
+	if len(usernames) == 0 {
+		return ids, nil
+	}
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, `SELECT id, username FROM users WHERE username = ANY($1)`, pq.Array(usernames))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске пользователей: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пользователя: %w", err)
+		}
+		ids[username] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при поиске пользователей: %w", err)
+	}
+	return ids, nil
+}
+
+// GetDueSoonTasks возвращает задачи, срок которых наступит в течение window.
+// Уведомление due_soon помнит срок, о котором оно было, поэтому после переноса срока
+// задача попадет в выборку снова.
+func (s *NotificationStore) GetDueSoonTasks(ctx context.Context, now time.Time, window time.Duration, limit int) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t
+	WHERE due_at > $1 AND due_at <= $2 AND COALESCE(status, '') <> 'completed'
+	AND deleted_at IS NULL AND archived_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1 FROM notifications n
+		WHERE n.user_id = t.user_id AND n.type = $3 AND n.task_id = t.id AND n.due_at = t.due_at
+	)
+	ORDER BY due_at LIMIT $4`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, now, now.Add(window), models.NotificationDueSoon, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске задач с близким сроком: %w", err)
+	}
+	defer rows.Close()
+	var tasks []models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования задачи: %w", err)
+		}
+		tasks = append(tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при поиске задач с близким сроком: %w", err)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/ical"
+	"kanban-backend/internal/models"
+)
+
+// maxOccurrencesPerRun ограничивает число повторений, создаваемых за один запуск планировщика.
+const maxOccurrencesPerRun = 500
+
+// MaterializeOccurrences создает следующее повторение для каждой повторяющейся задачи,
+// которая выполнена или срок которой наступил к моменту now. Каждая задача обрабатывается
+// в отдельной транзакции; FOR UPDATE SKIP LOCKED не дает нескольким репликам
+// создать одно повторение дважды. Возвращает количество созданных задач.
+func (s *TaskStore) MaterializeOccurrences(ctx context.Context, now time.Time) (int64, error) {
+	var created int64
+	for created < maxOccurrencesPerRun {
+		var next *models.Task
+		var found bool
+		runCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := withTx(runCtx, s.db, func(tx *sql.Tx) error {
+			var err error
+			next, found, err = materializeNextTx(runCtx, tx, now)
+			return err
+		})
+		cancel()
+		if err != nil {
+			return created, err
+		}
+		if !found {
+			break
+		}
+		if next != nil {
+			created++
+			s.publish(ctx, events.TaskCreated, next.UserID, next.ID, next)
+		}
+	}
+	return created, nil
+}
+
+// materializeNextTx блокирует одну задачу, ожидающую создания следующего повторения,
+// создает повторение и отмечает задачу обработанной. found равен false, если таких задач нет;
+// next равен nil, если серия закончилась.
+func materializeNextTx(ctx context.Context, tx *sql.Tx, now time.Time) (next *models.Task, found bool, err error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks
+	WHERE rrule <> '' AND NOT occurrence_created AND deleted_at IS NULL AND archived_at IS NULL
+	AND due_at IS NOT NULL AND (status = 'completed' OR due_at <= $1)
+	ORDER BY due_at LIMIT 1 FOR UPDATE SKIP LOCKED`
+	current, err := scanTask(tx.QueryRowContext(ctx, query, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка при выборке повторяющейся задачи: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET occurrence_created = TRUE WHERE id = $1`, current.ID); err != nil {
+		return nil, false, fmt.Errorf("ошибка при изменении задачи %d: %w", current.ID, err)
+	}
+
+	rule, err := ical.ParseRule(current.RRule)
+	if err != nil {
+		// Правила проверяются при сохранении; повторять попытку для такой задачи бесполезно
+		log.Printf("Задача %d: неверное правило повторения %q: %v", current.ID, current.RRule, err)
+		return nil, true, nil
+	}
+	start, due, ok := nextOccurrence(rule, current, now)
+	if !ok {
+		return nil, true, nil
+	}
+	next = &models.Task{
+		Title:           current.Title,
+		Description:     current.Description,
+		Status:          "pending",
+		UserID:          current.UserID,
+		DueAt:           &due,
+		RRule:           current.RRule,
+		RecurrenceStart: &start,
+		Estimate:        current.Estimate,
+		CustomFields:    current.CustomFields,
+	}
+	if err := insertTaskTx(ctx, tx, next); err != nil {
+		return nil, false, err
+	}
+	return next, true, nil
+}
+
+// nextOccurrence возвращает начало серии и срок следующего повторения задачи. Повторение
+// ищется после текущего срока, а у просроченной задачи - после now: пропущенные повторения
+// не создаются, иначе задача, просроченная на год, породила бы сотни задач сразу.
+func nextOccurrence(rule *ical.Rule, task *models.Task, now time.Time) (start time.Time, due time.Time, ok bool) {
+	start = *task.DueAt
+	if task.RecurrenceStart != nil {
+		start = *task.RecurrenceStart
+	}
+	after := *task.DueAt
+	if now.After(after) {
+		after = now
+	}
+	occurrences := rule.Occurrences(start, after, 1)
+	if len(occurrences) == 0 {
+		return start, time.Time{}, false
+	}
+	return start, occurrences[0], true
+}
+
+// PreviewOccurrences возвращает до n следующих повторений задачи после ее текущего срока.
+func (s *TaskStore) PreviewOccurrences(ctx context.Context, id int, userID int, n int) (*models.RecurrencePreview, error) {
+	task, err := s.GetTaskByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	preview := &models.RecurrencePreview{TaskID: task.ID, RRule: task.RRule, Occurrences: []time.Time{}}
+	if task.RRule == "" || task.DueAt == nil {
+		return preview, nil
+	}
+	rule, err := ical.ParseRule(task.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("задача %d: неверное правило повторения: %w", id, err)
+	}
+	start := *task.DueAt
+	if task.RecurrenceStart != nil {
+		start = *task.RecurrenceStart
+	}
+	if occurrences := rule.Occurrences(start, *task.DueAt, n); occurrences != nil {
+		preview.Occurrences = occurrences
+	}
+	return preview, nil
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
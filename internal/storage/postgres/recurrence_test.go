@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"kanban-backend/internal/ical"
+	"kanban-backend/internal/models"
+)
+
+func TestNextOccurrenceSkipsMissedOccurrences(t *testing.T) {
+	rule, err := ical.ParseRule("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	task := &models.Task{DueAt: &start, RRule: "FREQ=DAILY"}
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	gotStart, due, ok := nextOccurrence(rule, task, now)
+	if !ok {
+		t.Fatal("серия не должна закончиться")
+	}
+	if !gotStart.Equal(start) {
+		t.Errorf("начало серии %v, ожидалось %v", gotStart, start)
+	}
+	// Пропущенные за год повторения не создаются: следующее - первое после now
+	if want := time.Date(2026, 1, 11, 9, 0, 0, 0, time.UTC); !due.Equal(want) {
+		t.Errorf("следующий срок %v, ожидался %v", due, want)
+	}
+}
+
+func TestNextOccurrenceAfterDueDate(t *testing.T) {
+	rule, err := ical.ParseRule("FREQ=WEEKLY;BYDAY=MO,TH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) // понедельник
+	due := time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)   // четверг
+	task := &models.Task{DueAt: &due, RecurrenceStart: &start}
+	// Задача выполнена до срока: следующее повторение отсчитывается от срока, а не от now
+	now := time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC)
+
+	gotStart, next, ok := nextOccurrence(rule, task, now)
+	if !ok {
+		t.Fatal("серия не должна закончиться")
+	}
+	if !gotStart.Equal(start) {
+		t.Errorf("начало серии %v, ожидалось %v", gotStart, start)
+	}
+	if want := time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("следующий срок %v, ожидался %v", next, want)
+	}
+}
+
+func TestNextOccurrenceSeriesEnded(t *testing.T) {
+	rule, err := ical.ParseRule("FREQ=DAILY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)
+	task := &models.Task{DueAt: &due, RecurrenceStart: &start}
+
+	if _, next, ok := nextOccurrence(rule, task, due); ok {
+		t.Errorf("серия из трех повторений закончилась, но получен срок %v", next)
+	}
+}
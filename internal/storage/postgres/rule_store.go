@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// ruleExecutionLimit - сколько последних записей журнала хранится для каждого правила.
+const ruleExecutionLimit = 200
+
+// RuleStore реализует storage.RuleStore для PostgreSQL.
+type RuleStore struct {
+	db *sql.DB
+}
+
+// NewRuleStore создает новый экземпляр RuleStore.
+func NewRuleStore(db *sql.DB) *RuleStore {
+	return &RuleStore{db: db}
+}
+
+// Migrate создает таблицы правил, если они не существуют.
+func (s *RuleStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS rules (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		trigger_type VARCHAR(32) NOT NULL,
+		trigger_events TEXT[] NOT NULL DEFAULT '{}',
+		conditions JSONB NOT NULL DEFAULT '[]',
+		actions JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_rules_user_id ON rules(user_id, trigger_type) WHERE enabled;
+	CREATE TABLE IF NOT EXISTS rule_executions (
+		id BIGSERIAL PRIMARY KEY,
+		rule_id INTEGER NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+		task_id INTEGER NOT NULL,
+		trigger VARCHAR(64) NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		actions TEXT[] NOT NULL DEFAULT '{}',
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_rule_executions_rule_id ON rule_executions(rule_id, id);
+	CREATE TABLE IF NOT EXISTS rule_fired (
+		rule_id INTEGER NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+		task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		fired_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (rule_id, task_id)
+	);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+func marshalRuleParts(rule *models.Rule) (conditions, actions []byte, err error) {
+	if rule.Conditions == nil {
+		rule.Conditions = []models.RuleCondition{}
+	}
+	if rule.Actions == nil {
+		rule.Actions = []models.RuleAction{}
+	}
+	if rule.Trigger.Events == nil {
+		rule.Trigger.Events = []string{}
+	}
+	if conditions, err = json.Marshal(rule.Conditions); err != nil {
+		return nil, nil, fmt.Errorf("ошибка сериализации условий правила: %w", err)
+	}
+	if actions, err = json.Marshal(rule.Actions); err != nil {
+		return nil, nil, fmt.Errorf("ошибка сериализации действий правила: %w", err)
+	}
+	return conditions, actions, nil
+}
+
+// CreateRule добавляет новое правило.
+func (s *RuleStore) CreateRule(ctx context.Context, rule *models.Rule) (int, error) {
+	conditions, actions, err := marshalRuleParts(rule)
+	if err != nil {
+		return 0, err
+	}
+	query := `INSERT INTO rules (user_id, name, enabled, trigger_type, trigger_events, conditions, actions)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = s.db.QueryRowContext(createCtx, query, rule.UserID, rule.Name, rule.Enabled, rule.Trigger.Type,
+		pq.Array(rule.Trigger.Events), conditions, actions).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании правила: %w", err)
+	}
+	return rule.ID, nil
+}
+
+const ruleColumns = `r.id, r.user_id, r.name, r.enabled, r.trigger_type, r.trigger_events, r.conditions, r.actions, r.created_at`
+
+func scanRule(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.Rule, error) {
+	rule := &models.Rule{}
+	var conditions, actions []byte
+	dest := []interface{}{&rule.ID, &rule.UserID, &rule.Name, &rule.Enabled, &rule.Trigger.Type,
+		pq.Array(&rule.Trigger.Events), &conditions, &actions, &rule.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+		return nil, fmt.Errorf("ошибка чтения условий правила %d: %w", rule.ID, err)
+	}
+	if err := json.Unmarshal(actions, &rule.Actions); err != nil {
+		return nil, fmt.Errorf("ошибка чтения действий правила %d: %w", rule.ID, err)
+	}
+	return rule, nil
+}
+
+func (s *RuleStore) queryRules(ctx context.Context, query string, args ...interface{}) ([]models.Rule, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении правил: %w", err)
+	}
+	defer rows.Close()
+	rules := []models.Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования правила: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по правилам: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRules возвращает правила пользователя.
+func (s *RuleStore) GetRules(ctx context.Context, userID int) ([]models.Rule, error) {
+	return s.queryRules(ctx, `SELECT `+ruleColumns+` FROM rules r WHERE r.user_id = $1 ORDER BY r.id`, userID)
+}
+
+// GetEnabledRules возвращает включенные правила пользователя с заданным типом триггера.
+func (s *RuleStore) GetEnabledRules(ctx context.Context, userID int, triggerType string) ([]models.Rule, error) {
+	return s.queryRules(ctx, `SELECT `+ruleColumns+` FROM rules r
+	WHERE r.user_id = $1 AND r.trigger_type = $2 AND r.enabled ORDER BY r.id`, userID, triggerType)
+}
+
+// GetRule возвращает правило пользователя по ID.
+func (s *RuleStore) GetRule(ctx context.Context, id int, userID int) (*models.Rule, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rule, err := scanRule(s.db.QueryRowContext(getCtx, `SELECT `+ruleColumns+` FROM rules r WHERE r.id = $1 AND r.user_id = $2`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("правило %d: %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении правила %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// UpdateRule заменяет правило целиком.
+func (s *RuleStore) UpdateRule(ctx context.Context, rule *models.Rule) error {
+	conditions, actions, err := marshalRuleParts(rule)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE rules SET name = $3, enabled = $4, trigger_type = $5, trigger_events = $6, conditions = $7, actions = $8
+	WHERE id = $1 AND user_id = $2 RETURNING created_at`
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = s.db.QueryRowContext(updateCtx, query, rule.ID, rule.UserID, rule.Name, rule.Enabled, rule.Trigger.Type,
+		pq.Array(rule.Trigger.Events), conditions, actions).Scan(&rule.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("правило %d: %w", rule.ID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при изменении правила %d: %w", rule.ID, err)
+	}
+	return nil
+}
+
+// DeleteRule удаляет правило вместе с журналом его выполнения.
+func (s *RuleStore) DeleteRule(ctx context.Context, id int, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(deleteCtx, `DELETE FROM rules WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении правила %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("правило %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// GetDueRuleTasks возвращает пары правило due_passed и задача с прошедшим сроком,
+// для которых правило еще не срабатывало. Задачи в корзине и архиве не учитываются.
+func (s *RuleStore) GetDueRuleTasks(ctx context.Context, now time.Time, limit int) ([]storage.RuleTask, error) {
+	query := `SELECT ` + ruleColumns + `, t.id FROM rules r
+	JOIN tasks t ON t.user_id = r.user_id
+	WHERE r.enabled AND r.trigger_type = $1
+	AND t.due_at <= $2 AND t.deleted_at IS NULL AND t.archived_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM rule_fired f WHERE f.rule_id = r.id AND f.task_id = t.id)
+	ORDER BY t.due_at LIMIT $3`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, models.RuleTriggerDuePassed, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при выборке задач с прошедшим сроком: %w", err)
+	}
+	defer rows.Close()
+	var result []storage.RuleTask
+	for rows.Next() {
+		var taskID int
+		rule, err := scanRule(rows, &taskID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования правила: %w", err)
+		}
+		result = append(result, storage.RuleTask{Rule: *rule, TaskID: taskID})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по правилам: %w", err)
+	}
+	return result, nil
+}
+
+// MarkFired отмечает срабатывание правила для задачи.
+func (s *RuleStore) MarkFired(ctx context.Context, ruleID int, taskID int) (bool, error) {
+	markCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(markCtx, `INSERT INTO rule_fired (rule_id, task_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, ruleID, taskID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при отметке срабатывания правила %d: %w", ruleID, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// AppendExecution добавляет запись в журнал выполнения правила и удаляет самые старые записи сверх лимита.
+func (s *RuleStore) AppendExecution(ctx context.Context, exec *models.RuleExecution) error {
+	if exec.Actions == nil {
+		exec.Actions = []string{}
+	}
+	appendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	query := `INSERT INTO rule_executions (rule_id, task_id, trigger, status, actions, error) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err := s.db.QueryRowContext(appendCtx, query, exec.RuleID, exec.TaskID, exec.Trigger, exec.Status,
+		pq.Array(exec.Actions), exec.Error).Scan(&exec.ID, &exec.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи в журнал правила %d: %w", exec.RuleID, err)
+	}
+	pruneQuery := `
+	DELETE FROM rule_executions
+	WHERE rule_id = $1 AND id <= (
+		SELECT id FROM rule_executions WHERE rule_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1
+	)`
+	if _, err := s.db.ExecContext(appendCtx, pruneQuery, exec.RuleID, ruleExecutionLimit); err != nil {
+		return fmt.Errorf("ошибка при очистке журнала правила %d: %w", exec.RuleID, err)
+	}
+	return nil
+}
+
+// GetExecutions возвращает последние записи журнала выполнения правила, от новых к старым.
+func (s *RuleStore) GetExecutions(ctx context.Context, ruleID int, userID int, limit int) ([]models.RuleExecution, error) {
+	query := `SELECT e.id, e.rule_id, e.task_id, e.trigger, e.status, e.actions, e.error, e.created_at
+	FROM rule_executions e JOIN rules r ON r.id = e.rule_id
+	WHERE e.rule_id = $1 AND r.user_id = $2 ORDER BY e.id DESC LIMIT $3`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, ruleID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении журнала правила %d: %w", ruleID, err)
+	}
+	defer rows.Close()
+	executions := []models.RuleExecution{}
+	for rows.Next() {
+		var e models.RuleExecution
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.TaskID, &e.Trigger, &e.Status, pq.Array(&e.Actions), &e.Error, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи журнала правила: %w", err)
+		}
+		executions = append(executions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по журналу правила: %w", err)
+	}
+	return executions, nil
+}
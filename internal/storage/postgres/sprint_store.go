@@ -0,0 +1,495 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// SprintStore реализует storage.SprintStore для PostgreSQL.
+// Состав спринта хранится в tasks.sprint_id и меняется через changeTaskTx,
+// чтобы каждое изменение попадало в журнал задачи: по нему строятся диаграммы сгорания.
+type SprintStore struct {
+	db    *sql.DB
+	tasks *TaskStore
+}
+
+// NewSprintStore создает новый экземпляр SprintStore поверх хранилища задач.
+func NewSprintStore(tasks *TaskStore) *SprintStore {
+	return &SprintStore{db: tasks.DB(), tasks: tasks}
+}
+
+// Migrate создает таблицу спринтов, если она не существует.
+func (s *SprintStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS sprints (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL,
+		goal TEXT NOT NULL DEFAULT '',
+		start_date DATE NOT NULL,
+		end_date DATE NOT NULL,
+		closed_at TIMESTAMP WITH TIME ZONE,
+		stats JSONB,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		CHECK (start_date <= end_date)
+	);
+	CREATE INDEX IF NOT EXISTS idx_sprints_user_id ON sprints(user_id, start_date);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+const sprintColumns = `id, user_id, name, goal, start_date, end_date, closed_at, stats, created_at`
+
+func scanSprint(row interface{ Scan(...interface{}) error }) (*models.Sprint, error) {
+	sprint := &models.Sprint{}
+	var start, end time.Time
+	var closedAt sql.NullTime
+	var stats []byte
+	if err := row.Scan(&sprint.ID, &sprint.UserID, &sprint.Name, &sprint.Goal, &start, &end, &closedAt, &stats, &sprint.CreatedAt); err != nil {
+		return nil, err
+	}
+	sprint.StartDate = start.Format("2006-01-02")
+	sprint.EndDate = end.Format("2006-01-02")
+	if closedAt.Valid {
+		sprint.ClosedAt = &closedAt.Time
+	}
+	if stats != nil {
+		sprint.Stats = &models.SprintStats{}
+		if err := json.Unmarshal(stats, sprint.Stats); err != nil {
+			return nil, fmt.Errorf("ошибка чтения итогов спринта %d: %w", sprint.ID, err)
+		}
+	}
+	return sprint, nil
+}
+
+// CreateSprint добавляет новый спринт.
+func (s *SprintStore) CreateSprint(ctx context.Context, sprint *models.Sprint) (int, error) {
+	query := `INSERT INTO sprints (user_id, name, goal, start_date, end_date) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.db.QueryRowContext(createCtx, query, sprint.UserID, sprint.Name, sprint.Goal, sprint.StartDate, sprint.EndDate).
+		Scan(&sprint.ID, &sprint.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании спринта: %w", err)
+	}
+	return sprint.ID, nil
+}
+
+// GetSprints возвращает спринты пользователя в порядке начала.
+func (s *SprintStore) GetSprints(ctx context.Context, userID int) ([]models.Sprint, error) {
+	query := `SELECT ` + sprintColumns + ` FROM sprints WHERE user_id = $1 ORDER BY start_date, id`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении спринтов: %w", err)
+	}
+	defer rows.Close()
+	sprints := []models.Sprint{}
+	for rows.Next() {
+		sprint, err := scanSprint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования спринта: %w", err)
+		}
+		sprints = append(sprints, *sprint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по спринтам: %w", err)
+	}
+	return sprints, nil
+}
+
+// GetSprint возвращает спринт пользователя по ID.
+func (s *SprintStore) GetSprint(ctx context.Context, id int, userID int) (*models.Sprint, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return getSprint(getCtx, s.db, id, userID, "")
+}
+
+// getSprint читает спринт; lock добавляется к запросу (например, FOR UPDATE).
+func getSprint(ctx context.Context, q execQuerier, id int, userID int, lock string) (*models.Sprint, error) {
+	query := `SELECT ` + sprintColumns + ` FROM sprints WHERE id = $1 AND user_id = $2 ` + lock
+	sprint, err := scanSprint(q.QueryRowContext(ctx, query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("спринт %d: %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении спринта %d: %w", id, err)
+	}
+	return sprint, nil
+}
+
+// UpdateSprint заменяет название, цель и даты спринта.
+func (s *SprintStore) UpdateSprint(ctx context.Context, sprint *models.Sprint) error {
+	query := `UPDATE sprints SET name = $3, goal = $4, start_date = $5, end_date = $6
+	WHERE id = $1 AND user_id = $2 RETURNING ` + sprintColumns
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	updated, err := scanSprint(s.db.QueryRowContext(updateCtx, query, sprint.ID, sprint.UserID, sprint.Name, sprint.Goal, sprint.StartDate, sprint.EndDate))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("спринт %d: %w", sprint.ID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при изменении спринта %d: %w", sprint.ID, err)
+	}
+	*sprint = *updated
+	return nil
+}
+
+// DeleteSprint удаляет спринт, предварительно возвращая его задачи в бэклог.
+func (s *SprintStore) DeleteSprint(ctx context.Context, id int, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var changed []*models.Task
+	err := withTx(deleteCtx, s.db, func(tx *sql.Tx) error {
+		if _, err := getSprint(deleteCtx, tx, id, userID, "FOR UPDATE"); err != nil {
+			return err
+		}
+		var err error
+		if changed, err = moveSprintTasksTx(deleteCtx, tx, id, userID, nil, nil); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(deleteCtx, `DELETE FROM sprints WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("ошибка при удалении спринта %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.publishTasks(ctx, userID, changed)
+	return nil
+}
+
+// GetSprintTasks возвращает задачи спринта, кроме задач в корзине.
+func (s *SprintStore) GetSprintTasks(ctx context.Context, id int, userID int) ([]models.Task, error) {
+	if _, err := s.GetSprint(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE sprint_id = $1 AND user_id = $2 AND deleted_at IS NULL ORDER BY id`
+	return s.tasks.queryTasks(ctx, query, id, userID)
+}
+
+// setTaskSprint возвращает изменение, переносящее задачу в спринт sprintID (nil - в бэклог).
+func setTaskSprint(sprintID *int) func(task *models.Task) error {
+	return func(task *models.Task) error {
+		if err := notFoundIfDeleted(task); err != nil {
+			return err
+		}
+		if (task.SprintID == nil && sprintID == nil) || (task.SprintID != nil && sprintID != nil && *task.SprintID == *sprintID) {
+			return errNoChange
+		}
+		task.SprintID = sprintID
+		return nil
+	}
+}
+
+// AddSprintTasks переносит задачи в спринт, в том числе из других спринтов.
+// Задачи, уже входящие в спринт, не меняются.
+func (s *SprintStore) AddSprintTasks(ctx context.Context, id int, userID int, taskIDs []int) ([]models.Task, error) {
+	addCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	tasks := make([]models.Task, 0, len(taskIDs))
+	var changed []*models.Task
+	err := withTx(addCtx, s.db, func(tx *sql.Tx) error {
+		sprint, err := getSprint(addCtx, tx, id, userID, "FOR SHARE")
+		if err != nil {
+			return err
+		}
+		if sprint.ClosedAt != nil {
+			return fmt.Errorf("спринт %d: %w", id, storage.ErrSprintClosed)
+		}
+		for _, taskID := range taskIDs {
+			task, ok, err := changeTaskTx(addCtx, tx, taskID, userID, 0, events.TaskUpdated, setTaskSprint(&id))
+			if err != nil {
+				return err
+			}
+			if ok {
+				changed = append(changed, task)
+			}
+			tasks = append(tasks, *task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishTasks(ctx, userID, changed)
+	return tasks, nil
+}
+
+// RemoveSprintTask возвращает задачу спринта в бэклог.
+func (s *SprintStore) RemoveSprintTask(ctx context.Context, id int, userID int, taskID int) (*models.Task, error) {
+	removeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var task *models.Task
+	var changed bool
+	err := withTx(removeCtx, s.db, func(tx *sql.Tx) error {
+		sprint, err := getSprint(removeCtx, tx, id, userID, "FOR SHARE")
+		if err != nil {
+			return err
+		}
+		if sprint.ClosedAt != nil {
+			return fmt.Errorf("спринт %d: %w", id, storage.ErrSprintClosed)
+		}
+		remove := setTaskSprint(nil)
+		task, changed, err = changeTaskTx(removeCtx, tx, taskID, userID, 0, events.TaskUpdated, func(task *models.Task) error {
+			if task.SprintID == nil || *task.SprintID != id {
+				return fmt.Errorf("задача %d в спринте %d: %w", taskID, id, storage.ErrNotFound)
+			}
+			return remove(task)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		s.tasks.publish(ctx, events.TaskUpdated, userID, task.ID, task)
+	}
+	return task, nil
+}
+
+// moveSprintTasksTx переносит задачи спринта в спринт to (nil - в бэклог).
+// Если keep не nil, задачи, для которых keep возвращает true, остаются в спринте.
+func moveSprintTasksTx(ctx context.Context, tx *sql.Tx, id int, userID int, to *int, keep func(task *models.Task) bool) ([]*models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE sprint_id = $1 AND user_id = $2 AND deleted_at IS NULL ORDER BY id`
+	rows, err := tx.QueryContext(ctx, query, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задач спринта %d: %w", id, err)
+	}
+	var tasks []*models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка сканирования задачи: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по задачам спринта %d: %w", id, err)
+	}
+	var changed []*models.Task
+	for _, task := range tasks {
+		if keep != nil && keep(task) {
+			continue
+		}
+		after, ok, err := changeTaskTx(ctx, tx, task.ID, userID, 0, events.TaskUpdated, setTaskSprint(to))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			changed = append(changed, after)
+		}
+	}
+	return changed, nil
+}
+
+func (s *SprintStore) publishTasks(ctx context.Context, userID int, tasks []*models.Task) {
+	for _, task := range tasks {
+		s.tasks.publish(ctx, events.TaskUpdated, userID, task.ID, task)
+	}
+}
+
+// CloseSprint закрывает спринт. Итоги считаются по задачам спринта на момент закрытия:
+// задачи в выполненных статусах остаются в спринте, остальные переносятся дальше.
+func (s *SprintStore) CloseSprint(ctx context.Context, id int, userID int, nextID int, doneStatuses []string) (*models.Sprint, error) {
+	closeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var sprint *models.Sprint
+	var changed []*models.Task
+	err := withTx(closeCtx, s.db, func(tx *sql.Tx) error {
+		var err error
+		if sprint, err = getSprint(closeCtx, tx, id, userID, "FOR UPDATE"); err != nil {
+			return err
+		}
+		if sprint.ClosedAt != nil {
+			return fmt.Errorf("спринт %d: %w", id, storage.ErrSprintClosed)
+		}
+		next, err := nextSprintTx(closeCtx, tx, sprint, nextID)
+		if err != nil {
+			return err
+		}
+
+		stats := models.SprintStats{DoneStatuses: doneStatuses, RolledOverTo: next}
+		done := func(task *models.Task) bool {
+			for _, status := range doneStatuses {
+				if task.Status == status {
+					return true
+				}
+			}
+			return false
+		}
+		changed, err = moveSprintTasksTx(closeCtx, tx, id, userID, next, func(task *models.Task) bool {
+			estimate := 0.0
+			if task.Estimate != nil {
+				estimate = *task.Estimate
+			}
+			if done(task) {
+				stats.CompletedTasks++
+				stats.CompletedPoints += estimate
+				return true
+			}
+			stats.UnfinishedTasks++
+			stats.UnfinishedPoints += estimate
+			return false
+		})
+		if err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(stats)
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации итогов спринта %d: %w", id, err)
+		}
+		// closed_at совпадает со временем записей журнала о переносе задач (начало транзакции),
+		// поэтому диаграммы закрытого спринта строятся по состоянию до переноса
+		query := `UPDATE sprints SET closed_at = CURRENT_TIMESTAMP, stats = $3 WHERE id = $1 AND user_id = $2 RETURNING ` + sprintColumns
+		sprint, err = scanSprint(tx.QueryRowContext(closeCtx, query, id, userID, raw))
+		if err != nil {
+			return fmt.Errorf("ошибка при закрытии спринта %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishTasks(ctx, userID, changed)
+	return sprint, nil
+}
+
+// nextSprintTx выбирает спринт для невыполненных задач: nextID, если он указан,
+// иначе ближайший по дате начала незакрытый спринт. nil означает бэклог.
+func nextSprintTx(ctx context.Context, tx *sql.Tx, sprint *models.Sprint, nextID int) (*int, error) {
+	if nextID != 0 {
+		if nextID == sprint.ID {
+			return nil, fmt.Errorf("следующий спринт %d: %w", nextID, storage.ErrNotFound)
+		}
+		next, err := getSprint(ctx, tx, nextID, sprint.UserID, "FOR SHARE")
+		if err != nil {
+			return nil, err
+		}
+		if next.ClosedAt != nil {
+			return nil, fmt.Errorf("следующий спринт %d: %w", nextID, storage.ErrSprintClosed)
+		}
+		return &next.ID, nil
+	}
+	query := `SELECT id FROM sprints
+	WHERE user_id = $1 AND id <> $2 AND closed_at IS NULL AND start_date >= $3::date
+	ORDER BY start_date, id LIMIT 1 FOR SHARE`
+	var id int
+	err := tx.QueryRowContext(ctx, query, sprint.UserID, sprint.ID, sprint.StartDate).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при выборе следующего спринта: %w", err)
+	}
+	return &id, nil
+}
+
+// sprintDaysQuery восстанавливает состояние каждой задачи, когда-либо входившей в спринт,
+// на конец каждого дня: для sprint_id, estimate и deleted_at берется последняя запись
+// журнала, затрагивающая поле, а статус - из истории статусов. Для закрытого спринта
+// состояние берется не позже момента закрытия.
+const sprintDaysQuery = `
+WITH days AS (
+	SELECT generate_series($3::date, $4::date, interval '1 day')::date AS day
+), members AS (
+	SELECT DISTINCT task_id FROM task_events
+	WHERE user_id = $2 AND ((after->>'sprint_id')::int = $1 OR (before->>'sprint_id')::int = $1)
+), state AS (
+	SELECT d.day, sp.sprint_id, COALESCE(del.deleted, FALSE) AS deleted, est.estimate,
+		COALESCE(st.status = ANY($6), FALSE) AS done
+	FROM days d
+	CROSS JOIN members m
+	CROSS JOIN LATERAL (SELECT LEAST((d.day + 1)::timestamp AT TIME ZONE 'UTC', $5::timestamptz) AS cutoff) c
+	LEFT JOIN LATERAL (
+		SELECT (e.after->>'sprint_id')::int AS sprint_id FROM task_events e
+		WHERE e.task_id = m.task_id AND (e.before ? 'sprint_id' OR e.after ? 'sprint_id') AND e.created_at < c.cutoff
+		ORDER BY e.id DESC LIMIT 1
+	) sp ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT (e.after->>'estimate')::double precision AS estimate FROM task_events e
+		WHERE e.task_id = m.task_id AND (e.before ? 'estimate' OR e.after ? 'estimate') AND e.created_at < c.cutoff
+		ORDER BY e.id DESC LIMIT 1
+	) est ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT e.after ? 'deleted_at' AS deleted FROM task_events e
+		WHERE e.task_id = m.task_id AND (e.before ? 'deleted_at' OR e.after ? 'deleted_at') AND e.created_at < c.cutoff
+		ORDER BY e.id DESC LIMIT 1
+	) del ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT sc.to_status AS status FROM task_status_changes sc
+		WHERE sc.task_id = m.task_id AND sc.changed_at < c.cutoff
+		ORDER BY sc.changed_at DESC, sc.id DESC LIMIT 1
+	) st ON TRUE
+)
+SELECT d.day,
+	COUNT(s.day) FILTER (WHERE s.sprint_id = $1 AND NOT s.deleted),
+	COALESCE(SUM(s.estimate) FILTER (WHERE s.sprint_id = $1 AND NOT s.deleted), 0),
+	COUNT(s.day) FILTER (WHERE s.sprint_id = $1 AND NOT s.deleted AND s.done),
+	COALESCE(SUM(s.estimate) FILTER (WHERE s.sprint_id = $1 AND NOT s.deleted AND s.done), 0)
+FROM days d
+LEFT JOIN state s ON s.day = d.day
+GROUP BY d.day
+ORDER BY d.day`
+
+// GetSprintDays возвращает данные по дням от начала спринта до окончания,
+// текущего дня или дня закрытия - смотря что раньше.
+func (s *SprintStore) GetSprintDays(ctx context.Context, sprint *models.Sprint, doneStatuses []string) ([]models.SprintDay, error) {
+	from, err := time.Parse("2006-01-02", sprint.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("неверная дата начала спринта %d: %w", sprint.ID, err)
+	}
+	to, err := time.Parse("2006-01-02", sprint.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("неверная дата окончания спринта %d: %w", sprint.ID, err)
+	}
+	last := time.Now().UTC()
+	if sprint.ClosedAt != nil {
+		last = sprint.ClosedAt.UTC()
+	}
+	if last = last.Truncate(24 * time.Hour); last.Before(to) {
+		to = last
+	}
+	days := []models.SprintDay{}
+	if to.Before(from) {
+		return days, nil
+	}
+
+	getCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, sprintDaysQuery, sprint.ID, sprint.UserID, from.Format("2006-01-02"), to.Format("2006-01-02"),
+		sprint.ClosedAt, pq.Array(doneStatuses))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при расчете данных спринта %d: %w", sprint.ID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		var d models.SprintDay
+		if err := rows.Scan(&day, &d.ScopeTasks, &d.ScopePoints, &d.DoneTasks, &d.DonePoints); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования данных спринта: %w", err)
+		}
+		d.Date = day.Format("2006-01-02")
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по данным спринта: %w", err)
+	}
+	return days, nil
+}
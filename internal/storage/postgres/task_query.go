@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"kanban-backend/internal/models"
+)
+
+// FindTasks получает задачи пользователя, кроме задач в корзине, с фильтрами по
+// пользовательским полям. Фильтры eq выполняются через оператор @> и индекс GIN
+// по custom_fields; сравнения и сортировка приводят числовые поля к numeric.
+func (s *TaskStore) FindTasks(ctx context.Context, userID int, query models.TaskQuery) ([]models.Task, error) {
+	args := []interface{}{userID, query.IncludeArchived}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := `user_id = $1 AND deleted_at IS NULL AND ($2 OR archived_at IS NULL)`
+	for _, f := range query.Filters {
+		switch f.Op {
+		case models.FilterEq:
+			var value interface{} = f.Value
+			if f.Type == models.CustomFieldMultiSelect {
+				value = []interface{}{f.Value}
+			}
+			doc, err := json.Marshal(map[string]interface{}{f.Key: value})
+			if err != nil {
+				return nil, fmt.Errorf("ошибка сериализации фильтра по полю %s: %w", f.Key, err)
+			}
+			where += ` AND custom_fields @> ` + arg(string(doc)) + `::jsonb`
+		case models.FilterGte, models.FilterLte:
+			op := ">="
+			if f.Op == models.FilterLte {
+				op = "<="
+			}
+			where += fmt.Sprintf(` AND %s %s %s`, customFieldExpr(arg(f.Key), f.Type), op, arg(f.Value))
+		default:
+			return nil, fmt.Errorf("неизвестный оператор фильтра %q", f.Op)
+		}
+	}
+	order := `created_at DESC`
+	if query.Sort != nil {
+		dir := "ASC"
+		if query.Sort.Desc {
+			dir = "DESC"
+		}
+		order = fmt.Sprintf(`%s %s NULLS LAST, created_at DESC`, customFieldExpr(arg(query.Sort.Key), query.Sort.Type), dir)
+	}
+	return s.queryTasks(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+where+` ORDER BY `+order, args...)
+}
+
+// customFieldExpr возвращает выражение значения поля с ключом из параметра key.
+// Значения проверяются при записи, поэтому приведение к numeric не завершается ошибкой.
+func customFieldExpr(key string, fieldType string) string {
+	switch fieldType {
+	case models.CustomFieldNumber, models.CustomFieldUser:
+		return `(custom_fields->>` + key + `::text)::numeric`
+	}
+	return `(custom_fields->>` + key + `::text)`
+}
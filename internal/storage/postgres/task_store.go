@@ -3,18 +3,23 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log" // Используем стандартный логгер для простоты
 	"time"
 
+	"kanban-backend/internal/events"
 	"kanban-backend/internal/models" // Замените на свой путь
+	"kanban-backend/internal/storage"
 
 	_ "github.com/lib/pq" // Драйвер PostgreSQL
 )
 
 // TaskStore реализует интерфейс storage.TaskStore для PostgreSQL.
 type TaskStore struct {
-	db *sql.DB
+	db        *sql.DB
+	publisher events.Publisher
 }
 
 // NewTaskStore создает новый экземпляр TaskStore.
@@ -57,6 +62,26 @@ func (s *TaskStore) Connect(ctx context.Context, dsn string) error {
 	return fmt.Errorf("не удалось подключиться к PostgreSQL после %d попыток: %w", maxRetries, err)
 }
 
+// SetPublisher задает получателя событий об изменении задач.
+func (s *TaskStore) SetPublisher(p events.Publisher) {
+	s.publisher = p
+}
+
+// publish отправляет событие после успешной записи. Ошибка публикации
+// не отменяет уже выполненное изменение, поэтому только логируется.
+func (s *TaskStore) publish(ctx context.Context, eventType string, userID, taskID int, data interface{}) {
+	if s.publisher == nil {
+		return
+	}
+	e, err := events.New(eventType, userID, taskID, data)
+	if err == nil {
+		err = s.publisher.Publish(ctx, e)
+	}
+	if err != nil {
+		log.Printf("Не удалось опубликовать событие %s для задачи %d: %v", eventType, taskID, err)
+	}
+}
+
 // Close закрывает соединение с базой данных.
 func (s *TaskStore) Close() error {
 	if s.db != nil {
@@ -75,7 +100,51 @@ func (s *TaskStore) Migrate(ctx context.Context) error {
         description TEXT,
         status VARCHAR(50) DEFAULT 'pending',
         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-    );`
+    );
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_at TIMESTAMP WITH TIME ZONE;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+    CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks(deleted_at) WHERE deleted_at IS NOT NULL;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS rrule TEXT NOT NULL DEFAULT '';
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_start TIMESTAMP WITH TIME ZONE;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence_created BOOLEAN NOT NULL DEFAULT FALSE;
+    CREATE INDEX IF NOT EXISTS idx_tasks_recurring ON tasks(due_at) WHERE rrule <> '' AND NOT occurrence_created;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate DOUBLE PRECISION;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sprint_id INTEGER;
+    CREATE INDEX IF NOT EXISTS idx_tasks_sprint_id ON tasks(sprint_id) WHERE sprint_id IS NOT NULL;
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+    CREATE INDEX IF NOT EXISTS idx_tasks_custom_fields ON tasks USING GIN (custom_fields jsonb_path_ops);
+    CREATE TABLE IF NOT EXISTS task_events (
+        id BIGSERIAL PRIMARY KEY,
+        task_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        actor_id INTEGER NOT NULL,
+        type VARCHAR(64) NOT NULL,
+        before JSONB,
+        after JSONB,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id, id);
+    CREATE INDEX IF NOT EXISTS idx_task_events_user_id ON task_events(user_id, id);
+    CREATE TABLE IF NOT EXISTS task_status_changes (
+        id BIGSERIAL PRIMARY KEY,
+        task_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        from_status VARCHAR(50),
+        to_status VARCHAR(50) NOT NULL,
+        changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_task_status_changes_task_id ON task_status_changes(task_id, changed_at);
+    CREATE INDEX IF NOT EXISTS idx_task_status_changes_user_id ON task_status_changes(user_id, changed_at);
+    -- История статусов переживает окончательное удаление задачи из корзины: на ней построены метрики за прошлые периоды
+    ALTER TABLE task_status_changes DROP CONSTRAINT IF EXISTS task_status_changes_task_id_fkey;
+    -- История статусов до появления таблицы восстанавливается из журнала изменений
+    INSERT INTO task_status_changes (task_id, user_id, from_status, to_status, changed_at)
+    SELECT e.task_id, e.user_id, e.before->>'status', e.after->>'status', e.created_at
+    FROM task_events e JOIN tasks t ON t.id = e.task_id
+    WHERE e.after ? 'status' AND NOT EXISTS (SELECT 1 FROM task_status_changes)
+    ORDER BY e.id;`
 
 	migrateCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -88,30 +157,152 @@ func (s *TaskStore) Migrate(ctx context.Context) error {
 	return nil
 }
 
-// CreateTask добавляет новую задачу в базу данных.
+// CreateTask добавляет новую задачу в базу данных вместе с записью в журнале изменений.
 func (s *TaskStore) CreateTask(ctx context.Context, task *models.Task) (int, error) {
-	query := `INSERT INTO tasks (title, description, status, user_id) VALUES ($1, $2, $3, $4) RETURNING id`
-	if task.Status == "" {
-		task.Status = "pending"
-	}
 	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	err := s.db.QueryRowContext(createCtx, query, task.Title, task.Description, task.Status, task.UserID).Scan(&task.ID)
+	err := withTx(createCtx, s.db, func(tx *sql.Tx) error {
+		return insertTaskTx(createCtx, tx, task)
+	})
 	if err != nil {
-		return 0, fmt.Errorf("ошибка при создании задачи: %w", err)
+		return 0, err
 	}
 	log.Printf("Задача создана с ID: %d", task.ID)
+	s.publish(ctx, events.TaskCreated, task.UserID, task.ID, task)
 	return task.ID, nil
 }
 
-// GetTaskByID получает задачу по ее ID и user_id.
-func (s *TaskStore) GetTaskByID(ctx context.Context, id int, userID int) (*models.Task, error) {
-	query := `SELECT id, title, description, status, user_id FROM tasks WHERE id = $1 AND user_id = $2`
+// insertTaskTx добавляет задачу, запись в журнале изменений и доставки webhook в транзакции tx.
+func insertTaskTx(ctx context.Context, tx *sql.Tx, task *models.Task) error {
+	query := `INSERT INTO tasks (title, description, status, user_id, archived_at, due_at, rrule, recurrence_start, estimate, sprint_id, custom_fields)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, version`
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+	if task.RRule != "" && task.RecurrenceStart == nil {
+		task.RecurrenceStart = task.DueAt
+	}
+	customFields, err := marshalCustomFields(task.CustomFields)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, query, task.Title, task.Description, task.Status, task.UserID, task.ArchivedAt,
+		task.DueAt, task.RRule, task.RecurrenceStart, task.Estimate, task.SprintID, customFields).Scan(&task.ID, &task.Version)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании задачи: %w", err)
+	}
+	if err := recordActivity(ctx, tx, events.TaskCreated, task.UserID, nil, task); err != nil {
+		return err
+	}
+	return enqueueWebhookDeliveries(ctx, tx, events.TaskCreated, task)
+}
+
+// ImportTasks добавляет задачи пользователя одной транзакцией: либо все, либо ни одной.
+func (s *TaskStore) ImportTasks(ctx context.Context, userID int, tasks []models.Task) error {
+	importCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	err := withTx(importCtx, s.db, func(tx *sql.Tx) error {
+		for i := range tasks {
+			tasks[i].UserID = userID
+			if err := insertTaskTx(importCtx, tx, &tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Импортировано задач: %d", len(tasks))
+	for i := range tasks {
+		s.publish(ctx, events.TaskCreated, userID, tasks[i].ID, tasks[i])
+	}
+	return nil
+}
+
+// ExportTasks передает в fn задачи пользователя, кроме задач в корзине, по одной,
+// не загружая весь список в память. Архивные задачи включаются.
+func (s *TaskStore) ExportTasks(ctx context.Context, userID int, fn func(task *models.Task) error) error {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при экспорте задач: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return fmt.Errorf("ошибка сканирования задачи: %w", err)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при итерации по задачам: %w", err)
+	}
+	return nil
+}
+
+// taskColumns - столбцы задачи в порядке, ожидаемом scanTask.
+const taskColumns = `id, title, description, status, user_id, archived_at, deleted_at, version, due_at, rrule, recurrence_start, estimate, sprint_id, custom_fields`
+
+func scanTask(row interface{ Scan(...interface{}) error }) (*models.Task, error) {
 	task := &models.Task{}
+	var archivedAt, deletedAt, dueAt, recurrenceStart sql.NullTime
+	var estimate sql.NullFloat64
+	var sprintID sql.NullInt64
+	var customFields []byte
+	if err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.UserID, &archivedAt, &deletedAt, &task.Version,
+		&dueAt, &task.RRule, &recurrenceStart, &estimate, &sprintID, &customFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(customFields, &task.CustomFields); err != nil {
+		return nil, fmt.Errorf("ошибка чтения пользовательских полей задачи %d: %w", task.ID, err)
+	}
+	if len(task.CustomFields) == 0 {
+		task.CustomFields = nil
+	}
+	if archivedAt.Valid {
+		task.ArchivedAt = &archivedAt.Time
+	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+	if dueAt.Valid {
+		task.DueAt = &dueAt.Time
+	}
+	if recurrenceStart.Valid {
+		task.RecurrenceStart = &recurrenceStart.Time
+	}
+	if estimate.Valid {
+		task.Estimate = &estimate.Float64
+	}
+	if sprintID.Valid {
+		id := int(sprintID.Int64)
+		task.SprintID = &id
+	}
+	return task, nil
+}
+
+// marshalCustomFields сериализует значения пользовательских полей; nil сохраняется как {}.
+func marshalCustomFields(values map[string]interface{}) ([]byte, error) {
+	if values == nil {
+		return []byte("{}"), nil
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации пользовательских полей: %w", err)
+	}
+	return raw, nil
+}
+
+// GetTaskByID получает задачу по ее ID и user_id. Задачи в корзине не возвращаются.
+func (s *TaskStore) GetTaskByID(ctx context.Context, id int, userID int) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
 	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	row := s.db.QueryRowContext(getCtx, query, id, userID)
-	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.UserID)
+	task, err := scanTask(s.db.QueryRowContext(getCtx, query, id, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("задача с ID %d не найдена: %w", id, err)
@@ -121,25 +312,34 @@ func (s *TaskStore) GetTaskByID(ctx context.Context, id int, userID int) (*model
 	return task, nil
 }
 
-// GetAllTasks получает все задачи пользователя из базы данных.
-func (s *TaskStore) GetAllTasks(ctx context.Context, userID int) ([]models.Task, error) {
-	query := `SELECT id, title, description, status, user_id FROM tasks WHERE user_id = $1 ORDER BY created_at DESC`
+// GetAllTasks получает все задачи пользователя из базы данных, кроме задач в корзине.
+// Архивные задачи возвращаются только при includeArchived.
+func (s *TaskStore) GetAllTasks(ctx context.Context, userID int, includeArchived bool) ([]models.Task, error) {
+	return s.FindTasks(ctx, userID, models.TaskQuery{IncludeArchived: includeArchived})
+}
+
+// GetTrash получает задачи пользователя, находящиеся в корзине, начиная с недавно удаленных.
+func (s *TaskStore) GetTrash(ctx context.Context, userID int) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+	return s.queryTasks(ctx, query, userID)
+}
+
+func (s *TaskStore) queryTasks(ctx context.Context, query string, args ...interface{}) ([]models.Task, error) {
 	tasks := []models.Task{}
 	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	rows, err := s.db.QueryContext(getCtx, query, userID)
+	rows, err := s.db.QueryContext(getCtx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении списка задач: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.UserID)
+		task, err := scanTask(rows)
 		if err != nil {
 			log.Printf("Ошибка сканирования строки задачи: %v", err)
 			continue
 		}
-		tasks = append(tasks, task)
+		tasks = append(tasks, *task)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("ошибка при итерации по результатам задач: %w", err)
@@ -147,26 +347,222 @@ func (s *TaskStore) GetAllTasks(ctx context.Context, userID int) ([]models.Task,
 	return tasks, nil
 }
 
-// DeleteTask удаляет задачу по ее ID и user_id.
-func (s *TaskStore) DeleteTask(ctx context.Context, id int, userID int) error {
-	query := `DELETE FROM tasks WHERE id = $1 AND user_id = $2`
-	deleteCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+// errNoChange возвращается из функции изменения changeTask, если задача уже в нужном состоянии.
+var errNoChange = errors.New("задача не изменилась")
+
+// changeTask изменяет задачу в отдельной транзакции с помощью changeTaskTx
+// и после фиксации публикует событие.
+func (s *TaskStore) changeTask(ctx context.Context, id int, userID int, version int, eventType string, change func(task *models.Task) error) (*models.Task, error) {
+	changeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	result, err := s.db.ExecContext(deleteCtx, query, id, userID)
+	var after *models.Task
+	var changed bool
+	err := withTx(changeCtx, s.db, func(tx *sql.Tx) error {
+		var err error
+		after, changed, err = changeTaskTx(changeCtx, tx, id, userID, version, eventType, change)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка при удалении задачи %d: %w", id, err)
+		return nil, err
+	}
+	if changed {
+		s.publish(ctx, eventType, userID, id, after)
 	}
-	rowsAffected, err := result.RowsAffected()
+	return after, nil
+}
+
+// changeTaskTx изменяет задачу в транзакции tx: блокирует строку, применяет change к копии,
+// сохраняет результат, пишет запись в журнал изменений и ставит в очередь доставки webhook. changed равен false,
+// если change вернула errNoChange. Если version не равен 0, изменение выполняется
+// только для этой версии задачи, иначе возвращается storage.ErrVersionConflict.
+func changeTaskTx(ctx context.Context, tx *sql.Tx, id int, userID int, version int, eventType string, change func(task *models.Task) error) (*models.Task, bool, error) {
+	before, err := scanTask(tx.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 AND user_id = $2 FOR UPDATE`, id, userID))
 	if err != nil {
-		log.Printf("Не удалось получить количество удаленных строк для задачи %d: %v", id, err)
+		if err == sql.ErrNoRows {
+			return nil, false, fmt.Errorf("задача с ID %d не найдена: %w", id, storage.ErrNotFound)
+		}
+		return nil, false, fmt.Errorf("ошибка при получении задачи %d: %w", id, err)
+	}
+	updated, err := applyTaskChange(before, version, change)
+	if errors.Is(err, errNoChange) {
+		return before, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if version == 0 {
+		version = before.Version
+	}
+	query := `UPDATE tasks SET title = $3, description = $4, status = $5, archived_at = $6, deleted_at = $7, due_at = $8,
+	rrule = $9, recurrence_start = $10, estimate = $11, sprint_id = $12, custom_fields = $13, version = version + 1
+	WHERE id = $1 AND user_id = $2 AND version = $14 RETURNING version`
+	customFields, err := marshalCustomFields(updated.CustomFields)
+	if err != nil {
+		return nil, false, err
+	}
+	err = tx.QueryRowContext(ctx, query, id, userID, updated.Title, updated.Description, updated.Status,
+		updated.ArchivedAt, updated.DeletedAt, updated.DueAt, updated.RRule, updated.RecurrenceStart,
+		updated.Estimate, updated.SprintID, customFields, version).Scan(&updated.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, fmt.Errorf("задача %d, версия %d: %w", id, version, storage.ErrVersionConflict)
+		}
+		return nil, false, fmt.Errorf("ошибка при изменении задачи %d: %w", id, err)
+	}
+	if err := recordActivity(ctx, tx, eventType, userID, before, updated); err != nil {
+		return nil, false, err
+	}
+	if err := enqueueWebhookDeliveries(ctx, tx, eventType, updated); err != nil {
+		return nil, false, err
+	}
+	return updated, true, nil
+}
+
+// applyTaskChange применяет change к копии задачи, если version равен 0 или текущей версии.
+// Версия сверяется до change: иначе устаревший If-Match к задаче в корзине
+// получил бы ошибку change (404) вместо storage.ErrVersionConflict.
+func applyTaskChange(before *models.Task, version int, change func(task *models.Task) error) (*models.Task, error) {
+	if version != 0 && before.Version != version {
+		return nil, fmt.Errorf("задача %d, версия %d: %w", before.ID, version, storage.ErrVersionConflict)
+	}
+	updated := *before
+	if err := change(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// notFoundIfDeleted - задачи в корзине недоступны для изменений, кроме восстановления.
+func notFoundIfDeleted(task *models.Task) error {
+	if task.DeletedAt != nil {
+		return fmt.Errorf("задача с ID %d не найдена: %w", task.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// updateTask возвращает изменение, заменяющее редактируемые поля задачи.
+// Поля со значением nil не меняются.
+func updateTask(title, description, status *string) func(task *models.Task) error {
+	return func(task *models.Task) error {
+		if err := notFoundIfDeleted(task); err != nil {
+			return err
+		}
+		if title != nil {
+			task.Title = *title
+		}
+		if description != nil {
+			task.Description = *description
+		}
+		if status != nil && *status != "" {
+			task.Status = *status
+		}
+		return nil
+	}
+}
+
+func deleteTask(task *models.Task) error {
+	if err := notFoundIfDeleted(task); err != nil {
+		return err
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("задача с ID %d не найдена для удаления", id)
+	now := time.Now()
+	task.DeletedAt = &now
+	return nil
+}
+
+func restoreTask(task *models.Task) error {
+	if task.DeletedAt == nil {
+		return errNoChange
+	}
+	task.DeletedAt = nil
+	return nil
+}
+
+func archiveTask(task *models.Task) error {
+	if err := notFoundIfDeleted(task); err != nil {
+		return err
+	}
+	if task.ArchivedAt != nil {
+		return errNoChange
+	}
+	now := time.Now()
+	task.ArchivedAt = &now
+	return nil
+}
+
+func unarchiveTask(task *models.Task) error {
+	if err := notFoundIfDeleted(task); err != nil {
+		return err
+	}
+	if task.ArchivedAt == nil {
+		return errNoChange
+	}
+	task.ArchivedAt = nil
+	return nil
+}
+
+// UpdateTask заменяет редактируемые поля задачи значениями из update.
+func (s *TaskStore) UpdateTask(ctx context.Context, id int, userID int, version int, update models.TaskUpdatePayload) (*models.Task, error) {
+	change := updateTask(&update.Title, &update.Description, &update.Status)
+	return s.changeTask(ctx, id, userID, version, events.TaskUpdated, func(task *models.Task) error {
+		if err := change(task); err != nil {
+			return err
+		}
+		// Новое правило или срок начинают серию повторений заново
+		if task.RRule != update.RRule || !equalTimes(task.DueAt, update.DueAt) {
+			task.RecurrenceStart = nil
+			if update.RRule != "" {
+				task.RecurrenceStart = update.DueAt
+			}
+		}
+		task.DueAt = update.DueAt
+		task.RRule = update.RRule
+		task.Estimate = update.Estimate
+		task.CustomFields = update.CustomFields
+		return nil
+	})
+}
+
+// DeleteTask перемещает задачу в корзину. Окончательно задача удаляется PurgeDeleted
+// по истечении срока хранения.
+func (s *TaskStore) DeleteTask(ctx context.Context, id int, userID int, version int) error {
+	if _, err := s.changeTask(ctx, id, userID, version, events.TaskDeleted, deleteTask); err != nil {
+		return err
 	}
-	log.Printf("Задача с ID %d удалена", id)
+	log.Printf("Задача с ID %d перемещена в корзину", id)
 	return nil
 }
 
+// RestoreTask возвращает задачу из корзины.
+func (s *TaskStore) RestoreTask(ctx context.Context, id int, userID int, version int) (*models.Task, error) {
+	return s.changeTask(ctx, id, userID, version, events.TaskRestored, restoreTask)
+}
+
+// ArchiveTask скрывает задачу из списка задач, не удаляя ее.
+func (s *TaskStore) ArchiveTask(ctx context.Context, id int, userID int, version int) (*models.Task, error) {
+	return s.changeTask(ctx, id, userID, version, events.TaskArchived, archiveTask)
+}
+
+// UnarchiveTask возвращает архивную задачу в список задач.
+func (s *TaskStore) UnarchiveTask(ctx context.Context, id int, userID int, version int) (*models.Task, error) {
+	return s.changeTask(ctx, id, userID, version, events.TaskUnarchived, unarchiveTask)
+}
+
+// PurgeDeleted окончательно удаляет задачи, находящиеся в корзине дольше retention.
+// Записи журнала изменений и история статусов удаленных задач сохраняются.
+func (s *TaskStore) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	purgeCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := s.db.ExecContext(purgeCtx, `DELETE FROM tasks WHERE deleted_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при очистке корзины: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при очистке корзины: %w", err)
+	}
+	return n, nil
+}
+
 func (s *TaskStore) DB() *sql.DB {
 	return s.db
 }
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// Устаревший If-Match к задаче в корзине - конфликт версий (412), а не 404 от deleteTask.
+func TestApplyTaskChangeChecksVersionBeforeChange(t *testing.T) {
+	deletedAt := time.Now()
+	deleted := &models.Task{ID: 1, Title: "Задача", Version: 3, DeletedAt: &deletedAt}
+
+	for _, change := range []func(*models.Task) error{deleteTask, archiveTask, updateTask(nil, nil, nil)} {
+		if _, err := applyTaskChange(deleted, 2, change); !errors.Is(err, storage.ErrVersionConflict) {
+			t.Errorf("устаревшая версия: ошибка %v, ожидался ErrVersionConflict", err)
+		}
+		if _, err := applyTaskChange(deleted, 3, change); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("текущая версия: ошибка %v, ожидался ErrNotFound", err)
+		}
+		if _, err := applyTaskChange(deleted, 0, change); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("без If-Match: ошибка %v, ожидался ErrNotFound", err)
+		}
+	}
+
+	// Повторное восстановление без изменений тоже не проходит с устаревшей версией
+	active := &models.Task{ID: 2, Title: "Задача", Version: 5}
+	if _, err := applyTaskChange(active, 4, restoreTask); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("restore с устаревшей версией: ошибка %v, ожидался ErrVersionConflict", err)
+	}
+	if _, err := applyTaskChange(active, 5, restoreTask); !errors.Is(err, errNoChange) {
+		t.Errorf("restore активной задачи: ошибка %v, ожидался errNoChange", err)
+	}
+
+	updated, err := applyTaskChange(active, 5, archiveTask)
+	if err != nil || updated.ArchivedAt == nil || active.ArchivedAt != nil {
+		t.Errorf("archive должен изменить только копию задачи: %+v, %v", updated, err)
+	}
+}
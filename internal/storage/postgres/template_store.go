@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// TemplateStore реализует storage.TemplateStore для PostgreSQL.
+// Задачи шаблона хранятся одним JSONB-документом: они всегда читаются и пишутся целиком.
+type TemplateStore struct {
+	db *sql.DB
+}
+
+// NewTemplateStore создает новый экземпляр TemplateStore.
+func NewTemplateStore(db *sql.DB) *TemplateStore {
+	return &TemplateStore{db: db}
+}
+
+// Migrate создает таблицу шаблонов, если она не существует.
+func (s *TemplateStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS templates (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		tasks JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_templates_user_id ON templates(user_id);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+func marshalTemplateTasks(tasks []models.TemplateTask) ([]byte, error) {
+	if tasks == nil {
+		tasks = []models.TemplateTask{}
+	}
+	raw, err := json.Marshal(tasks)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации задач шаблона: %w", err)
+	}
+	return raw, nil
+}
+
+// CreateTemplate добавляет новый шаблон.
+func (s *TemplateStore) CreateTemplate(ctx context.Context, tpl *models.Template) (int, error) {
+	tasks, err := marshalTemplateTasks(tpl.Tasks)
+	if err != nil {
+		return 0, err
+	}
+	query := `INSERT INTO templates (user_id, name, description, tasks) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = s.db.QueryRowContext(createCtx, query, tpl.UserID, tpl.Name, tpl.Description, tasks).Scan(&tpl.ID, &tpl.CreatedAt, &tpl.UpdatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании шаблона: %w", err)
+	}
+	return tpl.ID, nil
+}
+
+const templateColumns = `id, user_id, name, description, tasks, created_at, updated_at`
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*models.Template, error) {
+	tpl := &models.Template{}
+	var tasks []byte
+	if err := row.Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.Description, &tasks, &tpl.CreatedAt, &tpl.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tasks, &tpl.Tasks); err != nil {
+		return nil, fmt.Errorf("ошибка чтения задач шаблона %d: %w", tpl.ID, err)
+	}
+	return tpl, nil
+}
+
+// GetTemplates возвращает шаблоны пользователя.
+func (s *TemplateStore) GetTemplates(ctx context.Context, userID int) ([]models.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE user_id = $1 ORDER BY name, id`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении шаблонов: %w", err)
+	}
+	defer rows.Close()
+	templates := []models.Template{}
+	for rows.Next() {
+		tpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования шаблона: %w", err)
+		}
+		templates = append(templates, *tpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по шаблонам: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate возвращает шаблон пользователя по ID.
+func (s *TemplateStore) GetTemplate(ctx context.Context, id int, userID int) (*models.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE id = $1 AND user_id = $2`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tpl, err := scanTemplate(s.db.QueryRowContext(getCtx, query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("шаблон %d: %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении шаблона %d: %w", id, err)
+	}
+	return tpl, nil
+}
+
+// UpdateTemplate заменяет название, описание и задачи шаблона.
+func (s *TemplateStore) UpdateTemplate(ctx context.Context, tpl *models.Template) error {
+	tasks, err := marshalTemplateTasks(tpl.Tasks)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE templates SET name = $3, description = $4, tasks = $5, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND user_id = $2 RETURNING created_at, updated_at`
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = s.db.QueryRowContext(updateCtx, query, tpl.ID, tpl.UserID, tpl.Name, tpl.Description, tasks).Scan(&tpl.CreatedAt, &tpl.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("шаблон %d: %w", tpl.ID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при изменении шаблона %d: %w", tpl.ID, err)
+	}
+	return nil
+}
+
+// DeleteTemplate удаляет шаблон. Задачи, созданные из него, не затрагиваются.
+func (s *TemplateStore) DeleteTemplate(ctx context.Context, id int, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(deleteCtx, `DELETE FROM templates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении шаблона %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("шаблон %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
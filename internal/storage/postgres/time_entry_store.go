@@ -0,0 +1,240 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// TimeEntryStore реализует storage.TimeStore для PostgreSQL.
+// Запущенный таймер - запись без ended_at; уникальный частичный индекс
+// гарантирует не больше одного запущенного таймера на пользователя.
+type TimeEntryStore struct {
+	db *sql.DB
+}
+
+// NewTimeEntryStore создает новый экземпляр TimeEntryStore.
+func NewTimeEntryStore(db *sql.DB) *TimeEntryStore {
+	return &TimeEntryStore{db: db}
+}
+
+// Migrate создает таблицу записей времени, если она не существует.
+func (s *TimeEntryStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS time_entries (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		task_id INTEGER NOT NULL,
+		started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		ended_at TIMESTAMP WITH TIME ZONE,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		CHECK (ended_at IS NULL OR ended_at >= started_at)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_time_entries_user_id ON time_entries(user_id, started_at);
+	CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id, started_at);
+	-- Учтенное время остается в табелях после окончательного удаления задачи из корзины,
+	-- поэтому название задачи копируется в запись
+	ALTER TABLE time_entries DROP CONSTRAINT IF EXISTS time_entries_task_id_fkey;
+	ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS task_title VARCHAR(255) NOT NULL DEFAULT '';
+	UPDATE time_entries e SET task_title = t.title FROM tasks t WHERE t.id = e.task_id AND e.task_title = '';`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// timeEntrySelect читает записи из e (таблица или CTE с ее столбцами) вместе с названием задачи.
+// Для задачи, удаленной окончательно, берется название, сохраненное в записи.
+const timeEntrySelect = `SELECT e.id, e.user_id, e.task_id, COALESCE(t.title, e.task_title), e.started_at, e.ended_at,
+	EXTRACT(EPOCH FROM COALESCE(e.ended_at, CURRENT_TIMESTAMP) - e.started_at)::bigint, e.note
+	FROM e LEFT JOIN tasks t ON t.id = e.task_id`
+
+func scanTimeEntry(row interface{ Scan(...interface{}) error }) (*models.TimeEntry, error) {
+	entry := &models.TimeEntry{}
+	var endedAt sql.NullTime
+	if err := row.Scan(&entry.ID, &entry.UserID, &entry.TaskID, &entry.TaskTitle, &entry.StartedAt, &endedAt,
+		&entry.DurationSeconds, &entry.Note); err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		entry.EndedAt = &endedAt.Time
+	}
+	entry.Running = entry.EndedAt == nil
+	return entry, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// StartTimer запускает таймер; задача должна принадлежать пользователю и не быть в корзине.
+func (s *TimeEntryStore) StartTimer(ctx context.Context, userID int, taskID int, note string) (*models.TimeEntry, error) {
+	query := `WITH e AS (
+		INSERT INTO time_entries (user_id, task_id, task_title, started_at, note)
+		SELECT $1, id, title, CURRENT_TIMESTAMP, $3 FROM tasks WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL
+		RETURNING *
+	) ` + timeEntrySelect
+	startCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(s.db.QueryRowContext(startCtx, query, userID, taskID, note))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("задача с ID %d не найдена: %w", taskID, storage.ErrNotFound)
+	case isUniqueViolation(err):
+		return nil, storage.ErrTimerRunning
+	case err != nil:
+		return nil, fmt.Errorf("ошибка при запуске таймера по задаче %d: %w", taskID, err)
+	}
+	return entry, nil
+}
+
+// StopTimer останавливает запущенный таймер по задаче.
+func (s *TimeEntryStore) StopTimer(ctx context.Context, userID int, taskID int) (*models.TimeEntry, error) {
+	query := `WITH e AS (
+		UPDATE time_entries SET ended_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND task_id = $2 AND ended_at IS NULL
+		RETURNING *
+	) ` + timeEntrySelect
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(s.db.QueryRowContext(stopCtx, query, userID, taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("таймер по задаче %d: %w", taskID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при остановке таймера по задаче %d: %w", taskID, err)
+	}
+	return entry, nil
+}
+
+// GetRunningTimer возвращает запущенный таймер пользователя.
+func (s *TimeEntryStore) GetRunningTimer(ctx context.Context, userID int) (*models.TimeEntry, error) {
+	query := `WITH e AS (SELECT * FROM time_entries WHERE user_id = $1 AND ended_at IS NULL) ` + timeEntrySelect
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(s.db.QueryRowContext(getCtx, query, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("запущенный таймер: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении запущенного таймера: %w", err)
+	}
+	return entry, nil
+}
+
+// CreateTimeEntry добавляет ручную запись времени по задаче пользователя.
+func (s *TimeEntryStore) CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+	query := `WITH e AS (
+		INSERT INTO time_entries (user_id, task_id, task_title, started_at, ended_at, note)
+		SELECT $1, id, title, $3, $4, $5 FROM tasks WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL
+		RETURNING *
+	) ` + timeEntrySelect
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	created, err := scanTimeEntry(s.db.QueryRowContext(createCtx, query, entry.UserID, entry.TaskID, entry.StartedAt, entry.EndedAt, entry.Note))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("задача с ID %d не найдена: %w", entry.TaskID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при создании записи времени: %w", err)
+	}
+	*entry = *created
+	return nil
+}
+
+// UpdateTimeEntry заменяет интервал и заметку записи. Запущенный таймер
+// при этом останавливается, так как ended_at обязателен.
+func (s *TimeEntryStore) UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error {
+	query := `WITH e AS (
+		UPDATE time_entries SET started_at = $3, ended_at = $4, note = $5
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	) ` + timeEntrySelect
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	updated, err := scanTimeEntry(s.db.QueryRowContext(updateCtx, query, entry.ID, entry.UserID, entry.StartedAt, entry.EndedAt, entry.Note))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("запись времени %d: %w", entry.ID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при изменении записи времени %d: %w", entry.ID, err)
+	}
+	*entry = *updated
+	return nil
+}
+
+// DeleteTimeEntry удаляет запись времени, в том числе запущенный таймер.
+func (s *TimeEntryStore) DeleteTimeEntry(ctx context.Context, id int64, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(deleteCtx, `DELETE FROM time_entries WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении записи времени %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("запись времени %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// GetTaskTime возвращает записи времени по задаче и их сумму.
+func (s *TimeEntryStore) GetTaskTime(ctx context.Context, taskID int, userID int) (*models.TaskTime, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var exists bool
+	err := s.db.QueryRowContext(getCtx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)`,
+		taskID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задачи %d: %w", taskID, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("задача с ID %d не найдена: %w", taskID, storage.ErrNotFound)
+	}
+	query := `WITH e AS (SELECT * FROM time_entries WHERE task_id = $1 AND user_id = $2) ` + timeEntrySelect + ` ORDER BY e.started_at, e.id`
+	entries, err := s.queryTimeEntries(getCtx, query, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	total := &models.TaskTime{TaskID: taskID, Entries: entries}
+	for _, e := range entries {
+		total.TotalSeconds += e.DurationSeconds
+	}
+	return total, nil
+}
+
+// GetTimeEntries возвращает записи пользователя, начатые в [from, to).
+func (s *TimeEntryStore) GetTimeEntries(ctx context.Context, userID int, from, to time.Time) ([]models.TimeEntry, error) {
+	query := `WITH e AS (SELECT * FROM time_entries WHERE user_id = $1 AND started_at >= $2 AND started_at < $3) ` +
+		timeEntrySelect + ` ORDER BY e.started_at, e.id`
+	getCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return s.queryTimeEntries(getCtx, query, userID, from, to)
+}
+
+func (s *TimeEntryStore) queryTimeEntries(ctx context.Context, query string, args ...interface{}) ([]models.TimeEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении записей времени: %w", err)
+	}
+	defer rows.Close()
+	entries := []models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи времени: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по записям времени: %w", err)
+	}
+	return entries, nil
+}
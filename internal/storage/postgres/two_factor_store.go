@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// TwoFactorStore реализует storage.TwoFactorStore для PostgreSQL.
+type TwoFactorStore struct {
+	db *sql.DB
+}
+
+// NewTwoFactorStore создает новый экземпляр TwoFactorStore.
+func NewTwoFactorStore(db *sql.DB) *TwoFactorStore {
+	return &TwoFactorStore{db: db}
+}
+
+// Migrate создает таблицы секретов TOTP и кодов восстановления, если они не существуют.
+// Секрет хранится открыто: он нужен для проверки кодов, а доступ к базе и так дает полный доступ к данным.
+func (s *TwoFactorStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS user_totp (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		confirmed_at TIMESTAMP WITH TIME ZONE,
+		last_counter BIGINT NOT NULL DEFAULT 0,
+		failed_attempts INTEGER NOT NULL DEFAULT 0,
+		locked_until TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS user_recovery_codes (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash CHAR(64) NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY (user_id, code_hash)
+	);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// GetTOTP возвращает секрет пользователя.
+func (s *TwoFactorStore) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	query := `SELECT user_id, secret, confirmed_at, last_counter, failed_attempts, locked_until FROM user_totp WHERE user_id = $1`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	t := &models.TOTP{}
+	var confirmedAt, lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(getCtx, query, userID).
+		Scan(&t.UserID, &t.Secret, &confirmedAt, &t.LastCounter, &t.FailedAttempts, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("2FA пользователя %d: %w", userID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении 2FA пользователя %d: %w", userID, err)
+	}
+	if confirmedAt.Valid {
+		t.ConfirmedAt = &confirmedAt.Time
+	}
+	if lockedUntil.Valid {
+		t.LockedUntil = &lockedUntil.Time
+	}
+	return t, nil
+}
+
+// StartTOTPEnrollment сохраняет неподтвержденный секрет.
+func (s *TwoFactorStore) StartTOTPEnrollment(ctx context.Context, userID int, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_counter = 0, failed_attempts = 0,
+		locked_until = NULL, created_at = CURRENT_TIMESTAMP
+	WHERE user_totp.confirmed_at IS NULL`
+	startCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	res, err := s.db.ExecContext(startCtx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении секрета 2FA пользователя %d: %w", userID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении секрета 2FA пользователя %d: %w", userID, err)
+	}
+	if n == 0 {
+		return storage.ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// ConfirmTOTP включает 2FA и сохраняет коды восстановления в одной транзакции.
+func (s *TwoFactorStore) ConfirmTOTP(ctx context.Context, userID int, counter int64, recoveryHashes []string) error {
+	confirmCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(confirmCtx, s.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(confirmCtx, `UPDATE user_totp SET confirmed_at = CURRENT_TIMESTAMP, last_counter = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL`, userID, counter)
+		if err != nil {
+			return fmt.Errorf("ошибка при включении 2FA пользователя %d: %w", userID, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("ошибка при включении 2FA пользователя %d: %w", userID, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("подключение 2FA пользователя %d: %w", userID, storage.ErrNotFound)
+		}
+		return replaceRecoveryCodes(confirmCtx, tx, userID, recoveryHashes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, q execQuerier, userID int, hashes []string) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка при удалении кодов восстановления пользователя %d: %w", userID, err)
+	}
+	for _, h := range hashes {
+		if _, err := q.ExecContext(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return fmt.Errorf("ошибка при сохранении кода восстановления пользователя %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+// UseTOTPCounter принимает код шага counter. Условие last_counter < counter в UPDATE
+// не дает принять один код дважды даже при параллельных запросах.
+func (s *TwoFactorStore) UseTOTPCounter(ctx context.Context, userID int, counter int64) (bool, error) {
+	query := `UPDATE user_totp SET last_counter = $2, failed_attempts = 0, locked_until = NULL
+	WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_counter < $2`
+	useCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	res, err := s.db.ExecContext(useCtx, query, userID, counter)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при проверке кода 2FA пользователя %d: %w", userID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при проверке кода 2FA пользователя %d: %w", userID, err)
+	}
+	return n > 0, nil
+}
+
+// UseRecoveryCode расходует код восстановления.
+func (s *TwoFactorStore) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	useCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	used := false
+	err := withTx(useCtx, s.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(useCtx, `UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, hash)
+		if err != nil {
+			return fmt.Errorf("ошибка при проверке кода восстановления пользователя %d: %w", userID, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("ошибка при проверке кода восстановления пользователя %d: %w", userID, err)
+		}
+		if n == 0 {
+			return nil
+		}
+		used = true
+		if _, err := tx.ExecContext(useCtx, `UPDATE user_totp SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("ошибка при сбросе неудачных попыток 2FA пользователя %d: %w", userID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return used, nil
+}
+
+// ReserveTwoFactorAttempt учитывает попытку до проверки кода. Проверка блокировки и увеличение
+// счетчика выполняются одним UPDATE, поэтому параллельные запросы не получат больше maxAttempts
+// попыток. Попытка, на которой ставится блокировка, еще проверяется; при блокировке счетчик
+// обнуляется, и после ее окончания снова доступно maxAttempts попыток.
+func (s *TwoFactorStore) ReserveTwoFactorAttempt(ctx context.Context, userID int, maxAttempts int, now time.Time, lockedUntil time.Time) (bool, error) {
+	query := `UPDATE user_totp SET
+		failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $4 END
+	WHERE user_id = $1 AND confirmed_at IS NOT NULL AND (locked_until IS NULL OR locked_until <= $3)`
+	reserveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	res, err := s.db.ExecContext(reserveCtx, query, userID, maxAttempts, now, lockedUntil)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при учете попытки 2FA пользователя %d: %w", userID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при учете попытки 2FA пользователя %d: %w", userID, err)
+	}
+	return n > 0, nil
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления.
+func (s *TwoFactorStore) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	replaceCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(replaceCtx, s.db, func(tx *sql.Tx) error {
+		return replaceRecoveryCodes(replaceCtx, tx, userID, hashes)
+	})
+}
+
+// RecoveryCodesLeft возвращает количество неиспользованных кодов восстановления.
+func (s *TwoFactorStore) RecoveryCodesLeft(ctx context.Context, userID int) (int, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var n int
+	err := s.db.QueryRowContext(getCtx, `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при подсчете кодов восстановления пользователя %d: %w", userID, err)
+	}
+	return n, nil
+}
+
+// DisableTwoFactor удаляет секрет и коды восстановления пользователя.
+func (s *TwoFactorStore) DisableTwoFactor(ctx context.Context, userID int) (bool, error) {
+	disableCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	enabled := false
+	err := withTx(disableCtx, s.db, func(tx *sql.Tx) error {
+		var confirmedAt sql.NullTime
+		err := tx.QueryRowContext(disableCtx, `DELETE FROM user_totp WHERE user_id = $1 RETURNING confirmed_at`, userID).Scan(&confirmedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("ошибка при отключении 2FA пользователя %d: %w", userID, err)
+		}
+		enabled = confirmedAt.Valid
+		if _, err := tx.ExecContext(disableCtx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("ошибка при удалении кодов восстановления пользователя %d: %w", userID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
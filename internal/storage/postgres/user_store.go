@@ -26,9 +26,9 @@ func (s *UserStore) CreateUser(ctx context.Context, user *models.User, password
 		return 0, fmt.Errorf("bcrypt error: %w", err)
 	}
 	user.CreatedAt = time.Now()
-	query := `INSERT INTO users (username, password_hash, created_at) VALUES ($1, $2, $3) RETURNING id`
+	query := `INSERT INTO users (username, password_hash, email, created_at) VALUES ($1, $2, $3, $4) RETURNING id`
 	var id int
-	err = s.db.QueryRowContext(ctx, query, user.Username, string(hash), user.CreatedAt).Scan(&id)
+	err = s.db.QueryRowContext(ctx, query, user.Username, string(hash), user.Email, user.CreatedAt).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, fmt.Errorf("user not created: %w", err)
@@ -41,9 +41,9 @@ func (s *UserStore) CreateUser(ctx context.Context, user *models.User, password
 }
 
 func (s *UserStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	query := `SELECT id, username, password_hash, created_at FROM users WHERE username = $1`
+	query := `SELECT id, username, password_hash, token_version, created_at FROM users WHERE username = $1`
 	user := &models.User{}
-	err := s.db.QueryRowContext(ctx, query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt)
+	err := s.db.QueryRowContext(ctx, query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.TokenVersion, &user.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("user not found: %w", err)
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// WatchStore реализует storage.WatchStore для PostgreSQL.
+type WatchStore struct {
+	db *sql.DB
+}
+
+// NewWatchStore создает новый экземпляр WatchStore.
+func NewWatchStore(db *sql.DB) *WatchStore {
+	return &WatchStore{db: db}
+}
+
+// Migrate создает таблицы наблюдателей и накопленных изменений, если они не существуют.
+// Обе таблицы очищаются вместе с окончательным удалением задачи.
+func (s *WatchStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS task_watchers (
+		task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (task_id, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_task_watchers_user_id ON task_watchers(user_id);
+	CREATE TABLE IF NOT EXISTS watch_updates (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		task_title VARCHAR(255) NOT NULL,
+		actor_id INTEGER NOT NULL,
+		event_type VARCHAR(50) NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, task_id)
+	);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// CanWatch проверяет, что пользователь владеет задачей или был в ней упомянут.
+func (s *WatchStore) CanWatch(ctx context.Context, taskID int, userID int) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM tasks t WHERE t.id = $1 AND t.deleted_at IS NULL AND (t.user_id = $2 OR EXISTS (
+			SELECT 1 FROM notifications n WHERE n.user_id = $2 AND n.task_id = t.id AND n.type = $3
+		))
+	)`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var ok bool
+	if err := s.db.QueryRowContext(getCtx, query, taskID, userID, models.NotificationMention).Scan(&ok); err != nil {
+		return false, fmt.Errorf("ошибка при проверке доступа к задаче %d: %w", taskID, err)
+	}
+	return ok, nil
+}
+
+// Watch подписывает пользователя на задачу.
+func (s *WatchStore) Watch(ctx context.Context, taskID int, userID int) error {
+	query := `INSERT INTO task_watchers (task_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(watchCtx, query, taskID, userID); err != nil {
+		return fmt.Errorf("ошибка при подписке на задачу %d: %w", taskID, err)
+	}
+	return nil
+}
+
+// Unwatch отписывает пользователя от задачи вместе с накопленными по ней изменениями.
+func (s *WatchStore) Unwatch(ctx context.Context, taskID int, userID int) error {
+	unwatchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return withTx(unwatchCtx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(unwatchCtx, `DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2`, taskID, userID); err != nil {
+			return fmt.Errorf("ошибка при отписке от задачи %d: %w", taskID, err)
+		}
+		if _, err := tx.ExecContext(unwatchCtx, `DELETE FROM watch_updates WHERE task_id = $1 AND user_id = $2`, taskID, userID); err != nil {
+			return fmt.Errorf("ошибка при отписке от задачи %d: %w", taskID, err)
+		}
+		return nil
+	})
+}
+
+// GetWatchers возвращает наблюдателей задачи в порядке подписки.
+func (s *WatchStore) GetWatchers(ctx context.Context, taskID int, ownerID int) ([]models.Watcher, error) {
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var exists bool
+	err := s.db.QueryRowContext(getCtx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)`,
+		taskID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задачи %d: %w", taskID, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("задача %d: %w", taskID, storage.ErrNotFound)
+	}
+	query := `SELECT w.user_id, u.username, w.created_at FROM task_watchers w JOIN users u ON u.id = w.user_id
+	WHERE w.task_id = $1 ORDER BY w.created_at, w.user_id`
+	rows, err := s.db.QueryContext(getCtx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении наблюдателей задачи %d: %w", taskID, err)
+	}
+	defer rows.Close()
+	watchers := []models.Watcher{}
+	for rows.Next() {
+		var w models.Watcher
+		if err := rows.Scan(&w.UserID, &w.Username, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования наблюдателя: %w", err)
+		}
+		watchers = append(watchers, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по наблюдателям: %w", err)
+	}
+	return watchers, nil
+}
+
+// GetWatching возвращает задачи, которые отслеживает пользователь, включая задачи в корзине,
+// начиная с последних подписок.
+func (s *WatchStore) GetWatching(ctx context.Context, userID int) ([]models.WatchedTask, error) {
+	query := `SELECT t.id, t.title, t.user_id, w.created_at FROM task_watchers w JOIN tasks t ON t.id = w.task_id
+	WHERE w.user_id = $1 ORDER BY w.created_at DESC, t.id DESC`
+	getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении отслеживаемых задач: %w", err)
+	}
+	defer rows.Close()
+	watching := []models.WatchedTask{}
+	for rows.Next() {
+		var w models.WatchedTask
+		if err := rows.Scan(&w.TaskID, &w.TaskTitle, &w.OwnerID, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования отслеживаемой задачи: %w", err)
+		}
+		watching = append(watching, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по отслеживаемым задачам: %w", err)
+	}
+	return watching, nil
+}
+
+// QueueUpdate добавляет или обновляет накопленное изменение задачи у каждого наблюдателя, кроме автора.
+func (s *WatchStore) QueueUpdate(ctx context.Context, update models.WatchUpdate) error {
+	query := `INSERT INTO watch_updates (user_id, task_id, task_title, actor_id, event_type)
+	SELECT user_id, task_id, $3, $2, $4 FROM task_watchers WHERE task_id = $1 AND user_id <> $2
+	ON CONFLICT (user_id, task_id) DO UPDATE SET task_title = EXCLUDED.task_title, actor_id = EXCLUDED.actor_id,
+		event_type = EXCLUDED.event_type, updated_at = CURRENT_TIMESTAMP`
+	queueCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(queueCtx, query, update.TaskID, update.ActorID, update.TaskTitle, update.EventType); err != nil {
+		return fmt.Errorf("ошибка при сохранении изменения задачи %d для наблюдателей: %w", update.TaskID, err)
+	}
+	return nil
+}
+
+// TakeUpdates удаляет и возвращает изменения наблюдателей, у которых изменения перестали поступать,
+// поэтому массовая операция попадает к наблюдателю одной пачкой.
+// Несколько реплик не получат одни и те же изменения: строки удаляются с блокировкой.
+func (s *WatchStore) TakeUpdates(ctx context.Context, quietSince time.Time, limit int) ([]models.WatchUpdate, error) {
+	query := `DELETE FROM watch_updates WHERE user_id IN (
+		SELECT user_id FROM watch_updates GROUP BY user_id HAVING MAX(updated_at) <= $1 ORDER BY MIN(updated_at) LIMIT $2
+	) RETURNING user_id, task_id, task_title, actor_id, event_type, updated_at`
+	takeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(takeCtx, query, quietSince, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении изменений для наблюдателей: %w", err)
+	}
+	defer rows.Close()
+	var updates []models.WatchUpdate
+	for rows.Next() {
+		var u models.WatchUpdate
+		if err := rows.Scan(&u.UserID, &u.TaskID, &u.TaskTitle, &u.ActorID, &u.EventType, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования изменения: %w", err)
+		}
+		updates = append(updates, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при получении изменений для наблюдателей: %w", err)
+	}
+	return updates, nil
+}
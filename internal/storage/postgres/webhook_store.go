@@ -0,0 +1,294 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"kanban-backend/internal/events"
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// WebhookStore реализует storage.WebhookStore для PostgreSQL.
+// Таблица webhook_deliveries служит очередью исходящих доставок (outbox); доставки
+// добавляются в той же транзакции, что и изменение задачи (см. enqueueWebhookDeliveries).
+type WebhookStore struct {
+	db *sql.DB
+}
+
+// NewWebhookStore создает новый экземпляр WebhookStore.
+func NewWebhookStore(db *sql.DB) *WebhookStore {
+	return &WebhookStore{db: db}
+}
+
+// Migrate создает таблицы подписок и доставок, если они не существуют.
+func (s *WebhookStore) Migrate(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		events TEXT[] NOT NULL DEFAULT '{}',
+		secret VARCHAR(255) NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event_id BIGINT,
+		event_type VARCHAR(64) NOT NULL,
+		request_body TEXT NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		response_code INTEGER,
+		latency_ms INTEGER,
+		last_error TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// CreateWebhook добавляет новую подписку.
+func (s *WebhookStore) CreateWebhook(ctx context.Context, hook *models.Webhook) (int, error) {
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	hook.Active = true
+	query := `INSERT INTO webhooks (user_id, url, events, secret, active) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	createCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.db.QueryRowContext(createCtx, query, hook.UserID, hook.URL, pq.Array(hook.Events), hook.Secret, hook.Active).Scan(&hook.ID, &hook.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании подписки: %w", err)
+	}
+	return hook.ID, nil
+}
+
+// GetWebhooks возвращает подписки пользователя без секретов.
+func (s *WebhookStore) GetWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	query := `SELECT id, user_id, url, events, active, created_at FROM webhooks WHERE user_id = $1 ORDER BY id`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении подписок: %w", err)
+	}
+	defer rows.Close()
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var h models.Webhook
+		if err := rows.Scan(&h.ID, &h.UserID, &h.URL, pq.Array(&h.Events), &h.Active, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования подписки: %w", err)
+		}
+		hooks = append(hooks, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по подпискам: %w", err)
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с журналом ее доставок.
+func (s *WebhookStore) DeleteWebhook(ctx context.Context, id int, userID int) error {
+	deleteCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(deleteCtx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении подписки %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("подписка %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.request_body, d.status, d.attempts,
+	d.next_attempt_at, d.response_code, d.latency_ms, d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row interface{ Scan(...interface{}) error }, d *models.WebhookDelivery, extra ...interface{}) error {
+	var eventID sql.NullInt64
+	var lastError sql.NullString
+	var responseCode, latency sql.NullInt32
+	var deliveredAt sql.NullTime
+	dest := []interface{}{&d.ID, &d.WebhookID, &eventID, &d.EventType, &d.RequestBody, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &responseCode, &latency, &lastError, &d.CreatedAt, &deliveredAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	d.EventID = eventID.Int64
+	d.LastError = lastError.String
+	if responseCode.Valid {
+		code := int(responseCode.Int32)
+		d.ResponseCode = &code
+	}
+	if latency.Valid {
+		ms := int(latency.Int32)
+		d.LatencyMs = &ms
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return nil
+}
+
+// GetDeliveries возвращает последние доставки подписки.
+func (s *WebhookStore) GetDeliveries(ctx context.Context, webhookID int, userID int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + `
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.webhook_id = $1 AND w.user_id = $2
+	ORDER BY d.id DESC LIMIT 100`
+	getCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(getCtx, query, webhookID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении доставок подписки %d: %w", webhookID, err)
+	}
+	defer rows.Close()
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по доставкам: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver ставит в очередь повторную отправку того же тела запроса новой доставкой.
+func (s *WebhookStore) Redeliver(ctx context.Context, deliveryID int64, webhookID int, userID int) (*models.WebhookDelivery, error) {
+	query := `
+	INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, request_body)
+	SELECT d.webhook_id, d.event_id, d.event_type, d.request_body
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.id = $1 AND d.webhook_id = $2 AND w.user_id = $3
+	RETURNING id, webhook_id, event_id, event_type, request_body, status, attempts,
+		next_attempt_at, response_code, latency_ms, last_error, created_at, delivered_at`
+	redeliverCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	d := &models.WebhookDelivery{}
+	if err := scanDelivery(s.db.QueryRowContext(redeliverCtx, query, deliveryID, webhookID, userID), d); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("доставка %d: %w", deliveryID, storage.ErrNotFound)
+		}
+		return nil, fmt.Errorf("ошибка при повторной доставке %d: %w", deliveryID, err)
+	}
+	return d, nil
+}
+
+// enqueueWebhookDeliveries ставит событие задачи в очередь доставки для всех подходящих активных
+// подписок. Вызывается в транзакции, которая меняет задачу: доставка попадает в очередь
+// тогда и только тогда, когда фиксируется само изменение.
+func enqueueWebhookDeliveries(ctx context.Context, q execQuerier, eventType string, task *models.Task) error {
+	e, err := events.New(eventType, task.UserID, task.ID, task)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события: %w", err)
+	}
+	query := `
+	INSERT INTO webhook_deliveries (webhook_id, event_type, request_body)
+	SELECT id, $2, $3 FROM webhooks
+	WHERE user_id = $1 AND active AND (cardinality(events) = 0 OR $2 = ANY(events))`
+	if _, err := q.ExecContext(ctx, query, e.UserID, e.Type, string(body)); err != nil {
+		return fmt.Errorf("ошибка постановки события в очередь webhook: %w", err)
+	}
+	return nil
+}
+
+// ClaimDeliveries захватывает готовые к отправке доставки, сдвигая next_attempt_at на lease.
+// Если воркер упадет, доставка снова станет доступной по истечении lease.
+// SKIP LOCKED позволяет нескольким репликам разбирать очередь параллельно.
+func (s *WebhookStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `
+	WITH due AS (
+		SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE webhook_deliveries d SET next_attempt_at = now() + $2 * interval '1 second'
+	FROM due, webhooks w
+	WHERE d.id = due.id AND w.id = d.webhook_id
+	RETURNING ` + deliveryColumns + `, w.url, w.secret`
+	claimCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(claimCtx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при захвате доставок: %w", err)
+	}
+	defer rows.Close()
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanDelivery(rows, &d, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по доставкам: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt сохраняет результат попытки: статус, счетчик попыток, код ответа и время следующей попытки.
+func (s *WebhookStore) RecordAttempt(ctx context.Context, d *models.WebhookDelivery) error {
+	query := `
+	UPDATE webhook_deliveries
+	SET status = $2, attempts = $3, next_attempt_at = $4, response_code = $5,
+		latency_ms = $6, last_error = NULLIF($7, ''), delivered_at = $8
+	WHERE id = $1`
+	recordCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(recordCtx, query, d.ID, d.Status, d.Attempts, d.NextAttemptAt,
+		d.ResponseCode, d.LatencyMs, d.LastError, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении результата доставки %d: %w", d.ID, err)
+	}
+	return nil
+}
+
+// webhookDeliveryLimit - сколько последних завершенных доставок хранится для каждой подписки.
+const webhookDeliveryLimit = 1000
+
+// PurgeDeliveries удаляет доставленные и отброшенные доставки старше retention, а также
+// завершенные доставки сверх webhookDeliveryLimit последних у каждой подписки.
+// Доставки в очереди не удаляются.
+func (s *WebhookStore) PurgeDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+	DELETE FROM webhook_deliveries WHERE id IN (
+		SELECT id FROM (
+			SELECT id, created_at, row_number() OVER (PARTITION BY webhook_id ORDER BY id DESC) AS n
+			FROM webhook_deliveries WHERE status IN ($1, $2)
+		) finished
+		WHERE created_at < $3 OR n > $4
+	)`
+	purgeCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := s.db.ExecContext(purgeCtx, query, models.DeliveryDelivered, models.DeliveryDead,
+		time.Now().Add(-retention), webhookDeliveryLimit)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при очистке истории доставок: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// RuleStore хранит правила автоматизации и журнал их выполнения.
+type RuleStore interface {
+	CreateRule(ctx context.Context, rule *models.Rule) (int, error)
+	GetRules(ctx context.Context, userID int) ([]models.Rule, error)
+	GetRule(ctx context.Context, id int, userID int) (*models.Rule, error)
+	UpdateRule(ctx context.Context, rule *models.Rule) error
+	DeleteRule(ctx context.Context, id int, userID int) error
+
+	// GetEnabledRules возвращает включенные правила пользователя с триггером triggerType.
+	GetEnabledRules(ctx context.Context, userID int, triggerType string) ([]models.Rule, error)
+	// GetDueRuleTasks возвращает пары (правило due_passed, задача), для которых срок задачи
+	// прошел к now, а правило для задачи еще не срабатывало.
+	GetDueRuleTasks(ctx context.Context, now time.Time, limit int) ([]RuleTask, error)
+	// MarkFired отмечает, что правило сработало для задачи. Возвращает false,
+	// если отметка уже была, например ее поставила другая реплика.
+	MarkFired(ctx context.Context, ruleID int, taskID int) (bool, error)
+
+	AppendExecution(ctx context.Context, exec *models.RuleExecution) error
+	GetExecutions(ctx context.Context, ruleID int, userID int, limit int) ([]models.RuleExecution, error)
+}
+
+// RuleTask - правило и задача, для которой его нужно проверить.
+type RuleTask struct {
+	Rule   models.Rule
+	TaskID int
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"kanban-backend/internal/models"
+)
+
+// ErrSprintClosed возвращается при попытке изменить состав закрытого спринта.
+var ErrSprintClosed = errors.New("спринт закрыт")
+
+// SprintStore хранит спринты и состав задач в них.
+type SprintStore interface {
+	CreateSprint(ctx context.Context, sprint *models.Sprint) (int, error)
+	GetSprints(ctx context.Context, userID int) ([]models.Sprint, error)
+	GetSprint(ctx context.Context, id int, userID int) (*models.Sprint, error)
+	UpdateSprint(ctx context.Context, sprint *models.Sprint) error
+	// DeleteSprint удаляет спринт; его задачи возвращаются в бэклог.
+	DeleteSprint(ctx context.Context, id int, userID int) error
+	GetSprintTasks(ctx context.Context, id int, userID int) ([]models.Task, error)
+	AddSprintTasks(ctx context.Context, id int, userID int, taskIDs []int) ([]models.Task, error)
+	RemoveSprintTask(ctx context.Context, id int, userID int, taskID int) (*models.Task, error)
+	// GetSprintDays возвращает объем спринта и выполненную часть на конец каждого
+	// прошедшего дня спринта, восстанавливая состав, оценки и статусы задач по журналу изменений.
+	GetSprintDays(ctx context.Context, sprint *models.Sprint, doneStatuses []string) ([]models.SprintDay, error)
+	// CloseSprint закрывает спринт, фиксирует итоги и переносит невыполненные задачи
+	// в спринт nextID (0 - ближайший следующий незакрытый спринт или бэклог).
+	CloseSprint(ctx context.Context, id int, userID int, nextID int, doneStatuses []string) (*models.Sprint, error)
+}
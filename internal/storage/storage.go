@@ -2,16 +2,43 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"kanban-backend/internal/models" // Замените на свой путь
 )
 
+// ErrNotFound возвращается, если запрошенная запись не существует или недоступна пользователю.
+var ErrNotFound = errors.New("запись не найдена")
+
+// ErrVersionConflict возвращается, если запись была изменена после того, как клиент ее прочитал.
+var ErrVersionConflict = errors.New("версия записи устарела")
+
 // TaskStore определяет методы для взаимодействия с хранилищем задач.
 type TaskStore interface {
 	Connect(ctx context.Context, dsn string) error
 	Close() error
 	CreateTask(ctx context.Context, task *models.Task) (int, error)
 	GetTaskByID(ctx context.Context, id int, userID int) (*models.Task, error)
-	GetAllTasks(ctx context.Context, userID int) ([]models.Task, error)
-	DeleteTask(ctx context.Context, id int, userID int) error
+	GetAllTasks(ctx context.Context, userID int, includeArchived bool) ([]models.Task, error)
+	// FindTasks возвращает задачи пользователя с фильтрами и сортировкой по пользовательским полям.
+	FindTasks(ctx context.Context, userID int, query models.TaskQuery) ([]models.Task, error)
+	GetTrash(ctx context.Context, userID int) ([]models.Task, error)
+	// Методы изменения принимают ожидаемую версию задачи; 0 означает любую версию.
+	UpdateTask(ctx context.Context, id int, userID int, version int, update models.TaskUpdatePayload) (*models.Task, error)
+	DeleteTask(ctx context.Context, id int, userID int, version int) error
+	RestoreTask(ctx context.Context, id int, userID int, version int) (*models.Task, error)
+	ArchiveTask(ctx context.Context, id int, userID int, version int) (*models.Task, error)
+	UnarchiveTask(ctx context.Context, id int, userID int, version int) (*models.Task, error)
+	ImportTasks(ctx context.Context, userID int, tasks []models.Task) error
+	ExportTasks(ctx context.Context, userID int, fn func(task *models.Task) error) error
+	BulkChangeTasks(ctx context.Context, userID int, ops []models.BulkOperation, continueOnError bool) (*models.BulkResponse, error)
+	GetTaskActivity(ctx context.Context, taskID int, userID int, before int64, limit int) (*models.ActivityPage, error)
+	GetActivity(ctx context.Context, userID int, before int64, limit int) (*models.ActivityPage, error)
+	// GetTaskModifiedTimes возвращает время последней записи журнала изменений каждой задачи пользователя.
+	GetTaskModifiedTimes(ctx context.Context, userID int) (map[int]time.Time, error)
+	PreviewOccurrences(ctx context.Context, id int, userID int, n int) (*models.RecurrencePreview, error)
+	GetFlowMetrics(ctx context.Context, userID int, query models.FlowMetricsQuery) (*models.FlowMetrics, error)
+	// GetStatusCounts возвращает количество задач по статусам на конец каждого дня из [from, to].
+	GetStatusCounts(ctx context.Context, userID int, from, to time.Time) ([]models.StatusCount, error)
 }
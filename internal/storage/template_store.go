@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"kanban-backend/internal/models"
+)
+
+// TemplateStore хранит шаблоны задач.
+type TemplateStore interface {
+	CreateTemplate(ctx context.Context, tpl *models.Template) (int, error)
+	GetTemplates(ctx context.Context, userID int) ([]models.Template, error)
+	GetTemplate(ctx context.Context, id int, userID int) (*models.Template, error)
+	UpdateTemplate(ctx context.Context, tpl *models.Template) error
+	DeleteTemplate(ctx context.Context, id int, userID int) error
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// ErrTimerRunning возвращается при запуске таймера, если у пользователя уже запущен другой.
+var ErrTimerRunning = errors.New("таймер уже запущен")
+
+// TimeStore хранит записи учета времени по задачам.
+type TimeStore interface {
+	// StartTimer запускает таймер по задаче. У пользователя может быть только один
+	// запущенный таймер, иначе возвращается ErrTimerRunning.
+	StartTimer(ctx context.Context, userID int, taskID int, note string) (*models.TimeEntry, error)
+	// StopTimer останавливает запущенный таймер пользователя по задаче.
+	StopTimer(ctx context.Context, userID int, taskID int) (*models.TimeEntry, error)
+	GetRunningTimer(ctx context.Context, userID int) (*models.TimeEntry, error)
+	CreateTimeEntry(ctx context.Context, entry *models.TimeEntry) error
+	UpdateTimeEntry(ctx context.Context, entry *models.TimeEntry) error
+	DeleteTimeEntry(ctx context.Context, id int64, userID int) error
+	GetTaskTime(ctx context.Context, taskID int, userID int) (*models.TaskTime, error)
+	// GetTimeEntries возвращает записи пользователя, начатые в [from, to), в порядке начала.
+	GetTimeEntries(ctx context.Context, userID int, from, to time.Time) ([]models.TimeEntry, error)
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// ErrTwoFactorEnabled возвращается при попытке заново подключить уже включенную 2FA.
+var ErrTwoFactorEnabled = errors.New("двухфакторная аутентификация уже включена")
+
+// TwoFactorStore хранит секреты TOTP и хеши кодов восстановления.
+type TwoFactorStore interface {
+	// GetTOTP возвращает секрет пользователя, подтвержденный или ожидающий подтверждения.
+	GetTOTP(ctx context.Context, userID int) (*models.TOTP, error)
+	// StartTOTPEnrollment сохраняет новый неподтвержденный секрет вместо прежнего неподтвержденного.
+	// Если 2FA уже включена, возвращает ErrTwoFactorEnabled.
+	StartTOTPEnrollment(ctx context.Context, userID int, secret string) error
+	// ConfirmTOTP включает 2FA после проверки первого кода с шагом counter и сохраняет коды восстановления.
+	ConfirmTOTP(ctx context.Context, userID int, counter int64, recoveryHashes []string) error
+	// UseTOTPCounter принимает код шага counter, если коды этого шага еще не использовались,
+	// и сбрасывает счетчик неудачных попыток.
+	UseTOTPCounter(ctx context.Context, userID int, counter int64) (bool, error)
+	// UseRecoveryCode расходует код восстановления и сбрасывает счетчик неудачных попыток.
+	UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error)
+	// ReserveTwoFactorAttempt атомарно учитывает попытку ввода кода до его проверки; после
+	// maxAttempts попыток без успешного кода проверка блокируется до lockedUntil.
+	// Возвращает false, если на момент now проверка заблокирована.
+	ReserveTwoFactorAttempt(ctx context.Context, userID int, maxAttempts int, now time.Time, lockedUntil time.Time) (bool, error)
+	// ReplaceRecoveryCodes заменяет все коды восстановления новыми.
+	ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error
+	// RecoveryCodesLeft возвращает количество неиспользованных кодов восстановления.
+	RecoveryCodesLeft(ctx context.Context, userID int) (int, error)
+	// DisableTwoFactor удаляет секрет и коды восстановления. Возвращает false, если 2FA не была включена.
+	DisableTwoFactor(ctx context.Context, userID int) (bool, error)
+}
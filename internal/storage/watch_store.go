@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// WatchStore хранит наблюдателей задач и накопленные для них изменения.
+type WatchStore interface {
+	// CanWatch сообщает, может ли пользователь отслеживать задачу: задача не удалена
+	// и пользователь ее владелец или был упомянут в ней.
+	CanWatch(ctx context.Context, taskID int, userID int) (bool, error)
+	// Watch подписывает пользователя на задачу; повторная подписка ничего не меняет.
+	Watch(ctx context.Context, taskID int, userID int) error
+	Unwatch(ctx context.Context, taskID int, userID int) error
+	// GetWatchers возвращает наблюдателей задачи владельца ownerID.
+	GetWatchers(ctx context.Context, taskID int, ownerID int) ([]models.Watcher, error)
+	GetWatching(ctx context.Context, userID int) ([]models.WatchedTask, error)
+
+	// QueueUpdate запоминает изменение задачи для всех ее наблюдателей, кроме автора изменения.
+	QueueUpdate(ctx context.Context, update models.WatchUpdate) error
+	// TakeUpdates забирает накопленные изменения не более чем limit наблюдателей,
+	// у которых не было новых изменений после quietSince.
+	TakeUpdates(ctx context.Context, quietSince time.Time, limit int) ([]models.WatchUpdate, error)
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"kanban-backend/internal/models"
+)
+
+// WebhookStore определяет методы для работы с подписками и очередью доставок.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook *models.Webhook) (int, error)
+	GetWebhooks(ctx context.Context, userID int) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int, userID int) error
+	GetDeliveries(ctx context.Context, webhookID int, userID int) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID int64, webhookID int, userID int) (*models.WebhookDelivery, error)
+
+	// ClaimDeliveries захватывает готовые к отправке доставки на время lease.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	// RecordAttempt сохраняет результат попытки доставки.
+	RecordAttempt(ctx context.Context, d *models.WebhookDelivery) error
+}
@@ -0,0 +1,192 @@
+// Package trello преобразует экспорт доски Trello (JSON из меню "Печать и экспорт")
+// в задачи этого сервиса.
+package trello
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kanban-backend/internal/models"
+)
+
+// Board - поля экспорта доски Trello, которые использует импорт.
+type Board struct {
+	Name       string      `json:"name"`
+	Lists      []List      `json:"lists"`
+	Cards      []Card      `json:"cards"`
+	Labels     []Label     `json:"labels"`
+	Checklists []Checklist `json:"checklists"`
+	Members    []Member    `json:"members"`
+	Actions    []Action    `json:"actions"`
+}
+
+// List - список (колонка) Trello.
+type List struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+}
+
+// Card - карточка Trello.
+type Card struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Desc         string   `json:"desc"`
+	Closed       bool     `json:"closed"`
+	IDList       string   `json:"idList"`
+	Due          *string  `json:"due"`
+	IDLabels     []string `json:"idLabels"`
+	IDMembers    []string `json:"idMembers"`
+	IDChecklists []string `json:"idChecklists"`
+}
+
+// Label - метка Trello.
+type Label struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Checklist - чек-лист карточки Trello.
+type Checklist struct {
+	ID     string `json:"id"`
+	IDCard string `json:"idCard"`
+}
+
+// Member - участник доски Trello.
+type Member struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"fullName"`
+}
+
+// Action - действие на доске; из них используются только комментарии.
+type Action struct {
+	Type string `json:"type"`
+}
+
+// Decode читает экспорт доски Trello.
+func Decode(r io.Reader) (*Board, error) {
+	var board Board
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("неверный JSON экспорта Trello: %w", err)
+	}
+	if board.Cards == nil && board.Lists == nil {
+		return nil, fmt.Errorf("в документе нет списков и карточек Trello")
+	}
+	return &board, nil
+}
+
+// Report - сводка импорта: что перенесено и что пропущено.
+// swagger:model TrelloImportReport
+type Report struct {
+	Board   string `json:"board"`
+	DryRun  bool   `json:"dry_run"`
+	Created int    `json:"created"`
+
+	// Перенесено
+	Cards         int `json:"cards"`
+	ArchivedCards int `json:"archived_cards"`
+	Lists         int `json:"lists"`
+	DueDates      int `json:"due_dates"`
+
+	// Пропущено: у задач нет соответствующих полей
+	SkippedLabels     int `json:"skipped_labels"`
+	SkippedChecklists int `json:"skipped_checklists"`
+	SkippedComments   int `json:"skipped_comments"`
+
+	// Участники доски, сопоставленные с пользователями по username, и оставшиеся без пары
+	MatchedMembers   []string `json:"matched_members"`
+	UnmatchedMembers []string `json:"unmatched_members"`
+
+	Warnings []string `json:"warnings"`
+	TaskIDs  []int    `json:"task_ids,omitempty"`
+}
+
+// maxStatusLength - длина столбца tasks.status.
+const maxStatusLength = 50
+
+// Convert преобразует карточки в задачи. Название списка становится статусом задачи,
+// закрытые карточки и карточки закрытых списков становятся архивными.
+// userExists сообщает, есть ли пользователь с таким username.
+func Convert(board *Board, userExists func(username string) bool) ([]models.Task, *Report) {
+	report := &Report{
+		Board:            board.Name,
+		MatchedMembers:   []string{},
+		UnmatchedMembers: []string{},
+		Warnings:         []string{},
+	}
+
+	now := time.Now().UTC()
+	lists := make(map[string]List, len(board.Lists))
+	for _, l := range board.Lists {
+		lists[l.ID] = l
+	}
+	report.Lists = len(board.Lists)
+
+	tasks := make([]models.Task, 0, len(board.Cards))
+	for _, card := range board.Cards {
+		title := strings.TrimSpace(card.Name)
+		if title == "" {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("карточка %s без названия пропущена", card.ID))
+			continue
+		}
+		if utf8.RuneCountInString(title) > 255 {
+			title = string([]rune(title)[:255])
+			report.Warnings = append(report.Warnings, fmt.Sprintf("название карточки %s обрезано до 255 символов", card.ID))
+		}
+
+		task := models.Task{Title: title, Description: card.Desc, Status: "pending"}
+		list, ok := lists[card.IDList]
+		if ok {
+			task.Status = strings.TrimSpace(list.Name)
+			if utf8.RuneCountInString(task.Status) > maxStatusLength {
+				task.Status = string([]rune(task.Status)[:maxStatusLength])
+			}
+		} else {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("список карточки %s не найден, статус pending", card.ID))
+		}
+		if card.Closed || list.Closed {
+			// В экспорте Trello нет времени закрытия карточки
+			archivedAt := now
+			task.ArchivedAt = &archivedAt
+			report.ArchivedCards++
+		}
+
+		report.SkippedLabels += len(card.IDLabels)
+		report.SkippedChecklists += len(card.IDChecklists)
+		if card.Due != nil {
+			if due, err := time.Parse(time.RFC3339, *card.Due); err == nil {
+				task.DueAt = &due
+				report.DueDates++
+			} else {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("срок карточки %s не распознан и пропущен", card.ID))
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	report.Cards = len(tasks)
+
+	for _, a := range board.Actions {
+		if a.Type == "commentCard" {
+			report.SkippedComments++
+		}
+	}
+	for _, m := range board.Members {
+		if userExists(m.Username) {
+			report.MatchedMembers = append(report.MatchedMembers, m.Username)
+		} else {
+			report.UnmatchedMembers = append(report.UnmatchedMembers, m.Username)
+		}
+	}
+	sort.Strings(report.MatchedMembers)
+	sort.Strings(report.UnmatchedMembers)
+	if len(board.Members) > 0 {
+		report.Warnings = append(report.Warnings, "у задач нет исполнителей: участники только сопоставлены, но не назначены")
+	}
+	return tasks, report
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress означает, что URL ведет во внутреннюю сеть сервера.
+var ErrForbiddenAddress = errors.New("адрес во внутренней сети запрещен")
+
+// forbiddenPrefixes - диапазоны, которые не покрываются методами netip.Addr, но тоже
+// не должны быть доступны пользовательским запросам: CGNAT, служебные и зарезервированные
+// сети, а также IPv6-адреса, в которые встроен IPv4 (NAT64, 6to4).
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// IsForbiddenAddr сообщает, ведет ли адрес в loopback, частную, link-local (в том числе
+// 169.254.169.254 - метаданные облака) или другую не публичную сеть.
+func IsForbiddenAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return true
+	}
+	for _, p := range forbiddenPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL проверяет, что URL - абсолютный http(s) адрес, все адреса хоста которого публичные.
+// Это проверка при сохранении, чтобы сразу сообщить пользователю об ошибке; от подмены DNS
+// после проверки защищает NewClient, который проверяет адрес при каждом подключении.
+func CheckURL(ctx context.Context, raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, errors.New("URL должен быть абсолютным http(s) адресом")
+	}
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if IsForbiddenAddr(ip) {
+			return nil, fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+		}
+		return u, nil
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupNetIP(lookupCtx, "ip", host)
+	if err != nil || len(ips) == 0 {
+		return nil, fmt.Errorf("не удалось определить адрес %s", host)
+	}
+	for _, ip := range ips {
+		if IsForbiddenAddr(ip) {
+			return nil, fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+		}
+	}
+	return u, nil
+}
+
+// NewClient создает HTTP-клиент для запросов на адреса, заданные пользователями.
+// Адрес проверяется в момент подключения, уже после разрешения имени, поэтому ни подмена
+// DNS (DNS rebinding), ни перенаправление не приведут запрос во внутреннюю сеть.
+// Прокси из окружения не используется: через него проверка адреса была бы бесполезна.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("неверный адрес %s: %w", address, err)
+			}
+			if IsForbiddenAddr(addr.Addr()) {
+				return fmt.Errorf("%s: %w", addr.Addr(), ErrForbiddenAddress)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
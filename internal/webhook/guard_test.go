@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsForbiddenAddr(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"224.0.0.1":        true,
+		"::1":              true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:127.0.0.1": true,
+		"64:ff9b::a00:1":   true,
+		"2002:7f00:1::":    true,
+		"8.8.8.8":          false,
+		"93.184.216.34":    false,
+		"2606:4700::1111":  false,
+	}
+	for addr, want := range cases {
+		if got := IsForbiddenAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsForbiddenAddr(%s) = %v, ожидалось %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, raw := range []string{"http://127.0.0.1:8080/hook", "https://[::1]/", "http://169.254.169.254/latest/meta-data"} {
+		if _, err := CheckURL(context.Background(), raw); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("CheckURL(%s): %v, ожидалась ErrForbiddenAddress", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com/", "/relative", "http://"} {
+		if _, err := CheckURL(context.Background(), raw); err == nil {
+			t.Errorf("CheckURL(%s) принял неверный URL", raw)
+		}
+	}
+	if _, err := CheckURL(context.Background(), "https://8.8.8.8/hook"); err != nil {
+		t.Errorf("CheckURL отклонил публичный адрес: %v", err)
+	}
+}
+
+func TestNewClientRefusesInternalAddress(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	resp, err := NewClient(5 * time.Second).Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("запрос на %s: %v, ожидалась ErrForbiddenAddress", srv.URL, err)
+	}
+	if called {
+		t.Error("запрос дошел до сервера во внутренней сети")
+	}
+}
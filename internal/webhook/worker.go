@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+const (
+	// SignatureHeader содержит подпись тела запроса: "sha256=<hex HMAC-SHA256>".
+	SignatureHeader = "X-Kanban-Signature"
+	// EventHeader содержит тип события.
+	EventHeader = "X-Kanban-Event"
+	// DeliveryHeader содержит ID доставки, одинаковый для всех попыток.
+	DeliveryHeader = "X-Kanban-Delivery"
+
+	pollInterval = 2 * time.Second
+	batchSize    = 20
+	// clientTimeout ограничивает одну попытку доставки.
+	clientTimeout = 10 * time.Second
+	// claimLease - на сколько доставка скрывается от других воркеров, пока идет отправка.
+	// Должен быть заметно больше clientTimeout: пакет отправляется параллельно за одну попытку.
+	claimLease  = time.Minute
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Sign вычисляет значение заголовка SignatureHeader для тела запроса.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret создает случайный секрет для подписи.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("ошибка генерации секрета: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Worker отправляет доставки из очереди webhook_deliveries.
+type Worker struct {
+	Store       storage.WebhookStore
+	Client      *http.Client
+	MaxAttempts int
+}
+
+// NewWorker создает новый экземпляр Worker. После maxAttempts неудачных
+// попыток доставка помечается как dead и больше не отправляется.
+func NewWorker(store storage.WebhookStore, maxAttempts int) *Worker {
+	return &Worker{
+		Store:       store,
+		Client:      NewClient(clientTimeout),
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// Run обрабатывает очередь до отмены ctx.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.processBatch(ctx)
+		}
+	}
+}
+
+func (w *Worker) processBatch(ctx context.Context) {
+	deliveries, err := w.Store.ClaimDeliveries(ctx, batchSize, claimLease)
+	if err != nil {
+		log.Printf("Ошибка получения доставок webhook: %v", err)
+		return
+	}
+	// Доставки пакета отправляются параллельно: весь пакет укладывается в таймаут клиента,
+	// который намного меньше claimLease, и другая реплика не захватит доставку повторно
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(d *models.WebhookDelivery) {
+			defer wg.Done()
+			w.deliver(ctx, d)
+			if err := w.Store.RecordAttempt(ctx, d); err != nil {
+				log.Printf("Ошибка сохранения результата доставки webhook: %v", err)
+			}
+		}(&deliveries[i])
+	}
+	wg.Wait()
+}
+
+// deliver выполняет одну попытку и заполняет ее результат в d.
+func (w *Worker) deliver(ctx context.Context, d *models.WebhookDelivery) {
+	body := []byte(d.RequestBody)
+	d.Attempts++
+	d.ResponseCode = nil
+	d.LastError = ""
+
+	start := time.Now()
+	err := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "kanban-backend-webhook")
+		req.Header.Set(EventHeader, d.EventType)
+		req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+		req.Header.Set(SignatureHeader, Sign(d.Secret, body))
+
+		resp, err := w.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		code := resp.StatusCode
+		d.ResponseCode = &code
+		if code < 200 || code >= 300 {
+			return fmt.Errorf("получатель ответил %d", code)
+		}
+		return nil
+	}()
+	latency := int(time.Since(start).Milliseconds())
+	d.LatencyMs = &latency
+
+	if err == nil {
+		now := time.Now()
+		d.Status = models.DeliveryDelivered
+		d.DeliveredAt = &now
+		return
+	}
+	d.LastError = err.Error()
+	if d.Attempts >= w.MaxAttempts {
+		d.Status = models.DeliveryDead
+		log.Printf("Доставка webhook %d отброшена после %d попыток: %v", d.ID, d.Attempts, err)
+		return
+	}
+	d.NextAttemptAt = time.Now().Add(backoff(d.Attempts))
+}
+
+// backoff возвращает паузу перед следующей попыткой: 10s, 20s, 40s... но не больше часа.
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"kanban-backend/internal/models"
+	"kanban-backend/internal/storage"
+)
+
+// fakeStore отдает доставки из памяти; остальные методы WebhookStore тестам не нужны.
+type fakeStore struct {
+	storage.WebhookStore
+	mu       sync.Mutex
+	pending  []models.WebhookDelivery
+	recorded []models.WebhookDelivery
+}
+
+func (s *fakeStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	claimed := s.pending
+	s.pending = nil
+	return claimed, nil
+}
+
+func (s *fakeStore) RecordAttempt(ctx context.Context, d *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorded = append(s.recorded, *d)
+	if d.Status == models.DeliveryPending {
+		s.pending = append(s.pending, *d)
+	}
+	return nil
+}
+
+func newDelivery(url string) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:          42,
+		WebhookID:   7,
+		EventType:   "task.created",
+		RequestBody: `{"type":"task.created","task_id":1}`,
+		Status:      models.DeliveryPending,
+		Secret:      "s3cret",
+		URL:         url,
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := Sign("key", body); got != want {
+		t.Errorf("Sign = %s, ожидалось %s", got, want)
+	}
+	if Sign("other", body) == want {
+		t.Error("подпись не зависит от секрета")
+	}
+}
+
+func TestDeliverSignsRequest(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w := &Worker{Client: srv.Client(), MaxAttempts: 3}
+	d := newDelivery(srv.URL)
+	w.deliver(context.Background(), &d)
+
+	if d.Status != models.DeliveryDelivered || d.DeliveredAt == nil {
+		t.Fatalf("доставка не отмечена доставленной: status %s, ошибка %q", d.Status, d.LastError)
+	}
+	if d.Attempts != 1 || d.ResponseCode == nil || *d.ResponseCode != http.StatusNoContent {
+		t.Errorf("попытки %d, код ответа %v", d.Attempts, d.ResponseCode)
+	}
+	if string(gotBody) != d.RequestBody {
+		t.Errorf("тело запроса %s, ожидалось %s", gotBody, d.RequestBody)
+	}
+	if sig := got.Header.Get(SignatureHeader); sig != Sign("s3cret", gotBody) {
+		t.Errorf("подпись %s не совпадает с подписью тела", sig)
+	}
+	if got.Header.Get(EventHeader) != "task.created" || got.Header.Get(DeliveryHeader) != "42" {
+		t.Errorf("заголовки события %q и доставки %q", got.Header.Get(EventHeader), got.Header.Get(DeliveryHeader))
+	}
+}
+
+func TestDeliverSchedulesRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	w := &Worker{Client: srv.Client(), MaxAttempts: 3}
+	d := newDelivery(srv.URL)
+	before := time.Now()
+	w.deliver(context.Background(), &d)
+
+	if d.Status != models.DeliveryPending {
+		t.Fatalf("после первой неудачи status %s, ожидался %s", d.Status, models.DeliveryPending)
+	}
+	if d.ResponseCode == nil || *d.ResponseCode != http.StatusBadGateway || d.LastError == "" {
+		t.Errorf("код ответа %v, ошибка %q", d.ResponseCode, d.LastError)
+	}
+	if d.NextAttemptAt.Before(before.Add(baseBackoff)) || d.NextAttemptAt.After(time.Now().Add(baseBackoff)) {
+		t.Errorf("следующая попытка %v, ожидалась через %v", d.NextAttemptAt, baseBackoff)
+	}
+
+	w.deliver(context.Background(), &d)
+	w.deliver(context.Background(), &d)
+	if d.Status != models.DeliveryDead || d.Attempts != 3 {
+		t.Errorf("после %d попыток status %s, ожидался %s", d.Attempts, d.Status, models.DeliveryDead)
+	}
+}
+
+func TestProcessBatchRetriesUntilDelivered(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{pending: []models.WebhookDelivery{newDelivery(srv.URL)}}
+	w := &Worker{Store: store, Client: srv.Client(), MaxAttempts: 5}
+	w.processBatch(context.Background())
+	w.processBatch(context.Background())
+
+	if len(store.recorded) != 2 {
+		t.Fatalf("сохранено %d попыток, ожидалось 2", len(store.recorded))
+	}
+	first, second := store.recorded[0], store.recorded[1]
+	if first.Status != models.DeliveryPending || *first.ResponseCode != http.StatusServiceUnavailable {
+		t.Errorf("первая попытка: status %s, код %d", first.Status, *first.ResponseCode)
+	}
+	if second.Status != models.DeliveryDelivered || second.Attempts != 2 {
+		t.Errorf("вторая попытка: status %s, попыток %d", second.Status, second.Attempts)
+	}
+	if len(store.pending) != 0 {
+		t.Errorf("в очереди осталось %d доставок", len(store.pending))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+	for _, c := range cases {
+		if got := backoff(c.attempts); got != c.want {
+			t.Errorf("backoff(%d) = %v, ожидалось %v", c.attempts, got, c.want)
+		}
+	}
+}